@@ -0,0 +1,47 @@
+// Command admin grants and revokes the admin role of users, which is never
+// given through the API. Check that the account belongs to the right person
+// before granting it, since emails are not verified at sign up:
+//
+//	go run ./cmd/admin grant chandelier.pipo@gmail.com
+//	go run ./cmd/admin revoke chandelier.pipo@gmail.com
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func main() {
+	if len(os.Args) != 3 || (os.Args[1] != "grant" && os.Args[1] != "revoke") {
+		fmt.Fprintln(os.Stderr, "usage: admin grant|revoke <email>")
+		os.Exit(2)
+	}
+
+	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		panic(err)
+	}
+	err = database.MigrateUserEmails(db)
+	if err == nil {
+		err = db.AutoMigrate(&entity.User{})
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	role := entity.RoleAdmin
+	if os.Args[1] == "revoke" {
+		role = ""
+	}
+	err = database.NewUserDB(db).SetRole(os.Args[2], role)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", os.Args[1], os.Args[2], err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s: role %q\n", entity.NormalizeEmail(os.Args[2]), role)
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -9,8 +12,15 @@ import (
 	"github.com/pedro-chandelier/go-expert-apis/configs"
 	_ "github.com/pedro-chandelier/go-expert-apis/docs"
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/blobstore"
 	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/jobs"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/notification"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/payment"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/scheduler"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/tax"
 	"github.com/pedro-chandelier/go-expert-apis/internal/infra/webserver/handlers"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -29,14 +39,70 @@ import (
 // @in 								header
 // @name 							Authorization
 func main() {
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{TranslateError: true})
+	if err != nil {
+		panic(err)
+	}
+	// emails must be unique before AutoMigrate builds their index
+	err = database.MigrateUserEmails(db)
+	if err != nil {
+		panic(err)
+	}
+	err = db.AutoMigrate(
+		&entity.Product{},
+		&entity.User{},
+		&entity.ExchangeRate{},
+		&entity.ProductPriceHistory{},
+		&entity.ScheduledPrice{},
+		&entity.Category{},
+		&entity.ProductCategory{},
+		&entity.ProductTag{},
+		&entity.StockLevel{},
+		&entity.StockMovement{},
+		&entity.StockReservation{},
+		&entity.Variant{},
+		&entity.ProductImage{},
+		&entity.CartItem{},
+		&entity.Order{},
+		&entity.OrderItem{},
+		&entity.Payment{},
+		&entity.PaymentEvent{},
+		&entity.Review{},
+		&entity.Wishlist{},
+		&entity.WishlistItem{},
+		&entity.Coupon{},
+		&entity.CouponRedemption{},
+		&entity.CartCoupon{},
+		&entity.TaxRegion{},
+		&entity.TaxRate{},
+		&entity.Job{},
+		&entity.IdempotentRequest{},
+	)
 	if err != nil {
 		panic(err)
 	}
-	db.AutoMigrate(&entity.Product{}, &entity.User{})
 
 	configs := configs.LoadConfig("configs/.env")
 
+	err = database.MigrateProductPrices(db, configs.DefaultCurrency)
+	if err != nil {
+		panic(err)
+	}
+
+	if configs.ExchangeRatesFile != "" {
+		err = loadExchangeRates(db, configs.ExchangeRatesFile)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	priceScheduler := scheduler.NewPriceScheduler(database.NewScheduledPriceDB(db), newProductDB(db), configs.PriceSchedulerInterval)
+	go priceScheduler.Run(context.Background())
+	reservationSweeper := scheduler.NewReservationSweeper(database.NewStockDB(db), configs.ReservationSweepInterval)
+	go reservationSweeper.Run(context.Background())
+	idempotencySweeper := scheduler.NewIdempotencySweeper(database.NewIdempotencyDB(db), configs.IdempotencySweepInterval)
+	go idempotencySweeper.Run(context.Background())
+
 	router := chi.NewRouter()
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
@@ -46,31 +112,355 @@ func main() {
 	router.Get("/docs/*", httpSwagger.Handler(httpSwagger.URL("http://localhost:8000/docs/doc.json")))
 	attachUserHandler(db, router)
 	attachProductHandler(db, router)
+	attachExchangeRateHandler(db, router)
+	attachCategoryHandler(db, router)
+	attachCartHandler(db, router)
+	attachOrderHandler(db, router)
+	attachWishlistHandler(db, router)
+	attachCouponHandler(db, router)
+	attachTaxHandler(db, router)
 
 	http.ListenAndServe(":8000", router)
 }
 
 func attachProductHandler(db *gorm.DB, router *chi.Mux) {
 	configs := configs.LoadConfig("configs/.env")
-	productDB := database.NewProductDB(db)
-	productHandler := handlers.NewProductHandler(productDB)
+	productDB := newProductDB(db)
+	exchangeRateDB := database.NewExchangeRateDB(db)
+	scheduledPriceDB := database.NewScheduledPriceDB(db)
+	variantDB := database.NewVariantDB(db)
+	productHandler := handlers.NewProductHandler(productDB, exchangeRateDB, scheduledPriceDB, variantDB)
+	if rounding, ok := money.ParseRoundingMode(configs.CurrencyRounding); ok {
+		productHandler.Rounding = rounding
+	}
+	files := newBlobStore(router)
+	productHandler.JobDB = database.NewJobDB(db)
+	productHandler.JobMaxAttempts = configs.JobMaxAttempts
+	productHandler.Files = newJobFileStore()
+	if configs.ImportMaxBytes > 0 {
+		productHandler.ImportMaxBytes = configs.ImportMaxBytes
+	}
+	if configs.ProductBatchLimit > 0 {
+		productHandler.BatchLimit = configs.ProductBatchLimit
+	}
+	idempotency := newIdempotency(db)
+	variantHandler := handlers.NewVariantHandler(productDB, variantDB)
+	imageHandler := handlers.NewImageHandler(productDB, database.NewImageDB(db), files)
+	if configs.ImageMaxBytes > 0 {
+		imageHandler.MaxBytes = configs.ImageMaxBytes
+	}
+	reviewHandler := handlers.NewReviewHandler(productDB, database.NewReviewDB(db))
+	stockHandler := handlers.NewStockHandler(productDB, database.NewStockDB(db), configs.StockReservationTTL)
 
 	router.Route("/products", func(r chi.Router) {
 		r.Use(jwtauth.Verifier(configs.TokenAuth))
 		r.Use(jwtauth.Authenticator)
-		r.Post("/", productHandler.CreateProduct)
+		r.With(idempotency.Handler).Post("/", productHandler.CreateProduct)
+		r.Post("/import", productHandler.ImportProducts)
+		r.Post("/batch", productHandler.BatchProducts)
+		r.Get("/export", productHandler.ExportProducts)
+		r.Post("/export", productHandler.CreateProductExport)
+		r.With(handlers.RequireAdmin).Post("/purge", productHandler.PurgeProducts)
 		r.Get("/", productHandler.GetProducts)
 		r.Get("/{id}", productHandler.GetProduct)
+		r.Get("/{id}/price-history", productHandler.GetPriceHistory)
+		r.Post("/{id}/scheduled-prices", productHandler.CreateScheduledPrice)
+		r.Get("/{id}/scheduled-prices", productHandler.GetScheduledPrices)
+		r.Delete("/{id}/scheduled-prices/{scheduledPriceID}", productHandler.CancelScheduledPrice)
 		r.Put("/{id}", productHandler.UpdateProduct)
 		r.Delete("/{id}", productHandler.DeleteProduct)
+		r.Put("/{id}/tags", productHandler.SetProductTags)
+		r.Get("/{id}/variants", variantHandler.GetVariants)
+		r.Post("/{id}/variants", variantHandler.CreateVariant)
+		r.Get("/{id}/variants/{variantID}", variantHandler.GetVariant)
+		r.Put("/{id}/variants/{variantID}", variantHandler.UpdateVariant)
+		r.Delete("/{id}/variants/{variantID}", variantHandler.DeleteVariant)
+		r.Get("/{id}/images", imageHandler.GetImages)
+		r.Post("/{id}/images", imageHandler.UploadImage)
+		r.Put("/{id}/images/order", imageHandler.ReorderImages)
+		r.Delete("/{id}/images/{imageID}", imageHandler.DeleteImage)
+		r.Get("/{id}/reviews", reviewHandler.GetReviews)
+		r.Post("/{id}/reviews", reviewHandler.CreateReview)
+		r.Put("/{id}/reviews/{reviewID}", reviewHandler.UpdateReview)
+		r.Delete("/{id}/reviews/{reviewID}", reviewHandler.DeleteReview)
+		r.With(handlers.RequireAdmin).Put("/{id}/reviews/{reviewID}/moderation", reviewHandler.ModerateReview)
+		r.Get("/{id}/stock", stockHandler.GetStock)
+		r.Get("/{id}/stock-ledger", stockHandler.GetStockLedger)
+		r.With(handlers.RequireAdmin).Post("/{id}/stock-adjustments", stockHandler.CreateStockAdjustment)
+		r.With(handlers.RequireAdmin).Post("/{id}/stock-reservations", stockHandler.CreateStockReservation)
+		r.With(handlers.RequireAdmin).Delete("/{id}/stock-reservations/{reservationID}", stockHandler.ReleaseStockReservation)
+	})
+
+	router.Route("/tags", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", productHandler.GetTags)
 	})
 
+	attachJobHandler(productHandler, router)
+
+}
+
+func attachExchangeRateHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	exchangeRateHandler := handlers.NewExchangeRateHandler(database.NewExchangeRateDB(db))
+
+	router.Route("/exchange-rates", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", exchangeRateHandler.GetExchangeRates)
+		r.With(handlers.RequireAdmin).Post("/", exchangeRateHandler.CreateExchangeRates)
+	})
+}
+
+func attachCategoryHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	categoryHandler := handlers.NewCategoryHandler(database.NewCategoryDB(db))
+
+	router.Route("/categories", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.With(handlers.RequireAdmin).Post("/", categoryHandler.CreateCategory)
+		r.Get("/", categoryHandler.GetCategories)
+		r.Get("/{id}", categoryHandler.GetCategory)
+		r.With(handlers.RequireAdmin).Put("/{id}", categoryHandler.UpdateCategory)
+		r.With(handlers.RequireAdmin).Delete("/{id}", categoryHandler.DeleteCategory)
+		r.Get("/{id}/products", categoryHandler.GetCategoryProducts)
+		r.With(handlers.RequireAdmin).Post("/{id}/products", categoryHandler.AssignProducts)
+		r.With(handlers.RequireAdmin).Delete("/{id}/products/{productID}", categoryHandler.UnassignProduct)
+	})
+}
+
+func attachCartHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	cartHandler := handlers.NewCartHandler(database.NewCartDB(db), database.NewProductDB(db), database.NewVariantDB(db), database.NewCouponDB(db))
+	cartHandler.Taxes = newTaxCalculator(db)
+	cartHandler.TaxRegion = configs.TaxRegion
+
+	router.Route("/cart", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/", cartHandler.GetCart)
+		r.Delete("/", cartHandler.ClearCart)
+		r.Post("/items", cartHandler.AddCartItem)
+		r.Put("/items/{itemID}", cartHandler.UpdateCartItem)
+		r.Delete("/items/{itemID}", cartHandler.DeleteCartItem)
+		r.Post("/accept-prices", cartHandler.AcceptCartPrices)
+		r.Post("/apply-coupon", cartHandler.ApplyCoupon)
+		r.Delete("/coupon", cartHandler.RemoveCoupon)
+	})
+}
+
+func attachTaxHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	taxHandler := handlers.NewTaxHandler(newTaxCalculator(db), database.NewTaxDB(db), database.NewProductDB(db), database.NewVariantDB(db))
+
+	router.Route("/tax", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Post("/quote", taxHandler.QuoteTax)
+		r.Get("/regions", taxHandler.GetTaxRegions)
+	})
+
+	// rules read from a file cannot be changed through the API
+	if configs.TaxRulesFile != "" {
+		return
+	}
+	router.Route("/admin/tax/regions", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(handlers.RequireAdmin)
+		r.Put("/{code}", taxHandler.SaveTaxRegion)
+		r.Delete("/{code}", taxHandler.DeleteTaxRegion)
+	})
+}
+
+func attachCouponHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	couponHandler := handlers.NewCouponHandler(database.NewCouponDB(db))
+
+	router.Route("/admin/coupons", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(handlers.RequireAdmin)
+		r.Post("/", couponHandler.CreateCoupon)
+		r.Get("/", couponHandler.GetCoupons)
+		r.Get("/{id}", couponHandler.GetCoupon)
+		r.Put("/{id}", couponHandler.UpdateCoupon)
+		r.Delete("/{id}", couponHandler.DeleteCoupon)
+	})
+}
+
+func attachOrderHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	orderDB := database.NewOrderDB(db)
+	orderHandler := handlers.NewOrderHandler(orderDB, database.NewCartDB(db), database.NewProductDB(db), database.NewVariantDB(db), database.NewCouponDB(db), configs.StockReservationTTL)
+	paymentHandler := handlers.NewPaymentHandler(orderDB, database.NewPaymentDB(db), newPaymentProvider())
+
+	router.Route("/orders", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Post("/", orderHandler.CreateOrder)
+		r.Get("/", orderHandler.GetOrders)
+		r.Get("/{id}", orderHandler.GetOrder)
+		r.Post("/{id}/cancel", orderHandler.CancelOrder)
+		r.Get("/{id}/payments", paymentHandler.GetOrderPayments)
+		r.Post("/{id}/payments", paymentHandler.PayOrder)
+	})
+
+	router.Route("/admin/orders", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Use(handlers.RequireAdmin)
+		r.Get("/", orderHandler.GetAllOrders)
+		r.Get("/{id}", orderHandler.GetAnyOrder)
+		r.Put("/{id}/status", orderHandler.UpdateOrderStatus)
+		r.Post("/{id}/refund", paymentHandler.RefundOrder)
+	})
+
+	router.Post("/payments/webhook", paymentHandler.PaymentWebhook)
+}
+
+func attachWishlistHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	wishlistHandler := handlers.NewWishlistHandler(database.NewWishlistDB(db), database.NewProductDB(db))
+
+	router.Route("/users/me/wishlists", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Post("/", wishlistHandler.CreateWishlist)
+		r.Get("/", wishlistHandler.GetWishlists)
+		r.Get("/{id}", wishlistHandler.GetWishlist)
+		r.Put("/{id}", wishlistHandler.RenameWishlist)
+		r.Delete("/{id}", wishlistHandler.DeleteWishlist)
+		r.Put("/{id}/items/{productID}", wishlistHandler.AddWishlistItem)
+		r.Delete("/{id}/items/{productID}", wishlistHandler.RemoveWishlistItem)
+		r.Post("/{id}/share", wishlistHandler.ShareWishlist)
+		r.Delete("/{id}/share", wishlistHandler.UnshareWishlist)
+	})
+
+	router.Get("/wishlists/shared/{token}", wishlistHandler.GetSharedWishlist)
+}
+
+// newProductDB builds the product repository whose price changes notify the
+// users who wishlisted the product.
+func newProductDB(db *gorm.DB) *database.ProductDB {
+	productDB := database.NewProductDB(db)
+	watcher := notification.NewPriceDropWatcher(database.NewWishlistDB(db), notification.LogNotifier{})
+	productDB.PriceHooks = append(productDB.PriceHooks, watcher.PriceChanged)
+	return productDB
+}
+
+// newIdempotency builds the middleware replaying the responses of requests
+// retried with the same Idempotency-Key.
+func newIdempotency(db *gorm.DB) *handlers.Idempotency {
+	configs := configs.LoadConfig("configs/.env")
+	idempotency := handlers.NewIdempotency(database.NewIdempotencyDB(db))
+	idempotency.TTL = configs.IdempotencyTTL
+	if configs.IdempotencyMaxBytes > 0 {
+		idempotency.MaxBytes = configs.IdempotencyMaxBytes
+	}
+	return idempotency
+}
+
+// newTaxCalculator builds the tax calculator over the rules of the
+// configured YAML file, or of the database when there is none.
+func newTaxCalculator(db *gorm.DB) *tax.Calculator {
+	configs := configs.LoadConfig("configs/.env")
+	var rules tax.Rules = database.NewTaxDB(db)
+	if configs.TaxRulesFile != "" {
+		fileRules, err := tax.LoadFile(configs.TaxRulesFile)
+		if err != nil {
+			panic(err)
+		}
+		rules = fileRules
+	}
+	return tax.NewCalculator(rules, database.NewCategoryDB(db))
+}
+
+// newPaymentProvider builds the configured payment provider. The fake one
+// is the only gateway available.
+func newPaymentProvider() payment.PaymentProvider {
+	configs := configs.LoadConfig("configs/.env")
+	switch configs.PaymentProvider {
+	case "fake":
+		return payment.NewFakeProvider(configs.PaymentWebhookSecret, configs.PaymentWebhookURL)
+	}
+	panic("PAYMENT_PROVIDER must be fake, the only payment gateway available")
+}
+
+// attachJobHandler mounts the job endpoints and starts the workers running
+// the imports, exports and purges queued by the product handler.
+func attachJobHandler(productHandler *handlers.ProductHandler, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
+	jobHandler := handlers.NewJobHandler(productHandler.JobDB, productHandler.Files)
+
+	runner := jobs.NewRunner(productHandler.JobDB, configs.JobWorkers, configs.JobPollInterval)
+	runner.Backoff = configs.JobRetryBackoff
+	runner.MaxBackoff = configs.JobMaxBackoff
+	runner.Register(entity.JobProductImport, productHandler.RunImportJob)
+	runner.Register(entity.JobProductExport, productHandler.RunExportJob)
+	runner.Register(entity.JobProductPurge, productHandler.RunPurgeJob)
+	go runner.Run(context.Background())
+
+	router.Route("/jobs", func(r chi.Router) {
+		r.Use(jwtauth.Verifier(configs.TokenAuth))
+		r.Use(jwtauth.Authenticator)
+		r.Get("/{id}", jobHandler.GetJob)
+		r.Delete("/{id}", jobHandler.CancelJob)
+		r.Get("/{id}/file", jobHandler.GetJobFile)
+	})
+}
+
+// newBlobStore builds the configured blob store. Local blobs are served by
+// the router under /blobs.
+func newBlobStore(router *chi.Mux) blobstore.BlobStore {
+	configs := configs.LoadConfig("configs/.env")
+	if configs.BlobStore == "s3" {
+		store := blobstore.NewS3Store(configs.S3Endpoint, configs.S3Region, configs.S3Bucket, configs.S3AccessKey, configs.S3SecretKey)
+		if configs.BlobBaseURL != "" {
+			store.PublicURL = configs.BlobBaseURL
+		}
+		return store
+	}
+
+	store := blobstore.NewLocalStore(configs.BlobDir, configs.BlobBaseURL)
+	router.Handle("/blobs/*", http.StripPrefix("/blobs/", store.Handler()))
+	return store
+}
+
+// newJobFileStore builds the store of the files that jobs read and write.
+// It is kept apart from the blob store, whose files are public, and only
+// reached through the jobs of their owner.
+func newJobFileStore() blobstore.BlobStore {
+	configs := configs.LoadConfig("configs/.env")
+	if configs.BlobStore == "s3" {
+		if configs.JobFilesBucket == "" || configs.JobFilesBucket == configs.S3Bucket {
+			panic("JOB_FILES_BUCKET must be set to a private bucket other than S3_BUCKET")
+		}
+		return blobstore.NewS3Store(configs.S3Endpoint, configs.S3Region, configs.JobFilesBucket, configs.S3AccessKey, configs.S3SecretKey)
+	}
+	return blobstore.NewLocalStore(configs.JobFilesDir, "")
+}
+
+// loadExchangeRates imports the rates of a CSV file at startup.
+func loadExchangeRates(db *gorm.DB, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = database.NewExchangeRateDB(db).ImportCSV(file, filepath.Base(path))
+	return err
 }
 
 func attachUserHandler(db *gorm.DB, router *chi.Mux) {
+	configs := configs.LoadConfig("configs/.env")
 	userDB := database.NewUserDB(db)
 	userHandler := handlers.NewUserHandler(userDB)
 
-	router.Post("/users", userHandler.CreateUser)
+	router.With(newIdempotency(db).Handler).Post("/users", userHandler.CreateUser)
 	router.Post("/users/generate-token", userHandler.GetJwt)
+	router.With(jwtauth.Verifier(configs.TokenAuth), jwtauth.Authenticator).Get("/users/{id}", userHandler.GetUser)
 }
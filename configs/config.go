@@ -1,6 +1,8 @@
 package configs
 
 import (
+	"time"
+
 	"github.com/go-chi/jwtauth"
 	"github.com/spf13/viper"
 )
@@ -15,7 +17,68 @@ type conf struct {
 	WebServerPort string `mapstructure:"WEB_SERVER_PORT"`
 	JwtSecret     string `mapstructure:"JWT_SECRET"`
 	JwtExpiresIn  int    `mapstructure:"JWT_EXPIRES_IN"`
-	TokenAuth     *jwtauth.JWTAuth
+	// DefaultCurrency is assumed for prices stored before products had a currency.
+	DefaultCurrency string `mapstructure:"DEFAULT_CURRENCY"`
+	// CurrencyRounding is the rounding mode of converted prices: half_even, half_up or down.
+	CurrencyRounding  string `mapstructure:"CURRENCY_ROUNDING"`
+	ExchangeRatesFile string `mapstructure:"EXCHANGE_RATES_FILE"`
+	// PriceSchedulerInterval is how often scheduled prices are checked, e.g. 1m.
+	PriceSchedulerInterval time.Duration `mapstructure:"PRICE_SCHEDULER_INTERVAL"`
+	// StockReservationTTL is how long a reservation holds stock, e.g. 15m.
+	StockReservationTTL time.Duration `mapstructure:"STOCK_RESERVATION_TTL"`
+	// ReservationSweepInterval is how often expired reservations are released.
+	ReservationSweepInterval time.Duration `mapstructure:"RESERVATION_SWEEP_INTERVAL"`
+	// BlobStore is where uploaded files are kept: local or s3.
+	BlobStore   string `mapstructure:"BLOB_STORE"`
+	BlobDir     string `mapstructure:"BLOB_DIR"`
+	BlobBaseURL string `mapstructure:"BLOB_BASE_URL"`
+	S3Endpoint  string `mapstructure:"S3_ENDPOINT"`
+	S3Region    string `mapstructure:"S3_REGION"`
+	S3Bucket    string `mapstructure:"S3_BUCKET"`
+	S3AccessKey string `mapstructure:"S3_ACCESS_KEY"`
+	S3SecretKey string `mapstructure:"S3_SECRET_KEY"`
+	// ImageMaxBytes is the largest image accepted for upload.
+	ImageMaxBytes int64 `mapstructure:"IMAGE_MAX_BYTES"`
+	// PaymentProvider is the payment gateway; only fake is available and the
+	// server refuses to start with any other.
+	PaymentProvider string `mapstructure:"PAYMENT_PROVIDER"`
+	// PaymentWebhookSecret signs the webhooks of the payment provider.
+	PaymentWebhookSecret string `mapstructure:"PAYMENT_WEBHOOK_SECRET"`
+	// PaymentWebhookURL is where the fake provider sends its webhooks.
+	PaymentWebhookURL string `mapstructure:"PAYMENT_WEBHOOK_URL"`
+	// TaxRulesFile is a YAML file with the tax rules; when empty they are kept
+	// in the database and managed through the admin endpoints.
+	TaxRulesFile string `mapstructure:"TAX_RULES_FILE"`
+	// TaxRegion is the region carts are taxed in when none is requested.
+	TaxRegion string `mapstructure:"TAX_REGION"`
+	// JobWorkers is how many background jobs run at the same time.
+	JobWorkers int `mapstructure:"JOB_WORKERS"`
+	// JobPollInterval is how often idle workers look for queued jobs.
+	JobPollInterval time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	// JobMaxAttempts is how many times a failing job is tried.
+	JobMaxAttempts int `mapstructure:"JOB_MAX_ATTEMPTS"`
+	// JobRetryBackoff is the wait before the first retry, doubled for each
+	// later one up to JobMaxBackoff.
+	JobRetryBackoff time.Duration `mapstructure:"JOB_RETRY_BACKOFF"`
+	JobMaxBackoff   time.Duration `mapstructure:"JOB_MAX_BACKOFF"`
+	// JobFilesDir keeps the files of jobs, such as imports and exports, when
+	// BlobStore is local, and JobFilesBucket when it is s3. Unlike images
+	// they are never served under /blobs.
+	JobFilesDir    string `mapstructure:"JOB_FILES_DIR"`
+	JobFilesBucket string `mapstructure:"JOB_FILES_BUCKET"`
+	// ImportMaxBytes is the largest file accepted for a background import.
+	ImportMaxBytes int64 `mapstructure:"IMPORT_MAX_BYTES"`
+	// ProductBatchLimit is the most operations a product batch may have.
+	ProductBatchLimit int `mapstructure:"PRODUCT_BATCH_LIMIT"`
+	// IdempotencyTTL is how long the response of a request made with an
+	// Idempotency-Key is replayed to its retries.
+	IdempotencyTTL time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	// IdempotencyMaxBytes is the largest body of a request made with an
+	// Idempotency-Key.
+	IdempotencyMaxBytes int64 `mapstructure:"IDEMPOTENCY_MAX_BYTES"`
+	// IdempotencySweepInterval is how often expired keys are deleted.
+	IdempotencySweepInterval time.Duration `mapstructure:"IDEMPOTENCY_SWEEP_INTERVAL"`
+	TokenAuth                *jwtauth.JWTAuth
 }
 
 func LoadConfig(configFilePath string) *conf {
@@ -38,6 +101,61 @@ func LoadConfig(configFilePath string) *conf {
 		panic(err)
 	}
 
+	if config.DefaultCurrency == "" {
+		config.DefaultCurrency = "USD"
+	}
+	if config.CurrencyRounding == "" {
+		config.CurrencyRounding = "half_even"
+	}
+	if config.PriceSchedulerInterval <= 0 {
+		config.PriceSchedulerInterval = time.Minute
+	}
+	if config.StockReservationTTL <= 0 {
+		config.StockReservationTTL = 15 * time.Minute
+	}
+	if config.ReservationSweepInterval <= 0 {
+		config.ReservationSweepInterval = time.Minute
+	}
+	if config.BlobStore == "" {
+		config.BlobStore = "local"
+	}
+	if config.BlobDir == "" {
+		config.BlobDir = "uploads"
+	}
+	if config.BlobBaseURL == "" {
+		config.BlobBaseURL = "http://localhost:8000/blobs"
+	}
+
+	if config.PaymentProvider == "" {
+		config.PaymentProvider = "fake"
+	}
+
+	if config.JobWorkers <= 0 {
+		config.JobWorkers = 2
+	}
+	if config.JobPollInterval <= 0 {
+		config.JobPollInterval = time.Second
+	}
+	if config.JobMaxAttempts <= 0 {
+		config.JobMaxAttempts = 3
+	}
+	if config.JobRetryBackoff <= 0 {
+		config.JobRetryBackoff = 10 * time.Second
+	}
+	if config.JobMaxBackoff <= 0 {
+		config.JobMaxBackoff = 10 * time.Minute
+	}
+	if config.JobFilesDir == "" {
+		config.JobFilesDir = "job_files"
+	}
+
+	if config.IdempotencyTTL <= 0 {
+		config.IdempotencyTTL = 24 * time.Hour
+	}
+	if config.IdempotencySweepInterval <= 0 {
+		config.IdempotencySweepInterval = time.Hour
+	}
+
 	config.TokenAuth = jwtauth.New("HS256", []byte(config.JwtSecret), nil)
 	return config
 }
@@ -1,8 +1,284 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
 type CreateProductInput struct {
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	Name  string      `json:"name"`
+	Price money.Money `json:"price"`
+}
+
+// ProductOutput is a product as returned by the API. Price is the list
+// price and EffectivePrice the one customers pay right now, which differs
+// while a promotion is active.
+type ProductOutput struct {
+	entity.Product
+	Tags            []string               `json:"tags"`
+	EffectivePrice  money.Money            `json:"effective_price"`
+	ActivePromotion *entity.ScheduledPrice `json:"active_promotion,omitempty"`
+	ConvertedPrice  *ConvertedPrice        `json:"converted_price,omitempty"`
+	Variants        []entity.Variant       `json:"variants,omitempty"`
+}
+
+// ConvertedPrice is a product price shown in another currency, along with the
+// rate used so clients can tell how fresh it is.
+type ConvertedPrice struct {
+	Price           money.Money `json:"price"`
+	Rate            string      `json:"rate" example:"0.9215"`
+	RateSource      string      `json:"rate_source"`
+	RateEffectiveAt time.Time   `json:"rate_effective_at"`
+	Rounding        string      `json:"rounding" example:"half_even"`
+}
+
+type PriceAsOfOutput struct {
+	ProductID entityPkg.ID `json:"product_id"`
+	AsOf      time.Time    `json:"as_of"`
+	Price     money.Money  `json:"price"`
+}
+
+type VariantInput struct {
+	SKU     string            `json:"sku" example:"TSHIRT-RED-M"`
+	Options map[string]string `json:"options"`
+	Price   *money.Money      `json:"price,omitempty"`
+	Stock   int64             `json:"stock"`
+}
+
+type ProductImageOutput struct {
+	entity.ProductImage
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+type ImageOrderInput struct {
+	ImageIDs []string `json:"image_ids"`
+}
+
+type ProductTagsInput struct {
+	Tags []string `json:"tags" example:"summer-sale"`
+}
+
+type StockAdjustmentInput struct {
+	Quantity int64  `json:"quantity" example:"10"`
+	Reason   string `json:"reason" example:"restock" enums:"restock,return,damaged,lost,correction"`
+	Note     string `json:"note,omitempty"`
+}
+
+type StockLevelOutput struct {
+	entity.StockLevel
+	Available int64 `json:"available"`
+}
+
+type CreateStockReservationInput struct {
+	Quantity  int64  `json:"quantity" example:"1"`
+	Reference string `json:"reference,omitempty" example:"order-1"`
+}
+
+type AddCartItemInput struct {
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id,omitempty"`
+	Quantity  int64   `json:"quantity" example:"1"`
+}
+
+type UpdateCartItemInput struct {
+	Quantity int64 `json:"quantity" example:"2"`
+}
+
+// CartItemOutput is a cart item priced at the current product price.
+// PriceChanged is set when that price differs from the one the item was
+// added with, and Available is false once the product or variant is gone.
+type CartItemOutput struct {
+	entity.CartItem
+	CurrentPrice *money.Money `json:"current_price,omitempty"`
+	Total        *money.Money `json:"total,omitempty"`
+	PriceChanged bool         `json:"price_changed"`
+	Available    bool         `json:"available"`
+}
+
+// CartOutput is the cart with its totals per currency. Coupon is the
+// evaluation of the applied coupon, whose discount is not taken off the
+// totals until checkout. Tax is quoted when a tax region is known and the
+// cart uses a single currency.
+type CartOutput struct {
+	Items          []CartItemOutput         `json:"items"`
+	Totals         []money.Money            `json:"totals"`
+	HasStalePrices bool                     `json:"has_stale_prices"`
+	Coupon         *entity.CouponEvaluation `json:"coupon,omitempty"`
+	Tax            *entity.TaxQuote         `json:"tax,omitempty"`
+}
+
+// CreateOrderInput lists the items to order. Without items the order is
+// placed for the cart.
+type CreateOrderInput struct {
+	Items []OrderItemInput `json:"items,omitempty"`
+}
+
+type OrderItemInput struct {
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id,omitempty"`
+	Quantity  int64   `json:"quantity" example:"1"`
+}
+
+type OrderStatusInput struct {
+	Status string `json:"status" example:"shipped" enums:"pending,paid,shipped,delivered,cancelled"`
+}
+
+type PayOrderInput struct {
+	Method string `json:"method" example:"fake_card"`
+}
+
+type ReviewInput struct {
+	Rating int    `json:"rating" example:"5"`
+	Text   string `json:"text"`
+}
+
+type ReviewModerationInput struct {
+	Status string `json:"status" example:"hidden" enums:"published,hidden"`
+}
+
+type WishlistInput struct {
+	Name string `json:"name" example:"Birthday"`
+}
+
+// SharedWishlistOutput is the read-only view of a shared wishlist, which
+// leaves out who owns it.
+type SharedWishlistOutput struct {
+	Name      string           `json:"name"`
+	Products  []entity.Product `json:"products"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// CouponInput creates or replaces a coupon. Amount applies to fixed coupons
+// and Percent to percentage ones; zero limits are unlimited. Coupons are
+// active unless Active is false.
+type CouponInput struct {
+	Code           string       `json:"code" example:"SUMMER10"`
+	Type           string       `json:"type" enums:"percentage,fixed" example:"percentage"`
+	Percent        int          `json:"percent,omitempty" example:"10"`
+	Amount         *money.Money `json:"amount,omitempty"`
+	MinOrder       *money.Money `json:"min_order,omitempty"`
+	MaxRedemptions int          `json:"max_redemptions,omitempty" example:"100"`
+	MaxPerUser     int          `json:"max_per_user,omitempty" example:"1"`
+	StartsAt       *time.Time   `json:"starts_at,omitempty"`
+	EndsAt         *time.Time   `json:"ends_at,omitempty"`
+	ProductIDs     []string     `json:"product_ids,omitempty"`
+	CategoryIDs    []string     `json:"category_ids,omitempty"`
+	Active         *bool        `json:"active,omitempty"`
+}
+
+type ApplyCouponInput struct {
+	Code string `json:"code" example:"SUMMER10"`
+}
+
+type TaxQuoteInput struct {
+	Region string           `json:"region" example:"US-CA"`
+	Items  []OrderItemInput `json:"items"`
+}
+
+// TaxRegionInput sets the rules of a tax region. Rounding is line, the
+// default, or order; a rate without category is the default of the region.
+type TaxRegionInput struct {
+	Name             string         `json:"name" example:"California"`
+	PricesIncludeTax bool           `json:"prices_include_tax"`
+	Rounding         string         `json:"rounding,omitempty" enums:"line,order" example:"line"`
+	Rates            []TaxRateInput `json:"rates"`
+}
+
+type TaxRateInput struct {
+	CategoryID *string `json:"category_id,omitempty" format:"uuid"`
+	Rate       string  `json:"rate" example:"7.25"`
+}
+
+// ImportReport is the outcome of a product import, one row per line of the
+// file that held a product. Nothing is saved on a dry run, where the rows
+// that would be created are valid.
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Valid   int               `json:"valid"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowOutput `json:"rows"`
+}
+
+// ImportRowOutput is the outcome of one row of an import. Status is created,
+// valid, invalid when the row was rejected or failed when it could not be
+// saved.
+type ImportRowOutput struct {
+	Line   int                `json:"line"`
+	Status string             `json:"status" enums:"created,valid,invalid,failed"`
+	ID     string             `json:"id,omitempty" format:"uuid"`
+	Detail string             `json:"detail,omitempty"`
+	Errors []ImportFieldError `json:"errors,omitempty"`
+}
+
+type ImportFieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// JobOutput is a background job. Result is what the job produced once it
+// succeeded and FileURL where the file it produced, if any, is downloaded.
+type JobOutput struct {
+	entity.Job
+	Result  json.RawMessage `json:"result,omitempty" swaggertype:"object"`
+	FileURL string          `json:"file_url,omitempty"`
+}
+
+// ExportResult is the result of an export job.
+type ExportResult struct {
+	Format   string `json:"format"`
+	Products int64  `json:"products"`
+}
+
+// PurgeResult is the result of a purge job.
+type PurgeResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// ProductBatchInput is a list of product operations applied in order.
+// Atomic batches are applied all or nothing.
+type ProductBatchInput struct {
+	Atomic     bool                    `json:"atomic"`
+	Operations []ProductOperationInput `json:"operations"`
+}
+
+// ProductOperationInput creates, updates or deletes a product. Product is
+// the product to create or the new state of the one to update; deletes only
+// need ID.
+type ProductOperationInput struct {
+	Op      string              `json:"op" enums:"create,update,delete"`
+	ID      string              `json:"id,omitempty" format:"uuid"`
+	Product *CreateProductInput `json:"product,omitempty"`
+}
+
+type CreateScheduledPriceInput struct {
+	Price    money.Money `json:"price"`
+	StartsAt time.Time   `json:"starts_at"`
+	EndsAt   *time.Time  `json:"ends_at,omitempty"`
+}
+
+type CategoryInput struct {
+	Name     string        `json:"name"`
+	ParentID *entityPkg.ID `json:"parent_id,omitempty"`
+}
+
+type AssignProductsInput struct {
+	ProductIDs []string `json:"product_ids"`
+}
+
+type CreateExchangeRateInput struct {
+	BaseCurrency  string    `json:"base_currency" example:"USD"`
+	QuoteCurrency string    `json:"quote_currency" example:"EUR"`
+	Rate          string    `json:"rate" example:"0.9215"`
+	EffectiveAt   time.Time `json:"effective_at"`
+	Source        string    `json:"source"`
 }
 
 type CreateUserInput struct {
@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// CartItem is a product, or one of its variants, in the cart of a user.
+// UnitPrice is the price when the item was added or last confirmed, so the
+// cart can tell when the product price changed since.
+type CartItem struct {
+	ID        entity.ID   `json:"id"`
+	UserID    string      `json:"-" gorm:"index"`
+	ProductID entity.ID   `json:"product_id"`
+	VariantID *entity.ID  `json:"variant_id,omitempty"`
+	Quantity  int64       `json:"quantity" validate:"gt=0,max=1000"`
+	UnitPrice money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+var (
+	ErrInvalidCartQuantity = errors.New("invalid cart quantity")
+	ErrVariantNotInProduct = errors.New("the variant does not belong to the product")
+)
+
+var cartItemErrors = map[string]error{
+	"quantity.gt":  ErrInvalidCartQuantity,
+	"quantity.max": ErrInvalidCartQuantity,
+}
+
+func NewCartItem(userID string, product *Product, variant *Variant, quantity int64) (*CartItem, error) {
+	item := &CartItem{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		ProductID: product.ID,
+		Quantity:  quantity,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if variant != nil {
+		if variant.ProductID != product.ID {
+			return nil, ErrVariantNotInProduct
+		}
+		item.VariantID = &variant.ID
+	}
+	item.UnitPrice = CartLine{Item: *item, Product: product, Variant: variant}.CurrentPrice()
+
+	err := item.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (i *CartItem) Validate() error {
+	return validate(i, cartItemErrors)
+}
+
+// SameLine tells whether two items are for the same product and variant.
+func (i *CartItem) SameLine(other *CartItem) bool {
+	if i.ProductID != other.ProductID {
+		return false
+	}
+	if i.VariantID == nil || other.VariantID == nil {
+		return i.VariantID == nil && other.VariantID == nil
+	}
+	return *i.VariantID == *other.VariantID
+}
+
+// CartLine is a cart item with the product it refers to as it is now.
+// Product is nil when the product no longer exists.
+type CartLine struct {
+	Item    CartItem
+	Product *Product
+	Variant *Variant
+}
+
+func (l CartLine) Available() bool {
+	return l.Product != nil && (l.Item.VariantID == nil || l.Variant != nil)
+}
+
+// CurrentPrice is the unit price of the line at the current product price.
+func (l CartLine) CurrentPrice() money.Money {
+	if l.Variant != nil {
+		return l.Variant.EffectivePrice(l.Product)
+	}
+	return l.Product.Price
+}
+
+// PriceChanged tells whether the price moved since the item was added.
+func (l CartLine) PriceChanged() bool {
+	return l.Available() && !l.CurrentPrice().Equal(l.Item.UnitPrice)
+}
+
+// Total is the current price of the line times its quantity. It fails with
+// money.ErrAmountOverflow when that is too large to be represented.
+func (l CartLine) Total() (money.Money, error) {
+	return l.CurrentPrice().Multiply(l.Item.Quantity)
+}
+
+// CartTotals adds up the available lines, one total per currency sorted by
+// currency code. It fails with money.ErrAmountOverflow when a total is too
+// large to be represented.
+func CartTotals(lines []CartLine) ([]money.Money, error) {
+	totals := map[string]money.Money{}
+	for _, line := range lines {
+		if !line.Available() {
+			continue
+		}
+		total, err := line.Total()
+		if err != nil {
+			return nil, err
+		}
+		if sum, ok := totals[total.Currency]; ok {
+			total, err = sum.Add(total)
+			if err != nil {
+				return nil, err
+			}
+		}
+		totals[total.Currency] = total
+	}
+
+	result := make([]money.Money, 0, len(totals))
+	for _, total := range totals {
+		result = append(result, total)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Currency < result[j].Currency })
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCartItem(t *testing.T) {
+	product, _ := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	price := money.MustParse("24.00", "USD")
+	variant, _ := NewVariant(product, "TSHIRT-XL", nil, &price, 1)
+
+	item, err := NewCartItem("user-1", product, nil, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, product.Price, item.UnitPrice)
+
+	item, err = NewCartItem("user-1", product, variant, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, price, item.UnitPrice)
+	assert.Equal(t, variant.ID, *item.VariantID)
+
+	_, err = NewCartItem("user-1", product, nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidCartQuantity)
+
+	other, _ := NewProduct("Mug", money.MustParse("8.00", "USD"))
+	_, err = NewCartItem("user-1", other, variant, 1)
+	assert.ErrorIs(t, err, ErrVariantNotInProduct)
+}
+
+func TestCartLines(t *testing.T) {
+	shirt, _ := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	mug, _ := NewProduct("Mug", money.MustParse("8.00", "USD"))
+	book, _ := NewProduct("Livro", money.MustParse("50.00", "BRL"))
+
+	shirtItem, _ := NewCartItem("user-1", shirt, nil, 2)
+	mugItem, _ := NewCartItem("user-1", mug, nil, 3)
+	bookItem, _ := NewCartItem("user-1", book, nil, 1)
+
+	// the shirt got more expensive after it was added
+	shirt.Price = money.MustParse("22.00", "USD")
+
+	lines := []CartLine{
+		{Item: *shirtItem, Product: shirt},
+		{Item: *mugItem, Product: mug},
+		{Item: *bookItem, Product: book},
+		{Item: *bookItem, Product: nil},
+	}
+	assert.True(t, lines[0].PriceChanged())
+	assert.False(t, lines[1].PriceChanged())
+	assert.False(t, lines[3].Available())
+	total, err := lines[0].Total()
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("44.00", "USD"), total)
+
+	totals, err := CartTotals(lines)
+	assert.NoError(t, err)
+	assert.Equal(t, []money.Money{
+		money.MustParse("50.00", "BRL"),
+		money.MustParse("68.00", "USD"),
+	}, totals)
+}
+
+// expensiveLines are n lines of the most expensive product in the largest
+// quantity a cart accepts.
+func expensiveLines(t *testing.T, n int) []CartLine {
+	product, err := NewProduct("Yacht", money.New(money.MaxAmount, "USD"))
+	assert.NoError(t, err)
+	item, err := NewCartItem("user-1", product, nil, 1000)
+	assert.NoError(t, err)
+
+	lines := make([]CartLine, n)
+	for i := range lines {
+		lines[i] = CartLine{Item: *item, Product: product}
+	}
+	return lines
+}
+
+func TestCartTotalsOverflow(t *testing.T) {
+	_, err := CartTotals(expensiveLines(t, 10))
+	assert.ErrorIs(t, err, money.ErrAmountOverflow)
+}
+
+func TestCartItemSameLine(t *testing.T) {
+	product, _ := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	variant, _ := NewVariant(product, "TSHIRT-XL", nil, nil, 1)
+
+	plain, _ := NewCartItem("user-1", product, nil, 1)
+	again, _ := NewCartItem("user-1", product, nil, 2)
+	sized, _ := NewCartItem("user-1", product, variant, 1)
+
+	assert.True(t, plain.SameLine(again))
+	assert.False(t, plain.SameLine(sized))
+	assert.True(t, sized.SameLine(sized))
+}
@@ -0,0 +1,91 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+// Category groups products. Categories nest through ParentID; a category
+// without a parent is a root of the tree.
+type Category struct {
+	ID        entity.ID  `json:"id" validate:"required,uuid_id"`
+	Name      string     `json:"name" validate:"required,trimmed,max=120"`
+	ParentID  *entity.ID `json:"parent_id,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+	Children  []Category `json:"children,omitempty" gorm:"-"`
+}
+
+// ProductCategory assigns a product to a category.
+type ProductCategory struct {
+	ProductID  entity.ID `gorm:"primaryKey"`
+	CategoryID entity.ID `gorm:"primaryKey;index"`
+}
+
+var (
+	ErrCategoryNameIsRequired = errors.New("category name is required")
+	ErrInvalidCategoryName    = errors.New("invalid category name")
+	ErrCategoryCycle          = errors.New("a category cannot be nested under itself or its descendants")
+	ErrCategoryHasChildren    = errors.New("category has subcategories")
+)
+
+var categoryErrors = map[string]error{
+	"id.required":   ErrIDIsRequired,
+	"id.uuid_id":    ErrInvalidID,
+	"name.required": ErrCategoryNameIsRequired,
+	"name.trimmed":  ErrInvalidCategoryName,
+	"name.max":      ErrInvalidCategoryName,
+}
+
+func NewCategory(name string, parentID *entity.ID) (*Category, error) {
+	category := &Category{
+		ID:        entity.NewID(),
+		Name:      name,
+		ParentID:  parentID,
+		CreatedAt: time.Now(),
+	}
+
+	err := category.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func (c *Category) Validate() error {
+	if c.ParentID != nil && *c.ParentID == c.ID {
+		return ErrCategoryCycle
+	}
+	return validate(c, categoryErrors)
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents and
+// returns the roots. Categories whose parent is not in the list are treated
+// as roots.
+func BuildCategoryTree(categories []Category) []Category {
+	children := map[entity.ID][]Category{}
+	known := map[entity.ID]bool{}
+	for _, category := range categories {
+		known[category.ID] = true
+	}
+
+	var roots []Category
+	for _, category := range categories {
+		if category.ParentID != nil && known[*category.ParentID] {
+			children[*category.ParentID] = append(children[*category.ParentID], category)
+		} else {
+			roots = append(roots, category)
+		}
+	}
+
+	var attach func(nodes []Category) []Category
+	attach = func(nodes []Category) []Category {
+		for i := range nodes {
+			nodes[i].Children = attach(children[nodes[i].ID])
+		}
+		return nodes
+	}
+	return attach(roots)
+}
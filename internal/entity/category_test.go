@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCategory(t *testing.T) {
+	root, err := NewCategory("Electronics", nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, root.ID)
+	assert.Nil(t, root.ParentID)
+
+	child, err := NewCategory("Phones", &root.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, root.ID, *child.ParentID)
+}
+
+func TestNewCategoryWhenInvalid(t *testing.T) {
+	_, err := NewCategory("", nil)
+	assert.ErrorIs(t, err, ErrCategoryNameIsRequired)
+
+	_, err = NewCategory(" Phones", nil)
+	assert.ErrorIs(t, err, ErrInvalidCategoryName)
+
+	_, err = NewCategory(strings.Repeat("a", 121), nil)
+	assert.ErrorIs(t, err, ErrInvalidCategoryName)
+
+	category, err := NewCategory("Phones", nil)
+	assert.NoError(t, err)
+	category.ParentID = &category.ID
+	assert.ErrorIs(t, category.Validate(), ErrCategoryCycle)
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	electronics, _ := NewCategory("Electronics", nil)
+	phones, _ := NewCategory("Phones", &electronics.ID)
+	android, _ := NewCategory("Android", &phones.ID)
+	books, _ := NewCategory("Books", nil)
+
+	tree := BuildCategoryTree([]Category{*android, *electronics, *books, *phones})
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Electronics", tree[0].Name)
+	assert.Equal(t, "Phones", tree[0].Children[0].Name)
+	assert.Equal(t, "Android", tree[0].Children[0].Children[0].Name)
+	assert.Empty(t, tree[1].Children)
+
+	// a subtree keeps its top category as root
+	subtree := BuildCategoryTree([]Category{*phones, *android})
+	assert.Len(t, subtree, 1)
+	assert.Equal(t, "Phones", subtree[0].Name)
+}
@@ -0,0 +1,351 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+type CouponType string
+
+const (
+	CouponPercentage CouponType = "percentage"
+	CouponFixed      CouponType = "fixed"
+)
+
+// Coupon is a discount code. A percentage coupon takes Percent off the
+// eligible items and a fixed one takes Amount off them, never more than
+// they cost. Without product or category restrictions every item is
+// eligible; a category covers its subcategories. Zero limits are unlimited.
+type Coupon struct {
+	ID             entity.ID    `json:"id"`
+	Code           string       `json:"code" gorm:"size:64;uniqueIndex" validate:"required,max=64"`
+	Type           CouponType   `json:"type" validate:"oneof=percentage fixed"`
+	Percent        int          `json:"percent,omitempty" validate:"gte=0,lte=100"`
+	Amount         *money.Money `json:"amount,omitempty" gorm:"embedded;embeddedPrefix:amount_" validate:"omitempty,money_positive"`
+	MinOrder       *money.Money `json:"min_order,omitempty" gorm:"embedded;embeddedPrefix:min_order_" validate:"omitempty,money_positive"`
+	MaxRedemptions int          `json:"max_redemptions" validate:"gte=0"`
+	MaxPerUser     int          `json:"max_per_user" validate:"gte=0"`
+	Redemptions    int          `json:"redemptions"`
+	StartsAt       *time.Time   `json:"starts_at,omitempty"`
+	EndsAt         *time.Time   `json:"ends_at,omitempty"`
+	ProductIDs     []string     `json:"product_ids,omitempty" gorm:"serializer:json"`
+	CategoryIDs    []string     `json:"category_ids,omitempty" gorm:"serializer:json"`
+	Active         bool         `json:"active"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// CouponRedemption is a use of a coupon by a user, recorded at checkout.
+type CouponRedemption struct {
+	ID        entity.ID `json:"id"`
+	CouponID  entity.ID `json:"coupon_id" gorm:"index:idx_redemption_user"`
+	UserID    string    `json:"user_id" gorm:"size:255;index:idx_redemption_user"`
+	OrderID   entity.ID `json:"order_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CartCoupon is the coupon a user applied to their cart, redeemed when the
+// cart is checked out.
+type CartCoupon struct {
+	UserID    string `gorm:"primaryKey;size:255"`
+	Code      string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+// Reasons a coupon is rejected, returned to clients as codes.
+const (
+	CouponNotFound          = "not_found"
+	CouponInactive          = "inactive"
+	CouponNotStarted        = "not_started"
+	CouponExpired           = "expired"
+	CouponUsageLimitReached = "usage_limit_reached"
+	CouponUserLimitReached  = "user_limit_reached"
+	CouponEmptyCart         = "empty_cart"
+	CouponCurrencyMismatch  = "currency_mismatch"
+	CouponMinOrderNotMet    = "min_order_not_met"
+	CouponNoEligibleItems   = "no_eligible_items"
+)
+
+var couponCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]*$`)
+
+var (
+	ErrCouponCodeIsRequired  = errors.New("coupon code is required")
+	ErrInvalidCouponCode     = errors.New("invalid coupon code")
+	ErrInvalidCouponType     = errors.New("invalid coupon type")
+	ErrInvalidCouponPercent  = errors.New("percentage coupons take 1 to 100 percent off")
+	ErrInvalidCouponAmount   = errors.New("fixed coupons need a positive amount")
+	ErrInvalidCouponMinOrder = errors.New("the minimum order must be a positive amount")
+	ErrInvalidCouponLimit    = errors.New("coupon limits cannot be negative")
+	ErrInvalidCouponWindow   = errors.New("ends_at must be after starts_at")
+	ErrInvalidCouponProduct  = errors.New("coupon product restrictions must be product IDs")
+	ErrInvalidCouponCategory = errors.New("coupon category restrictions must be category IDs")
+	ErrCouponAlreadyExists   = errors.New("coupon code already exists")
+	ErrCouponRejected        = errors.New("the coupon cannot be applied")
+)
+
+var couponErrors = map[string]error{
+	"code.required":            ErrCouponCodeIsRequired,
+	"code.max":                 ErrInvalidCouponCode,
+	"type.oneof":               ErrInvalidCouponType,
+	"percent.gte":              ErrInvalidCouponPercent,
+	"percent.lte":              ErrInvalidCouponPercent,
+	"amount.money_positive":    ErrInvalidCouponAmount,
+	"currency.required":        ErrInvalidCurrency,
+	"currency.iso4217":         ErrInvalidCurrency,
+	"min_order.money_positive": ErrInvalidCouponMinOrder,
+	"max_redemptions.gte":      ErrInvalidCouponLimit,
+	"max_per_user.gte":         ErrInvalidCouponLimit,
+}
+
+// NewCoupon creates an active coupon without limits or restrictions. Amount
+// is only used by fixed coupons and percent by percentage ones.
+func NewCoupon(code string, couponType CouponType, percent int, amount *money.Money) (*Coupon, error) {
+	coupon := &Coupon{
+		ID:        entity.NewID(),
+		Code:      code,
+		Type:      couponType,
+		Active:    true,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if couponType == CouponFixed {
+		coupon.Amount = amount
+	} else {
+		coupon.Percent = percent
+	}
+
+	err := coupon.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return coupon, nil
+}
+
+// NormalizeCouponCode uppercases and trims a code so that codes are case
+// insensitive.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (c *Coupon) Validate() error {
+	c.Code = NormalizeCouponCode(c.Code)
+	err := validate(c, couponErrors)
+	if err != nil {
+		return err
+	}
+	if !couponCodePattern.MatchString(c.Code) {
+		return ErrInvalidCouponCode
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return ErrInvalidCouponWindow
+	}
+	for _, id := range c.ProductIDs {
+		if _, err := entity.ParseID(id); err != nil {
+			return ErrInvalidCouponProduct
+		}
+	}
+	for _, id := range c.CategoryIDs {
+		if _, err := entity.ParseID(id); err != nil {
+			return ErrInvalidCouponCategory
+		}
+	}
+	switch c.Type {
+	case CouponPercentage:
+		if c.Percent < 1 {
+			return ErrInvalidCouponPercent
+		}
+	case CouponFixed:
+		if c.Amount == nil {
+			return ErrInvalidCouponAmount
+		}
+	}
+	return nil
+}
+
+// CouponRejection explains why a coupon does not apply.
+type CouponRejection struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// CouponEvaluation is the outcome of applying a coupon to a cart.
+// LineDiscounts spreads the discount of a valid coupon over the lines it
+// was evaluated against, in the same order; lines the coupon does not apply
+// to get nothing.
+type CouponEvaluation struct {
+	Code          string            `json:"code"`
+	Valid         bool              `json:"valid"`
+	Discount      *money.Money      `json:"discount,omitempty"`
+	Rejections    []CouponRejection `json:"rejections,omitempty"`
+	LineDiscounts []money.Money     `json:"-"`
+}
+
+// CouponCheck is what a coupon is evaluated against. Eligible lists the
+// products the coupon restrictions allow; it is ignored by coupons without
+// restrictions.
+type CouponCheck struct {
+	Now             time.Time
+	Lines           []CartLine
+	Eligible        map[entity.ID]bool
+	UserRedemptions int
+}
+
+// Restricted tells whether the coupon only applies to some products.
+func (c *Coupon) Restricted() bool {
+	return len(c.ProductIDs) > 0 || len(c.CategoryIDs) > 0
+}
+
+// Evaluate tells whether the coupon applies to the cart and how much it
+// takes off, listing every reason it does not. It fails with
+// money.ErrAmountOverflow when the cart adds up to more than can be
+// represented.
+func (c *Coupon) Evaluate(check CouponCheck) (CouponEvaluation, error) {
+	evaluation := CouponEvaluation{Code: c.Code}
+	reject := func(code, message string) {
+		evaluation.Rejections = append(evaluation.Rejections, CouponRejection{Code: code, Message: message})
+	}
+
+	if !c.Active {
+		reject(CouponInactive, "the coupon is not active")
+	}
+	if c.StartsAt != nil && check.Now.Before(*c.StartsAt) {
+		reject(CouponNotStarted, "the coupon is valid from "+c.StartsAt.UTC().Format(time.RFC3339))
+	}
+	if c.EndsAt != nil && !check.Now.Before(*c.EndsAt) {
+		reject(CouponExpired, "the coupon expired at "+c.EndsAt.UTC().Format(time.RFC3339))
+	}
+	if c.MaxRedemptions > 0 && c.Redemptions >= c.MaxRedemptions {
+		reject(CouponUsageLimitReached, "the coupon was used as many times as allowed")
+	}
+	if c.MaxPerUser > 0 && check.UserRedemptions >= c.MaxPerUser {
+		reject(CouponUserLimitReached, "you already used the coupon as many times as allowed")
+	}
+
+	subtotal, eligible, ok, err := c.subtotals(check)
+	if err != nil {
+		return CouponEvaluation{}, err
+	}
+	switch {
+	case !ok && subtotal.Currency == "":
+		reject(CouponEmptyCart, "the cart is empty")
+	case !ok:
+		reject(CouponCurrencyMismatch, "the coupon does not apply to the currency of the cart")
+	default:
+		if c.MinOrder != nil && subtotal.Amount < c.MinOrder.Amount {
+			reject(CouponMinOrderNotMet, "the cart must add up to at least "+c.MinOrder.String())
+		}
+		if eligible.IsZero() {
+			reject(CouponNoEligibleItems, "no item of the cart is eligible for the coupon")
+		}
+	}
+
+	if len(evaluation.Rejections) > 0 {
+		return evaluation, nil
+	}
+	discount := c.discount(eligible)
+	evaluation.LineDiscounts, err = c.spread(check, discount, eligible)
+	if err != nil {
+		return CouponEvaluation{}, err
+	}
+	evaluation.Valid = true
+	evaluation.Discount = &discount
+	return evaluation, nil
+}
+
+// subtotals adds up the available lines and the eligible ones. It is not
+// ok when the cart is empty, mixes currencies or uses one the coupon
+// amounts are not in.
+func (c *Coupon) subtotals(check CouponCheck) (subtotal, eligible money.Money, ok bool, err error) {
+	for _, line := range check.Lines {
+		if !line.Available() {
+			continue
+		}
+		total, err := line.Total()
+		if err != nil {
+			return subtotal, eligible, false, err
+		}
+		if subtotal.Currency == "" {
+			subtotal = money.New(0, total.Currency)
+			eligible = money.New(0, total.Currency)
+		}
+		if total.Currency != subtotal.Currency {
+			return subtotal, eligible, false, nil
+		}
+		subtotal, err = subtotal.Add(total)
+		if err != nil {
+			return subtotal, eligible, false, err
+		}
+		if !c.Restricted() || check.Eligible[line.Item.ProductID] {
+			eligible, err = eligible.Add(total)
+			if err != nil {
+				return subtotal, eligible, false, err
+			}
+		}
+	}
+
+	if subtotal.Currency == "" {
+		return subtotal, eligible, false, nil
+	}
+	if c.Amount != nil && c.Amount.Currency != subtotal.Currency {
+		return subtotal, eligible, false, nil
+	}
+	if c.MinOrder != nil && c.MinOrder.Currency != subtotal.Currency {
+		return subtotal, eligible, false, nil
+	}
+	return subtotal, eligible, true, nil
+}
+
+// spread splits the discount over the eligible lines in proportion to their
+// totals. Shares are rounded down and the units left go to the lines with
+// the largest remainders, so that they add up to the discount.
+func (c *Coupon) spread(check CouponCheck, discount, eligible money.Money) ([]money.Money, error) {
+	shares := make([]money.Money, len(check.Lines))
+	remainders := make([]*big.Int, len(check.Lines))
+	var order []int
+	var allocated int64
+	for i, line := range check.Lines {
+		shares[i] = money.New(0, discount.Currency)
+		if !line.Available() || (c.Restricted() && !check.Eligible[line.Item.ProductID]) {
+			continue
+		}
+		total, err := line.Total()
+		if err != nil {
+			return nil, err
+		}
+
+		// discount × total / eligible, which may not fit in an int64 halfway
+		share := new(big.Int).Mul(big.NewInt(discount.Amount), big.NewInt(total.Amount))
+		share, remainders[i] = share.QuoRem(share, big.NewInt(eligible.Amount), new(big.Int))
+		shares[i].Amount = share.Int64()
+		allocated += shares[i].Amount
+		order = append(order, i)
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	for i := int64(0); i < discount.Amount-allocated; i++ {
+		shares[order[i]].Amount++
+	}
+	return shares, nil
+}
+
+// discount is what the coupon takes off the eligible subtotal, rounding
+// percentages half up to the minor unit.
+func (c *Coupon) discount(eligible money.Money) money.Money {
+	if c.Type == CouponFixed {
+		if c.Amount.Amount > eligible.Amount {
+			return eligible
+		}
+		return *c.Amount
+	}
+	return money.New((eligible.Amount*int64(c.Percent)+50)/100, eligible.Currency)
+}
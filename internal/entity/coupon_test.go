@@ -0,0 +1,179 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCoupon(t *testing.T) {
+	coupon, err := NewCoupon(" summer10 ", CouponPercentage, 10, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "SUMMER10", coupon.Code)
+	assert.True(t, coupon.Active)
+
+	amount := money.MustParse("5.00", "USD")
+	coupon, err = NewCoupon("FIVE", CouponFixed, 0, &amount)
+	assert.NoError(t, err)
+	assert.Equal(t, amount, *coupon.Amount)
+
+	_, err = NewCoupon("", CouponPercentage, 10, nil)
+	assert.ErrorIs(t, err, ErrCouponCodeIsRequired)
+	_, err = NewCoupon("TEN OFF", CouponPercentage, 10, nil)
+	assert.ErrorIs(t, err, ErrInvalidCouponCode)
+	_, err = NewCoupon("FREE", "free", 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidCouponType)
+	_, err = NewCoupon("ALL", CouponPercentage, 101, nil)
+	assert.ErrorIs(t, err, ErrInvalidCouponPercent)
+	_, err = NewCoupon("NONE", CouponPercentage, 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidCouponPercent)
+	_, err = NewCoupon("FIVE", CouponFixed, 0, nil)
+	assert.ErrorIs(t, err, ErrInvalidCouponAmount)
+
+	coupon, _ = NewCoupon("LATE", CouponPercentage, 10, nil)
+	startsAt := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(-time.Hour)
+	coupon.StartsAt, coupon.EndsAt = &startsAt, &endsAt
+	assert.ErrorIs(t, coupon.Validate(), ErrInvalidCouponWindow)
+
+	coupon, _ = NewCoupon("SHIRTS", CouponPercentage, 10, nil)
+	coupon.ProductIDs = []string{"shirt"}
+	assert.ErrorIs(t, coupon.Validate(), ErrInvalidCouponProduct)
+}
+
+func couponLines(t *testing.T) (lines []CartLine, shirt, mug *Product) {
+	shirt, _ = NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	mug, _ = NewProduct("Mug", money.MustParse("8.25", "USD"))
+	shirtItem, err := NewCartItem("user-1", shirt, nil, 2)
+	assert.NoError(t, err)
+	mugItem, err := NewCartItem("user-1", mug, nil, 1)
+	assert.NoError(t, err)
+	lines = []CartLine{
+		{Item: *shirtItem, Product: shirt},
+		{Item: *mugItem, Product: mug},
+	}
+	return lines, shirt, mug
+}
+
+func TestCouponEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	lines, _, mug := couponLines(t)
+
+	coupon, _ := NewCoupon("TEN", CouponPercentage, 10, nil)
+	evaluation, err := coupon.Evaluate(CouponCheck{Now: now, Lines: lines})
+	assert.NoError(t, err)
+	assert.True(t, evaluation.Valid)
+	// 10% of 48.25 is 4.825, rounded half up
+	assert.Equal(t, money.MustParse("4.83", "USD"), *evaluation.Discount)
+
+	// restricted coupons only discount the eligible items
+	coupon.ProductIDs = []string{mug.ID.String()}
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: now, Lines: lines, Eligible: map[entity.ID]bool{mug.ID: true}})
+	assert.NoError(t, err)
+	assert.True(t, evaluation.Valid)
+	assert.Equal(t, money.MustParse("0.83", "USD"), *evaluation.Discount)
+
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: now, Lines: lines})
+	assert.NoError(t, err)
+	assert.False(t, evaluation.Valid)
+	assert.Equal(t, []string{CouponNoEligibleItems}, rejectionCodes(evaluation))
+
+	// fixed discounts never exceed what the eligible items cost
+	amount := money.MustParse("10.00", "USD")
+	coupon, _ = NewCoupon("TENOFF", CouponFixed, 0, &amount)
+	coupon.ProductIDs = []string{mug.ID.String()}
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: now, Lines: lines, Eligible: map[entity.ID]bool{mug.ID: true}})
+	assert.NoError(t, err)
+	assert.True(t, evaluation.Valid)
+	assert.Equal(t, money.MustParse("8.25", "USD"), *evaluation.Discount)
+}
+
+func TestCouponEvaluateExplainsRejections(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	lines, _, _ := couponLines(t)
+
+	coupon, _ := NewCoupon("BIG", CouponPercentage, 10, nil)
+	minOrder := money.MustParse("100.00", "USD")
+	endsAt := now.Add(-time.Hour)
+	coupon.MinOrder = &minOrder
+	coupon.EndsAt = &endsAt
+	coupon.Active = false
+	coupon.MaxRedemptions, coupon.Redemptions = 5, 5
+	coupon.MaxPerUser = 1
+
+	evaluation, err := coupon.Evaluate(CouponCheck{Now: now, Lines: lines, UserRedemptions: 1})
+	assert.NoError(t, err)
+	assert.False(t, evaluation.Valid)
+	assert.Nil(t, evaluation.Discount)
+	assert.Equal(t, []string{
+		CouponInactive,
+		CouponExpired,
+		CouponUsageLimitReached,
+		CouponUserLimitReached,
+		CouponMinOrderNotMet,
+	}, rejectionCodes(evaluation))
+
+	startsAt := now.Add(time.Hour)
+	coupon, _ = NewCoupon("SOON", CouponPercentage, 10, nil)
+	coupon.StartsAt = &startsAt
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: now})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CouponNotStarted, CouponEmptyCart}, rejectionCodes(evaluation))
+
+	amount := money.MustParse("5.00", "BRL")
+	coupon, _ = NewCoupon("REAIS", CouponFixed, 0, &amount)
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: now, Lines: lines})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CouponCurrencyMismatch}, rejectionCodes(evaluation))
+}
+
+func TestCouponEvaluateSpreadsTheDiscount(t *testing.T) {
+	lines, _, mug := couponLines(t)
+
+	// 4.83 off 40.00 and 8.25 leaves a unit to the largest remainder
+	coupon, _ := NewCoupon("TEN", CouponPercentage, 10, nil)
+	evaluation, err := coupon.Evaluate(CouponCheck{Now: time.Now(), Lines: lines})
+	assert.NoError(t, err)
+	assert.Equal(t, []money.Money{money.MustParse("4.00", "USD"), money.MustParse("0.83", "USD")}, evaluation.LineDiscounts)
+
+	coupon.ProductIDs = []string{mug.ID.String()}
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: time.Now(), Lines: lines, Eligible: map[entity.ID]bool{mug.ID: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []money.Money{money.MustParse("0.00", "USD"), money.MustParse("0.83", "USD")}, evaluation.LineDiscounts)
+}
+
+func TestCouponEvaluateOverflow(t *testing.T) {
+	coupon, _ := NewCoupon("TEN", CouponPercentage, 10, nil)
+	_, err := coupon.Evaluate(CouponCheck{Now: time.Now(), Lines: expensiveLines(t, 10)})
+	assert.ErrorIs(t, err, money.ErrAmountOverflow)
+}
+
+func rejectionCodes(evaluation CouponEvaluation) []string {
+	var codes []string
+	for _, rejection := range evaluation.Rejections {
+		codes = append(codes, rejection.Code)
+	}
+	return codes
+}
+
+func TestOrderApplyCoupon(t *testing.T) {
+	lines, _, _ := couponLines(t)
+	order, err := NewOrder("user-1", lines)
+	assert.NoError(t, err)
+
+	coupon, _ := NewCoupon("TEN", CouponPercentage, 10, nil)
+	evaluation, err := coupon.Evaluate(CouponCheck{Now: time.Now(), Lines: lines})
+	assert.NoError(t, err)
+	assert.NoError(t, order.ApplyCoupon(evaluation))
+	assert.Equal(t, "TEN", order.CouponCode)
+	assert.Equal(t, money.MustParse("4.83", "USD"), *order.Discount)
+	assert.Equal(t, money.MustParse("43.42", "USD"), order.Total)
+
+	coupon.Active = false
+	evaluation, err = coupon.Evaluate(CouponCheck{Now: time.Now(), Lines: lines})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, order.ApplyCoupon(evaluation), ErrCouponRejected)
+}
@@ -0,0 +1,35 @@
+package entity
+
+import "github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+
+// ValidationError carries the field errors of a failed struct validation
+// together with the sentinel of the first field, so callers relying on
+// errors.Is keep working.
+type ValidationError struct {
+	Sentinel error
+	Fields   validator.ValidationErrors
+}
+
+func (e *ValidationError) Error() string {
+	return e.Sentinel.Error()
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return []error{e.Sentinel, e.Fields}
+}
+
+// validate runs the struct tags of s and maps the first failed "field.tag"
+// found in sentinels to its error.
+func validate(s interface{}, sentinels map[string]error) error {
+	err := validator.GetValidatorInstance().Struct(s)
+	fields, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+	for _, fe := range fields {
+		if sentinel, ok := sentinels[fe.Field()+"."+fe.Tag()]; ok {
+			return &ValidationError{Sentinel: sentinel, Fields: fields}
+		}
+	}
+	return fields
+}
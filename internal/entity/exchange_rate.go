@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// ExchangeRate is how many units of QuoteCurrency one unit of BaseCurrency
+// buys from EffectiveAt on, until a newer rate for the pair takes effect.
+type ExchangeRate struct {
+	ID            entity.ID `json:"id"`
+	BaseCurrency  string    `json:"base_currency" gorm:"size:3;uniqueIndex:idx_exchange_rate_pair_date" validate:"required,iso4217"`
+	QuoteCurrency string    `json:"quote_currency" gorm:"size:3;uniqueIndex:idx_exchange_rate_pair_date" validate:"required,iso4217,nefield=BaseCurrency"`
+	Rate          string    `json:"rate" validate:"required,positive_decimal"`
+	EffectiveAt   time.Time `json:"effective_at" gorm:"uniqueIndex:idx_exchange_rate_pair_date" validate:"required"`
+	Source        string    `json:"source"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+var (
+	ErrInvalidBaseCurrency   = errors.New("invalid base currency")
+	ErrInvalidQuoteCurrency  = errors.New("invalid quote currency")
+	ErrInvalidRate           = errors.New("invalid rate")
+	ErrEffectiveAtIsRequired = errors.New("effective_at is required")
+	ErrExchangeRateNotFound  = errors.New("exchange rate not found")
+)
+
+var exchangeRateErrors = map[string]error{
+	"base_currency.required":  ErrInvalidBaseCurrency,
+	"base_currency.iso4217":   ErrInvalidBaseCurrency,
+	"quote_currency.required": ErrInvalidQuoteCurrency,
+	"quote_currency.iso4217":  ErrInvalidQuoteCurrency,
+	"quote_currency.nefield":  ErrInvalidQuoteCurrency,
+	"rate.required":           ErrInvalidRate,
+	"rate.positive_decimal":   ErrInvalidRate,
+	"effective_at.required":   ErrEffectiveAtIsRequired,
+}
+
+func NewExchangeRate(base, quote, rate string, effectiveAt time.Time, source string) (*ExchangeRate, error) {
+	exchangeRate := &ExchangeRate{
+		ID:            entity.NewID(),
+		BaseCurrency:  strings.ToUpper(strings.TrimSpace(base)),
+		QuoteCurrency: strings.ToUpper(strings.TrimSpace(quote)),
+		Rate:          strings.TrimSpace(rate),
+		EffectiveAt:   effectiveAt.UTC(),
+		Source:        source,
+		CreatedAt:     time.Now(),
+	}
+
+	err := exchangeRate.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return exchangeRate, nil
+}
+
+func (r *ExchangeRate) Validate() error {
+	return validate(r, exchangeRateErrors)
+}
+
+// Inverse returns the rate for the opposite direction of the pair, keeping
+// its source and effective date.
+func (r *ExchangeRate) Inverse() (*ExchangeRate, error) {
+	rate, err := money.ParseRate(r.Rate)
+	if err != nil {
+		return nil, ErrInvalidRate
+	}
+	inverse := *r
+	inverse.BaseCurrency, inverse.QuoteCurrency = r.QuoteCurrency, r.BaseCurrency
+	inverse.Rate = money.FormatRate(new(big.Rat).Inv(rate))
+	return &inverse, nil
+}
+
+// Convert turns an amount in the base currency into the quote currency.
+func (r *ExchangeRate) Convert(amount money.Money, mode money.RoundingMode) (money.Money, error) {
+	if amount.Currency != r.BaseCurrency {
+		return money.Money{}, money.ErrCurrencyMismatch
+	}
+	rate, err := money.ParseRate(r.Rate)
+	if err != nil {
+		return money.Money{}, ErrInvalidRate
+	}
+	return amount.Convert(r.QuoteCurrency, rate, mode)
+}
@@ -0,0 +1,52 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExchangeRate(t *testing.T) {
+	effectiveAt := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	rate, err := NewExchangeRate("usd", "eur", "0.9215", effectiveAt, "ecb")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, rate.ID)
+	assert.Equal(t, "USD", rate.BaseCurrency)
+	assert.Equal(t, "EUR", rate.QuoteCurrency)
+	assert.Equal(t, effectiveAt, rate.EffectiveAt)
+}
+
+func TestNewExchangeRateWhenInvalid(t *testing.T) {
+	now := time.Now()
+
+	_, err := NewExchangeRate("usd", "usd", "1", now, "ecb")
+	assert.ErrorIs(t, err, ErrInvalidQuoteCurrency)
+
+	_, err = NewExchangeRate("usd", "eur", "-1", now, "ecb")
+	assert.ErrorIs(t, err, ErrInvalidRate)
+
+	_, err = NewExchangeRate("dollar", "eur", "1", now, "ecb")
+	assert.ErrorIs(t, err, ErrInvalidBaseCurrency)
+
+	_, err = NewExchangeRate("usd", "eur", "1", time.Time{}, "ecb")
+	assert.ErrorIs(t, err, ErrEffectiveAtIsRequired)
+}
+
+func TestExchangeRateConvert(t *testing.T) {
+	rate, err := NewExchangeRate("USD", "EUR", "0.5", time.Now(), "ecb")
+	assert.NoError(t, err)
+
+	converted, err := rate.Convert(money.New(1000, "USD"), money.RoundHalfEven)
+	assert.NoError(t, err)
+	assert.Equal(t, money.New(500, "EUR"), converted)
+
+	_, err = rate.Convert(money.New(1000, "BRL"), money.RoundHalfEven)
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	inverse, err := rate.Inverse()
+	assert.NoError(t, err)
+	assert.Equal(t, "EUR", inverse.BaseCurrency)
+	assert.Equal(t, "2", inverse.Rate)
+}
@@ -0,0 +1,93 @@
+package entity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+// IdempotentRequest is a request made with an Idempotency-Key, kept with its
+// response for a while so that retrying it returns the same response
+// instead of running it again. Keys belong to a user and Fingerprint tells
+// whether a retry is the same request. Status is zero until the first
+// request is answered.
+type IdempotentRequest struct {
+	ID          entity.ID `json:"id"`
+	UserID      string    `json:"-" gorm:"size:255;uniqueIndex:idx_idempotency_key"`
+	Key         string    `json:"key" gorm:"column:idempotency_key;size:255;uniqueIndex:idx_idempotency_key"`
+	Fingerprint string    `json:"-" gorm:"size:64"`
+	Status      int       `json:"status"`
+	Header      string    `json:"-"`
+	Body        []byte    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+}
+
+// AnonymousIdempotencyPrefix starts the user of anonymous requests, which
+// user IDs never do.
+const AnonymousIdempotencyPrefix = "anonymous:"
+
+var (
+	ErrIdempotencyKeyReused      = errors.New("the idempotency key was already used with a different request")
+	ErrRequestInProgress         = errors.New("a request with the same idempotency key is still being handled")
+	ErrIdempotentRequestTooLarge = errors.New("the request is too large to be made with an idempotency key")
+)
+
+// NewIdempotentRequest records a request of the user made with the key,
+// fingerprinted by its method, path and body, to be kept until expiresAt.
+// Anonymous requests, without a user, are kept apart under their
+// fingerprint, so that only the very same request made again with the key
+// can reach its response.
+func NewIdempotentRequest(userID, key, method, path string, body []byte, expiresAt time.Time) (*IdempotentRequest, error) {
+	if key == "" || len(key) > 255 {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	fingerprint := hex.EncodeToString(hash.Sum(nil))
+	if userID == "" {
+		userID = AnonymousIdempotencyPrefix + fingerprint
+	}
+	return &IdempotentRequest{
+		ID:          entity.NewID(),
+		UserID:      userID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// Matches tells whether other is the same request, made again.
+func (r *IdempotentRequest) Matches(other *IdempotentRequest) bool {
+	return r.Fingerprint == other.Fingerprint
+}
+
+// IsAnswered tells whether the response of the request was recorded.
+func (r *IdempotentRequest) IsAnswered() bool {
+	return r.Status != 0
+}
+
+// Answer records the response of the request.
+func (r *IdempotentRequest) Answer(status int, header http.Header, body []byte) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	r.Status, r.Header, r.Body = status, string(data), body
+	return nil
+}
+
+// ResponseHeader returns the headers of the recorded response.
+func (r *IdempotentRequest) ResponseHeader() http.Header {
+	header := http.Header{}
+	json.Unmarshal([]byte(r.Header), &header)
+	return header
+}
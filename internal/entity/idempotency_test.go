@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIdempotentRequest(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	request, err := NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", []byte(`{"name":"A"}`), expiresAt)
+	assert.NoError(t, err)
+	assert.Len(t, request.Fingerprint, 64)
+	assert.Equal(t, expiresAt, request.ExpiresAt)
+	assert.False(t, request.IsAnswered())
+
+	same, _ := NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", []byte(`{"name":"A"}`), expiresAt)
+	assert.True(t, request.Matches(same))
+	otherBody, _ := NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", []byte(`{"name":"B"}`), expiresAt)
+	assert.False(t, request.Matches(otherBody))
+	otherPath, _ := NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/users", []byte(`{"name":"A"}`), expiresAt)
+	assert.False(t, request.Matches(otherPath))
+
+	_, err = NewIdempotentRequest("user-1", "", http.MethodPost, "/products", nil, expiresAt)
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+	_, err = NewIdempotentRequest("user-1", strings.Repeat("k", 256), http.MethodPost, "/products", nil, expiresAt)
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
+
+func TestIdempotentRequestAnswer(t *testing.T) {
+	request, _ := NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", nil, time.Now().Add(time.Hour))
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Location", "/products/1")
+
+	assert.NoError(t, request.Answer(http.StatusCreated, header, []byte(`{"id":"1"}`)))
+	assert.True(t, request.IsAnswered())
+	assert.Equal(t, header, request.ResponseHeader())
+	assert.Equal(t, `{"id":"1"}`, string(request.Body))
+}
@@ -0,0 +1,143 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+const (
+	JobProductImport = "product_import"
+	JobProductExport = "product_export"
+	JobProductPurge  = "product_purge"
+)
+
+// Job is an operation run in the background by the job workers. Payload
+// holds its parameters and Result what it produced, both as JSON, and File
+// the blob key of the file it produced, if any. A failed attempt is retried
+// at RunAt until MaxAttempts is reached.
+type Job struct {
+	ID              entity.ID  `json:"id"`
+	Type            string     `json:"type" gorm:"size:64"`
+	UserID          string     `json:"-" gorm:"index"`
+	Status          JobStatus  `json:"status" gorm:"index:idx_job_queue"`
+	Payload         string     `json:"-"`
+	Progress        int64      `json:"progress"`
+	Result          string     `json:"-"`
+	File            string     `json:"-"`
+	Error           string     `json:"error,omitempty"`
+	Attempts        int        `json:"attempts"`
+	MaxAttempts     int        `json:"max_attempts"`
+	RunAt           time.Time  `json:"run_at" gorm:"index:idx_job_queue"`
+	CancelRequested bool       `json:"cancel_requested"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      *time.Time `json:"finished_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+var (
+	ErrJobTypeIsRequired = errors.New("job type is required")
+	ErrInvalidJobPayload = errors.New("invalid job payload")
+	ErrJobNotCancelable  = errors.New("the job already finished")
+	ErrJobCancelled      = errors.New("the job was cancelled")
+	ErrJobInterrupted    = errors.New("the job was interrupted")
+	ErrJobHasNoFile      = errors.New("the job has no file to download")
+)
+
+// NewJob queues a job of the given type for the user, to be run right away
+// and tried up to maxAttempts times.
+func NewJob(jobType, userID string, payload any, maxAttempts int) (*Job, error) {
+	if jobType == "" {
+		return nil, ErrJobTypeIsRequired
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, ErrInvalidJobPayload
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	now := time.Now()
+	return &Job{
+		ID:          entity.NewID(),
+		Type:        jobType,
+		UserID:      userID,
+		Status:      JobQueued,
+		Payload:     string(data),
+		MaxAttempts: maxAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// DecodePayload reads the parameters of the job into v.
+func (j *Job) DecodePayload(v any) error {
+	err := json.Unmarshal([]byte(j.Payload), v)
+	if err != nil {
+		return ErrInvalidJobPayload
+	}
+	return nil
+}
+
+func (j *Job) IsFinished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
+
+// Succeed finishes the job with its result.
+func (j *Job) Succeed(result any, now time.Time) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	j.Status, j.Result, j.Error = JobSucceeded, string(data), ""
+	j.FinishedAt = &now
+	return nil
+}
+
+// Fail records a failed attempt. The job goes back to the queue to be tried
+// again after backoff when retry is set and attempts are left, and fails
+// for good otherwise.
+func (j *Job) Fail(err error, retry bool, backoff time.Duration, now time.Time) {
+	j.Error = err.Error()
+	if retry && j.Attempts < j.MaxAttempts {
+		j.Status = JobQueued
+		j.RunAt = now.Add(backoff)
+		return
+	}
+	j.Status = JobFailed
+	j.FinishedAt = &now
+}
+
+// Cancel finishes a job that was cancelled while running.
+func (j *Job) Cancel(now time.Time) {
+	j.Status, j.Error = JobCancelled, ErrJobCancelled.Error()
+	j.FinishedAt = &now
+}
+
+// Backoff is how long to wait before the next attempt: base doubled for each
+// attempt already made, up to limit.
+func (j *Job) Backoff(base, limit time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < j.Attempts && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if backoff > limit {
+		return limit
+	}
+	return backoff
+}
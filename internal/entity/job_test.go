@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewJob(t *testing.T) {
+	job, err := NewJob(JobProductExport, "user-1", map[string]string{"format": "csv"}, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, JobQueued, job.Status)
+	assert.Equal(t, 3, job.MaxAttempts)
+
+	var payload map[string]string
+	assert.NoError(t, job.DecodePayload(&payload))
+	assert.Equal(t, "csv", payload["format"])
+
+	_, err = NewJob("", "user-1", nil, 3)
+	assert.ErrorIs(t, err, ErrJobTypeIsRequired)
+	_, err = NewJob(JobProductExport, "user-1", func() {}, 3)
+	assert.ErrorIs(t, err, ErrInvalidJobPayload)
+}
+
+func TestJobFailRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	job, _ := NewJob(JobProductImport, "user-1", nil, 3)
+
+	job.Attempts = 1
+	job.Fail(errors.New("boom"), true, job.Backoff(time.Second, time.Minute), now)
+	assert.Equal(t, JobQueued, job.Status)
+	assert.Equal(t, now.Add(time.Second), job.RunAt)
+	assert.Equal(t, "boom", job.Error)
+
+	job.Attempts = 2
+	assert.Equal(t, 2*time.Second, job.Backoff(time.Second, time.Minute))
+	job.Attempts = 10
+	assert.Equal(t, time.Minute, job.Backoff(time.Second, time.Minute))
+
+	job.Attempts = 3
+	job.Fail(errors.New("boom"), true, time.Second, now)
+	assert.Equal(t, JobFailed, job.Status)
+	assert.True(t, job.IsFinished())
+}
+
+func TestJobFailWithoutRetry(t *testing.T) {
+	job, _ := NewJob(JobProductImport, "user-1", nil, 3)
+	job.Attempts = 1
+	job.Fail(errors.New("malformed file"), false, time.Second, time.Now())
+	assert.Equal(t, JobFailed, job.Status)
+	assert.NotNil(t, job.FinishedAt)
+}
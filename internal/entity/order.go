@@ -0,0 +1,177 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+type OrderStatus string
+
+const (
+	OrderPending   OrderStatus = "pending"
+	OrderPaid      OrderStatus = "paid"
+	OrderShipped   OrderStatus = "shipped"
+	OrderDelivered OrderStatus = "delivered"
+	OrderCancelled OrderStatus = "cancelled"
+)
+
+// orderTransitions lists the statuses an order can move to from each status.
+// Delivered and cancelled orders are final.
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+	OrderShipped: {OrderDelivered},
+}
+
+// Order is a purchase of a user. Its items keep the product name and price
+// at the time of the purchase, so later catalog changes never alter it.
+// Total is what the user pays, after the discount of the coupon if any.
+type Order struct {
+	ID         entity.ID    `json:"id"`
+	UserID     string       `json:"user_id" gorm:"index"`
+	Status     OrderStatus  `json:"status" gorm:"index"`
+	Items      []OrderItem  `json:"items" validate:"min=1"`
+	CouponCode string       `json:"coupon_code,omitempty" gorm:"size:64"`
+	Discount   *money.Money `json:"discount,omitempty" gorm:"embedded;embeddedPrefix:discount_"`
+	Total      money.Money  `json:"total" gorm:"embedded;embeddedPrefix:total_" validate:"money_positive"`
+	// PayingUntil is set while a payment attempt is running, so that the
+	// order is charged by one attempt at a time and not cancelled meanwhile.
+	// It lapses on its own if the attempt never finishes.
+	PayingUntil *time.Time `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// OrderItem is a product, or one of its variants, bought in an order. The
+// stock of the item is held by its reservation until the order is paid.
+type OrderItem struct {
+	ID            entity.ID   `json:"id"`
+	OrderID       entity.ID   `json:"-" gorm:"index"`
+	ProductID     entity.ID   `json:"product_id"`
+	VariantID     *entity.ID  `json:"variant_id,omitempty"`
+	ProductName   string      `json:"product_name"`
+	SKU           string      `json:"sku,omitempty"`
+	UnitPrice     money.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	Quantity      int64       `json:"quantity"`
+	Total         money.Money `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	ReservationID entity.ID   `json:"-"`
+}
+
+var (
+	ErrOrderIsEmpty           = errors.New("an order needs at least one item")
+	ErrOrderCurrencyMismatch  = errors.New("all the items of an order must use the same currency")
+	ErrInvalidOrderTotal      = errors.New("the order total must be positive")
+	ErrInvalidOrderStatus     = errors.New("invalid order status")
+	ErrInvalidOrderTransition = errors.New("the order cannot move to the requested status")
+	ErrCartItemUnavailable    = errors.New("the cart has items that are no longer available")
+	ErrCartPricesChanged      = errors.New("the cart has prices that changed; accept them before checking out")
+)
+
+var orderErrors = map[string]error{
+	"items.min":            ErrOrderIsEmpty,
+	"total.money_positive": ErrInvalidOrderTotal,
+}
+
+// NewOrder creates a pending order for the cart lines, at their current
+// prices.
+func NewOrder(userID string, lines []CartLine) (*Order, error) {
+	order := &Order{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Status:    OrderPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	for _, line := range lines {
+		if !line.Available() {
+			return nil, ErrCartItemUnavailable
+		}
+		lineTotal, err := line.Total()
+		if err != nil {
+			return nil, err
+		}
+		item := OrderItem{
+			ID:          entity.NewID(),
+			OrderID:     order.ID,
+			ProductID:   line.Product.ID,
+			VariantID:   line.Item.VariantID,
+			ProductName: line.Product.Name,
+			UnitPrice:   line.CurrentPrice(),
+			Quantity:    line.Item.Quantity,
+			Total:       lineTotal,
+		}
+		if line.Variant != nil {
+			item.SKU = line.Variant.SKU
+		}
+
+		if len(order.Items) == 0 {
+			order.Total = item.Total
+		} else {
+			total, err := order.Total.Add(item.Total)
+			if errors.Is(err, money.ErrCurrencyMismatch) {
+				return nil, ErrOrderCurrencyMismatch
+			}
+			if err != nil {
+				return nil, err
+			}
+			order.Total = total
+		}
+		order.Items = append(order.Items, item)
+	}
+
+	err := validate(order, orderErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// ApplyCoupon takes the discount of a valid coupon evaluation off the
+// order total.
+func (o *Order) ApplyCoupon(evaluation CouponEvaluation) error {
+	if !evaluation.Valid {
+		return ErrCouponRejected
+	}
+	total, err := o.Total.Sub(*evaluation.Discount)
+	if err != nil {
+		return ErrCouponRejected
+	}
+	o.CouponCode = evaluation.Code
+	o.Discount = evaluation.Discount
+	o.Total = total
+	return nil
+}
+
+func ParseOrderStatus(status string) (OrderStatus, error) {
+	switch s := OrderStatus(status); s {
+	case OrderPending, OrderPaid, OrderShipped, OrderDelivered, OrderCancelled:
+		return s, nil
+	}
+	return "", ErrInvalidOrderStatus
+}
+
+// CanTransition tells whether the order can move to status.
+func (o *Order) CanTransition(status OrderStatus) bool {
+	for _, next := range orderTransitions[o.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the order to status, failing with
+// ErrInvalidOrderTransition when the state machine does not allow it.
+func (o *Order) Transition(status OrderStatus) error {
+	if !o.CanTransition(status) {
+		return ErrInvalidOrderTransition
+	}
+	o.Status = status
+	o.UpdatedAt = time.Now()
+	return nil
+}
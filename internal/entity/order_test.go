@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewOrder(t *testing.T) {
+	shirt, _ := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	price := money.MustParse("24.00", "USD")
+	variant, _ := NewVariant(shirt, "TSHIRT-XL", nil, &price, 1)
+	mug, _ := NewProduct("Mug", money.MustParse("8.00", "USD"))
+
+	shirtItem, _ := NewCartItem("user-1", shirt, variant, 1)
+	mugItem, _ := NewCartItem("user-1", mug, nil, 2)
+
+	order, err := NewOrder("user-1", []CartLine{
+		{Item: *shirtItem, Product: shirt, Variant: variant},
+		{Item: *mugItem, Product: mug},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, OrderPending, order.Status)
+	assert.Len(t, order.Items, 2)
+	assert.Equal(t, "T-shirt", order.Items[0].ProductName)
+	assert.Equal(t, "TSHIRT-XL", order.Items[0].SKU)
+	assert.Equal(t, price, order.Items[0].UnitPrice)
+	assert.Equal(t, money.MustParse("16.00", "USD"), order.Items[1].Total)
+	assert.Equal(t, money.MustParse("40.00", "USD"), order.Total)
+
+	// the order keeps the price it was placed at
+	shirt.Name = "Shirt"
+	variant.Price = nil
+	assert.Equal(t, "T-shirt", order.Items[0].ProductName)
+	assert.Equal(t, price, order.Items[0].UnitPrice)
+}
+
+func TestNewOrderErrors(t *testing.T) {
+	shirt, _ := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	book, _ := NewProduct("Livro", money.MustParse("50.00", "BRL"))
+	shirtItem, _ := NewCartItem("user-1", shirt, nil, 1)
+	bookItem, _ := NewCartItem("user-1", book, nil, 1)
+
+	_, err := NewOrder("user-1", nil)
+	assert.ErrorIs(t, err, ErrOrderIsEmpty)
+
+	_, err = NewOrder("user-1", []CartLine{{Item: *shirtItem, Product: shirt}, {Item: *bookItem, Product: book}})
+	assert.ErrorIs(t, err, ErrOrderCurrencyMismatch)
+
+	_, err = NewOrder("user-1", []CartLine{{Item: *shirtItem}})
+	assert.ErrorIs(t, err, ErrCartItemUnavailable)
+
+	_, err = NewOrder("user-1", expensiveLines(t, 10))
+	assert.ErrorIs(t, err, money.ErrAmountOverflow)
+
+	_, err = NewOrder("user-1", expensiveLines(t, 2))
+	assert.ErrorIs(t, err, ErrInvalidOrderTotal)
+
+	free := *shirt
+	free.Price = money.New(0, "USD")
+	_, err = NewOrder("user-1", []CartLine{{Item: *shirtItem, Product: &free}})
+	assert.ErrorIs(t, err, ErrInvalidOrderTotal)
+}
+
+func TestOrderTransition(t *testing.T) {
+	order := &Order{Status: OrderPending}
+	assert.ErrorIs(t, order.Transition(OrderShipped), ErrInvalidOrderTransition)
+	assert.NoError(t, order.Transition(OrderPaid))
+	assert.NoError(t, order.Transition(OrderShipped))
+	assert.ErrorIs(t, order.Transition(OrderCancelled), ErrInvalidOrderTransition)
+	assert.NoError(t, order.Transition(OrderDelivered))
+	assert.Equal(t, OrderDelivered, order.Status)
+
+	for _, status := range []OrderStatus{OrderPending, OrderPaid, OrderShipped, OrderCancelled} {
+		assert.ErrorIs(t, order.Transition(status), ErrInvalidOrderTransition)
+	}
+
+	_, err := ParseOrderStatus("lost")
+	assert.ErrorIs(t, err, ErrInvalidOrderStatus)
+	status, err := ParseOrderStatus("shipped")
+	assert.NoError(t, err)
+	assert.Equal(t, OrderShipped, status)
+}
@@ -0,0 +1,103 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentAuthorized PaymentStatus = "authorized"
+	PaymentCaptured   PaymentStatus = "captured"
+	PaymentDeclined   PaymentStatus = "declined"
+	PaymentRefunded   PaymentStatus = "refunded"
+	PaymentFailed     PaymentStatus = "failed"
+)
+
+var paymentTransitions = map[PaymentStatus][]PaymentStatus{
+	PaymentAuthorized: {PaymentCaptured, PaymentFailed},
+	PaymentCaptured:   {PaymentRefunded},
+}
+
+// Payment is an attempt to pay an order through a payment provider. Each
+// attempt has an idempotency key chosen by the client, so that retrying a
+// request never charges the order twice.
+type Payment struct {
+	ID             entity.ID     `json:"id"`
+	OrderID        entity.ID     `json:"order_id" gorm:"uniqueIndex:idx_payment_attempt"`
+	IdempotencyKey string        `json:"idempotency_key" gorm:"size:255;uniqueIndex:idx_payment_attempt" validate:"required,max=255"`
+	Provider       string        `json:"provider"`
+	ProviderRef    string        `json:"-" gorm:"index"`
+	Method         string        `json:"method"`
+	Amount         money.Money   `json:"amount" gorm:"embedded;embeddedPrefix:amount_"`
+	Status         PaymentStatus `json:"status"`
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// PaymentEvent is a provider webhook already applied, kept so that
+// redelivered events are ignored.
+type PaymentEvent struct {
+	ID         string `gorm:"primaryKey;size:255"`
+	Type       string `gorm:"size:64"`
+	ReceivedAt time.Time
+}
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+	ErrOrderNotPayable       = errors.New("only pending orders can be paid")
+	ErrPaymentInProgress     = errors.New("another payment of the order is in progress")
+	ErrPaymentDeclined       = errors.New("the payment was declined")
+	ErrPaymentNotRefundable  = errors.New("the order has no captured payment to refund")
+)
+
+var paymentErrors = map[string]error{
+	"idempotency_key.required": ErrInvalidIdempotencyKey,
+	"idempotency_key.max":      ErrInvalidIdempotencyKey,
+}
+
+func NewPayment(order *Order, idempotencyKey, provider, method string) (*Payment, error) {
+	payment := &Payment{
+		ID:             entity.NewID(),
+		OrderID:        order.ID,
+		IdempotencyKey: idempotencyKey,
+		Provider:       provider,
+		Method:         method,
+		Amount:         order.Total,
+		CreatedAt:      time.Now(),
+		UpdatedAt:      time.Now(),
+	}
+
+	err := validate(payment, paymentErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// CanTransition tells whether the payment can move to status.
+func (p *Payment) CanTransition(status PaymentStatus) bool {
+	for _, next := range paymentTransitions[p.Status] {
+		if next == status {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderStatus is the status an order moves to when its payment reaches
+// status, if any.
+func (status PaymentStatus) OrderStatus() (OrderStatus, bool) {
+	switch status {
+	case PaymentCaptured:
+		return OrderPaid, true
+	case PaymentRefunded:
+		return OrderCancelled, true
+	}
+	return "", false
+}
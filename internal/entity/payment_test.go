@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPayment(t *testing.T) {
+	order := &Order{Total: money.MustParse("12.50", "USD")}
+
+	payment, err := NewPayment(order, "key-1", "fake", "fake_card")
+	assert.NoError(t, err)
+	assert.Equal(t, order.Total, payment.Amount)
+
+	_, err = NewPayment(order, "", "fake", "fake_card")
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+	_, err = NewPayment(order, strings.Repeat("k", 256), "fake", "fake_card")
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+}
+
+func TestPaymentTransitions(t *testing.T) {
+	payment := &Payment{Status: PaymentAuthorized}
+	assert.True(t, payment.CanTransition(PaymentCaptured))
+	assert.False(t, payment.CanTransition(PaymentRefunded))
+
+	payment.Status = PaymentCaptured
+	assert.True(t, payment.CanTransition(PaymentRefunded))
+	assert.False(t, payment.CanTransition(PaymentCaptured))
+
+	payment.Status = PaymentDeclined
+	assert.False(t, payment.CanTransition(PaymentCaptured))
+
+	status, ok := PaymentCaptured.OrderStatus()
+	assert.True(t, ok)
+	assert.Equal(t, OrderPaid, status)
+	_, ok = PaymentFailed.OrderStatus()
+	assert.False(t, ok)
+}
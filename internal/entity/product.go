@@ -5,24 +5,46 @@ import (
 	"time"
 
 	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
 )
 
+// Product is an item of the catalog. RatingAverage and RatingCount sum up
+// its published reviews and are maintained by the review repository.
 type Product struct {
-	ID        entity.ID `json:"id"`
-	Name      string    `json:"name"`
-	Price     float64   `json:"price"`
-	CreatedAt time.Time `json:"created_at"`
+	ID            entity.ID   `json:"id" validate:"required,uuid_id"`
+	Name          string      `json:"name" validate:"required,trimmed,max=120"`
+	Price         money.Money `json:"price" gorm:"embedded;embeddedPrefix:price_" validate:"required,money_positive"`
+	RatingAverage float64     `json:"rating_average" gorm:"index"`
+	RatingCount   int64       `json:"rating_count"`
+	CreatedAt     time.Time   `json:"created_at"`
 }
 
 var (
 	ErrIDIsRequired    = errors.New("id is required")
 	ErrInvalidID       = errors.New("invalid id")
 	ErrNameIsRequired  = errors.New("name is required")
+	ErrInvalidName     = errors.New("invalid name")
 	ErrPriceIsRequired = errors.New("price is required")
 	ErrInvalidPrice    = errors.New("invalid price")
+	ErrInvalidCurrency = errors.New("invalid currency")
+	// ErrCurrencyLocked is returned when the currency of a product changes
+	// while variants or scheduled prices are still priced in the old one.
+	ErrCurrencyLocked = errors.New("the currency of a product with variant or scheduled prices cannot change")
 )
 
-func NewProduct(name string, price float64) (*Product, error) {
+var productErrors = map[string]error{
+	"id.required":          ErrIDIsRequired,
+	"id.uuid_id":           ErrInvalidID,
+	"name.required":        ErrNameIsRequired,
+	"name.trimmed":         ErrInvalidName,
+	"name.max":             ErrInvalidName,
+	"price.required":       ErrPriceIsRequired,
+	"price.money_positive": ErrInvalidPrice,
+	"currency.required":    ErrInvalidCurrency,
+	"currency.iso4217":     ErrInvalidCurrency,
+}
+
+func NewProduct(name string, price money.Money) (*Product, error) {
 	product := &Product{
 		ID:        entity.NewID(),
 		Name:      name,
@@ -39,20 +61,5 @@ func NewProduct(name string, price float64) (*Product, error) {
 }
 
 func (p *Product) Validate() error {
-	if p.ID.String() == "" {
-		return ErrIDIsRequired
-	}
-	if _, err := entity.ParseID(p.ID.String()); err != nil {
-		return ErrInvalidID
-	}
-	if p.Name == "" {
-		return ErrNameIsRequired
-	}
-	if p.Price == 0 {
-		return ErrPriceIsRequired
-	}
-	if p.Price < 0 {
-		return ErrInvalidPrice
-	}
-	return nil
+	return validate(p, productErrors)
 }
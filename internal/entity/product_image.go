@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+// ImageTypes are the image content types accepted for products, with the
+// file extension their blobs are stored under.
+var ImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// ProductImage is an image of a product kept in the blob store along with
+// its thumbnail. Images are shown in Position order.
+type ProductImage struct {
+	ID           entity.ID `json:"id"`
+	ProductID    entity.ID `json:"product_id" gorm:"index"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+var (
+	ErrUnsupportedImageType = errors.New("images must be JPEG, PNG or GIF")
+	ErrImageTooLarge        = errors.New("image is too large")
+	ErrInvalidImageOrder    = errors.New("the order must list every image of the product exactly once")
+)
+
+// NewProductImage describes an image of contentType for the product. The
+// thumbnail is stored with thumbnailType, which may differ from the image's.
+func NewProductImage(productID entity.ID, contentType, thumbnailType string, size int64, width, height int) (*ProductImage, error) {
+	ext, ok := ImageTypes[contentType]
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+	thumbnailExt, ok := ImageTypes[thumbnailType]
+	if !ok {
+		return nil, ErrUnsupportedImageType
+	}
+
+	id := entity.NewID()
+	prefix := "products/" + productID.String() + "/images/" + id.String()
+	return &ProductImage{
+		ID:           id,
+		ProductID:    productID,
+		Key:          prefix + ext,
+		ThumbnailKey: prefix + "_thumb" + thumbnailExt,
+		ContentType:  contentType,
+		Size:         size,
+		Width:        width,
+		Height:       height,
+		CreatedAt:    time.Now(),
+	}, nil
+}
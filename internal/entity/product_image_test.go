@@ -0,0 +1,23 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProductImage(t *testing.T) {
+	productID := entity.NewID()
+
+	image, err := NewProductImage(productID, "image/gif", "image/png", 1024, 640, 480)
+	assert.NoError(t, err)
+	assert.Equal(t, productID, image.ProductID)
+	assert.True(t, strings.HasPrefix(image.Key, "products/"+productID.String()+"/images/"))
+	assert.True(t, strings.HasSuffix(image.Key, ".gif"))
+	assert.True(t, strings.HasSuffix(image.ThumbnailKey, "_thumb.png"))
+
+	_, err = NewProductImage(productID, "image/webp", "image/png", 1024, 640, 480)
+	assert.ErrorIs(t, err, ErrUnsupportedImageType)
+}
@@ -0,0 +1,36 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// ProductPriceHistory records one change of a product's price.
+type ProductPriceHistory struct {
+	ID        entity.ID   `json:"id"`
+	ProductID entity.ID   `json:"product_id" gorm:"index"`
+	OldPrice  money.Money `json:"old_price" gorm:"embedded;embeddedPrefix:old_price_"`
+	NewPrice  money.Money `json:"new_price" gorm:"embedded;embeddedPrefix:new_price_"`
+	ChangedBy string      `json:"changed_by"`
+	ChangedAt time.Time   `json:"changed_at" gorm:"index"`
+}
+
+var ErrPriceNotFound = errors.New("the product had no price at that date")
+
+func (ProductPriceHistory) TableName() string {
+	return "product_price_history"
+}
+
+func NewProductPriceHistory(productID entity.ID, oldPrice, newPrice money.Money, changedBy string) *ProductPriceHistory {
+	return &ProductPriceHistory{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+		ChangedBy: changedBy,
+		ChangedAt: time.Now(),
+	}
+}
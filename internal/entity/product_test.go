@@ -1,44 +1,92 @@
 package entity
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewProduct(t *testing.T) {
-	p, err := NewProduct("Product 1", 100.0)
+	p, err := NewProduct("Product 1", money.MustParse("100.00", "USD"))
 	assert.Nil(t, err)
 	assert.NotNil(t, p)
 	assert.NotEmpty(t, p.ID)
 	assert.Equal(t, "Product 1", p.Name)
-	assert.Equal(t, 100.0, p.Price)
+	assert.Equal(t, money.New(10000, "USD"), p.Price)
 	assert.NotNil(t, p.CreatedAt)
 }
 
 func TestNewProductWhenNameIsRequired(t *testing.T) {
-	p, err := NewProduct("", 100.0)
+	p, err := NewProduct("", money.MustParse("100.00", "USD"))
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, ErrNameIsRequired, err)
+	assert.ErrorIs(t, err, ErrNameIsRequired)
 }
 
 func TestNewProductWhenPriceIsRequired(t *testing.T) {
-	p, err := NewProduct("Product 1", 0.0)
+	p, err := NewProduct("Product 1", money.Money{})
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, ErrPriceIsRequired, err)
+	assert.ErrorIs(t, err, ErrPriceIsRequired)
 }
 
 func TestNewProductWhenPriceIsInvalid(t *testing.T) {
-	p, err := NewProduct("Product 1", -1.0)
+	p, err := NewProduct("Product 1", money.MustParse("-1", "USD"))
 	assert.NotNil(t, err)
 	assert.Nil(t, p)
-	assert.Equal(t, ErrInvalidPrice, err)
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+}
+
+func TestNewProductWhenNameIsInvalid(t *testing.T) {
+	p, err := NewProduct(" Product 1", money.MustParse("100.00", "USD"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidName)
+
+	p, err = NewProduct(strings.Repeat("a", 121), money.MustParse("100.00", "USD"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidName)
+}
+
+func TestNewProductWhenPriceIsZero(t *testing.T) {
+	p, err := NewProduct("Product 1", money.New(0, "USD"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	var fields validator.ValidationErrors
+	assert.ErrorAs(t, err, &fields)
+	assert.Equal(t, "money_positive", fields[0].Tag())
+}
+
+func TestNewProductWhenPriceIsTooLarge(t *testing.T) {
+	p, err := NewProduct("Product 1", money.New(money.MaxAmount+1, "USD"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	p, err = NewProduct("Product 1", money.New(money.MaxAmount, "USD"))
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+}
+
+func TestNewProductWhenCurrencyIsInvalid(t *testing.T) {
+	p, err := NewProduct("Product 1", money.New(100, "XYZ"))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+
+	p, err = NewProduct("Product 1", money.New(100, ""))
+	assert.Nil(t, p)
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+}
+
+func TestProductValidateWhenIDIsMissing(t *testing.T) {
+	p := &Product{Name: "Product 1", Price: money.New(1000, "USD")}
+	assert.ErrorIs(t, p.Validate(), ErrIDIsRequired)
 }
 
 func TestProductValidate(t *testing.T) {
-	p, err := NewProduct("Product 1", 100.0)
+	p, err := NewProduct("Product 1", money.MustParse("100.00", "USD"))
 	assert.Nil(t, err)
 	assert.Nil(t, p.Validate())
 }
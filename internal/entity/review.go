@@ -0,0 +1,89 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewPublished ReviewStatus = "published"
+	// ReviewHidden reviews were taken down by an admin; they are left out of
+	// the product rating.
+	ReviewHidden ReviewStatus = "hidden"
+)
+
+// Review is the opinion of a user about a product. Users review each
+// product once and can edit their review afterwards.
+type Review struct {
+	ID        entity.ID    `json:"id"`
+	ProductID entity.ID    `json:"product_id" gorm:"uniqueIndex:idx_review_author"`
+	UserID    string       `json:"user_id" gorm:"size:255;uniqueIndex:idx_review_author"`
+	Rating    int          `json:"rating" validate:"min=1,max=5"`
+	Text      string       `json:"text" validate:"max=2000"`
+	Status    ReviewStatus `json:"status" gorm:"index"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+var (
+	ErrInvalidRating       = errors.New("rating must be between 1 and 5")
+	ErrInvalidReviewText   = errors.New("review text is too long")
+	ErrInvalidReviewStatus = errors.New("invalid review status")
+	ErrReviewAlreadyExists = errors.New("the product was already reviewed by this user")
+	ErrNotReviewAuthor     = errors.New("only the author can edit a review")
+)
+
+var reviewErrors = map[string]error{
+	"rating.min": ErrInvalidRating,
+	"rating.max": ErrInvalidRating,
+	"text.max":   ErrInvalidReviewText,
+}
+
+func NewReview(productID entity.ID, userID string, rating int, text string) (*Review, error) {
+	review := &Review{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		UserID:    userID,
+		Rating:    rating,
+		Text:      strings.TrimSpace(text),
+		Status:    ReviewPublished,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := review.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+func (r *Review) Validate() error {
+	return validate(r, reviewErrors)
+}
+
+// Edit changes the rating and text of the review on behalf of userID, who
+// must be its author.
+func (r *Review) Edit(userID string, rating int, text string) error {
+	if r.UserID != userID {
+		return ErrNotReviewAuthor
+	}
+	r.Rating = rating
+	r.Text = strings.TrimSpace(text)
+	r.UpdatedAt = time.Now()
+	return r.Validate()
+}
+
+func ParseReviewStatus(status string) (ReviewStatus, error) {
+	switch s := ReviewStatus(status); s {
+	case ReviewPublished, ReviewHidden:
+		return s, nil
+	}
+	return "", ErrInvalidReviewStatus
+}
@@ -0,0 +1,41 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReview(t *testing.T) {
+	productID := entity.NewID()
+
+	review, err := NewReview(productID, "user-1", 5, "  Great mug  ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Great mug", review.Text)
+	assert.Equal(t, ReviewPublished, review.Status)
+
+	_, err = NewReview(productID, "user-1", 0, "")
+	assert.ErrorIs(t, err, ErrInvalidRating)
+	_, err = NewReview(productID, "user-1", 6, "")
+	assert.ErrorIs(t, err, ErrInvalidRating)
+	_, err = NewReview(productID, "user-1", 3, strings.Repeat("a", 2001))
+	assert.ErrorIs(t, err, ErrInvalidReviewText)
+}
+
+func TestEditReview(t *testing.T) {
+	review, _ := NewReview(entity.NewID(), "user-1", 5, "Great")
+
+	assert.ErrorIs(t, review.Edit("user-2", 1, "Bad"), ErrNotReviewAuthor)
+	assert.Equal(t, 5, review.Rating)
+
+	assert.NoError(t, review.Edit("user-1", 4, "Good"))
+	assert.Equal(t, 4, review.Rating)
+	assert.Equal(t, "Good", review.Text)
+
+	assert.ErrorIs(t, review.Edit("user-1", 9, "Good"), ErrInvalidRating)
+
+	_, err := ParseReviewStatus("deleted")
+	assert.ErrorIs(t, err, ErrInvalidReviewStatus)
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+type ScheduledPriceStatus string
+
+const (
+	// ScheduledPriceScheduled has not started yet.
+	ScheduledPriceScheduled ScheduledPriceStatus = "scheduled"
+	// ScheduledPriceActive is a promotion currently running.
+	ScheduledPriceActive ScheduledPriceStatus = "active"
+	// ScheduledPriceApplied replaced the product list price for good.
+	ScheduledPriceApplied ScheduledPriceStatus = "applied"
+	// ScheduledPriceEnded is a promotion whose window is over.
+	ScheduledPriceEnded     ScheduledPriceStatus = "ended"
+	ScheduledPriceCancelled ScheduledPriceStatus = "cancelled"
+)
+
+// ScheduledPrice is a price that takes effect at StartsAt. Without EndsAt it
+// becomes the product list price; with EndsAt it is a promotion that only
+// changes the effective price during its window.
+type ScheduledPrice struct {
+	ID        entity.ID            `json:"id"`
+	ProductID entity.ID            `json:"product_id" gorm:"index"`
+	Price     money.Money          `json:"price" gorm:"embedded;embeddedPrefix:price_" validate:"required,money_positive"`
+	StartsAt  time.Time            `json:"starts_at" gorm:"index" validate:"required"`
+	EndsAt    *time.Time           `json:"ends_at,omitempty" validate:"omitempty,gtfield=StartsAt"`
+	Status    ScheduledPriceStatus `json:"status" gorm:"index"`
+	CreatedBy string               `json:"created_by"`
+	CreatedAt time.Time            `json:"created_at"`
+}
+
+var (
+	ErrStartsAtIsRequired          = errors.New("starts_at is required")
+	ErrInvalidEndsAt               = errors.New("ends_at must be after starts_at")
+	ErrScheduledPriceCurrency      = errors.New("scheduled price must use the product currency")
+	ErrScheduledPriceNotCancelable = errors.New("only scheduled prices that have not started can be cancelled")
+)
+
+var scheduledPriceErrors = map[string]error{
+	"price.required":       ErrPriceIsRequired,
+	"price.money_positive": ErrInvalidPrice,
+	"currency.required":    ErrInvalidCurrency,
+	"currency.iso4217":     ErrInvalidCurrency,
+	"starts_at.required":   ErrStartsAtIsRequired,
+	"ends_at.gtfield":      ErrInvalidEndsAt,
+}
+
+func NewScheduledPrice(product *Product, price money.Money, startsAt time.Time, endsAt *time.Time, createdBy string) (*ScheduledPrice, error) {
+	scheduledPrice := &ScheduledPrice{
+		ID:        entity.NewID(),
+		ProductID: product.ID,
+		Price:     price,
+		StartsAt:  startsAt.UTC(),
+		Status:    ScheduledPriceScheduled,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	if endsAt != nil {
+		utc := endsAt.UTC()
+		scheduledPrice.EndsAt = &utc
+	}
+
+	err := validate(scheduledPrice, scheduledPriceErrors)
+	if err != nil {
+		return nil, err
+	}
+	if price.Currency != product.Price.Currency {
+		return nil, ErrScheduledPriceCurrency
+	}
+
+	return scheduledPrice, nil
+}
+
+func (s *ScheduledPrice) IsPromotion() bool {
+	return s.EndsAt != nil
+}
+
+// ActiveAt tells whether the price window includes t.
+func (s *ScheduledPrice) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartsAt) && (s.EndsAt == nil || t.Before(*s.EndsAt))
+}
+
+func (s *ScheduledPrice) Cancel() error {
+	if s.Status != ScheduledPriceScheduled {
+		return ErrScheduledPriceNotCancelable
+	}
+	s.Status = ScheduledPriceCancelled
+	return nil
+}
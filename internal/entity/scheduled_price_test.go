@@ -0,0 +1,60 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScheduledPrice(t *testing.T) {
+	product, err := NewProduct("Product 1", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+
+	startsAt := time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC)
+	endsAt := startsAt.Add(72 * time.Hour)
+	promotion, err := NewScheduledPrice(product, money.MustParse("7.50", "USD"), startsAt, &endsAt, "admin@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, product.ID, promotion.ProductID)
+	assert.Equal(t, ScheduledPriceScheduled, promotion.Status)
+	assert.True(t, promotion.IsPromotion())
+	assert.False(t, promotion.ActiveAt(startsAt.Add(-time.Second)))
+	assert.True(t, promotion.ActiveAt(startsAt))
+	assert.False(t, promotion.ActiveAt(endsAt))
+
+	change, err := NewScheduledPrice(product, money.MustParse("12.00", "USD"), startsAt, nil, "admin@example.com")
+	assert.NoError(t, err)
+	assert.False(t, change.IsPromotion())
+	assert.True(t, change.ActiveAt(startsAt.AddDate(1, 0, 0)))
+}
+
+func TestNewScheduledPriceWhenInvalid(t *testing.T) {
+	product, err := NewProduct("Product 1", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	startsAt := time.Now()
+
+	_, err = NewScheduledPrice(product, money.MustParse("7.50", "USD"), time.Time{}, nil, "")
+	assert.ErrorIs(t, err, ErrStartsAtIsRequired)
+
+	endsAt := startsAt.Add(-time.Hour)
+	_, err = NewScheduledPrice(product, money.MustParse("7.50", "USD"), startsAt, &endsAt, "")
+	assert.ErrorIs(t, err, ErrInvalidEndsAt)
+
+	_, err = NewScheduledPrice(product, money.New(0, "USD"), startsAt, nil, "")
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	_, err = NewScheduledPrice(product, money.MustParse("7.50", "EUR"), startsAt, nil, "")
+	assert.ErrorIs(t, err, ErrScheduledPriceCurrency)
+}
+
+func TestCancelScheduledPrice(t *testing.T) {
+	product, err := NewProduct("Product 1", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	scheduledPrice, err := NewScheduledPrice(product, money.MustParse("12.00", "USD"), time.Now(), nil, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, scheduledPrice.Cancel())
+	assert.Equal(t, ScheduledPriceCancelled, scheduledPrice.Status)
+	assert.ErrorIs(t, scheduledPrice.Cancel(), ErrScheduledPriceNotCancelable)
+}
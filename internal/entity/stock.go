@@ -0,0 +1,148 @@
+package entity
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+type StockReason string
+
+const (
+	StockRestock    StockReason = "restock"
+	StockReturn     StockReason = "return"
+	StockDamaged    StockReason = "damaged"
+	StockLost       StockReason = "lost"
+	StockCorrection StockReason = "correction"
+	// StockSale is recorded when a reservation is committed; it cannot be
+	// used for manual adjustments.
+	StockSale StockReason = "sale"
+)
+
+type StockReservationStatus string
+
+const (
+	StockReservationHeld      StockReservationStatus = "held"
+	StockReservationCommitted StockReservationStatus = "committed"
+	StockReservationReleased  StockReservationStatus = "released"
+	StockReservationExpired   StockReservationStatus = "expired"
+)
+
+// StockMovement is one entry of the stock ledger. The ledger is the source
+// of truth for stock: the quantity on hand of a product is the sum of its
+// movements.
+type StockMovement struct {
+	ID            entity.ID   `json:"id"`
+	ProductID     entity.ID   `json:"product_id" gorm:"index"`
+	Quantity      int64       `json:"quantity" validate:"ne=0"`
+	Reason        StockReason `json:"reason" validate:"required,oneof=restock return damaged lost correction sale"`
+	ReservationID *entity.ID  `json:"reservation_id,omitempty"`
+	Note          string      `json:"note,omitempty" validate:"max=255"`
+	CreatedBy     string      `json:"created_by"`
+	CreatedAt     time.Time   `json:"created_at" gorm:"index"`
+}
+
+func (StockMovement) TableName() string {
+	return "stock_ledger"
+}
+
+// StockLevel is the running balance of the ledger for a product together
+// with the quantity held by reservations, kept so that stock can be checked
+// and changed with a single conditional update.
+type StockLevel struct {
+	ProductID entity.ID `json:"product_id" gorm:"primaryKey"`
+	OnHand    int64     `json:"on_hand"`
+	Reserved  int64     `json:"reserved"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Available is the stock that can still be reserved or removed.
+func (l *StockLevel) Available() int64 {
+	return l.OnHand - l.Reserved
+}
+
+// StockReservation holds stock for a pending order until it is committed,
+// released or expires.
+type StockReservation struct {
+	ID        entity.ID              `json:"id"`
+	ProductID entity.ID              `json:"product_id" gorm:"index"`
+	Quantity  int64                  `json:"quantity" validate:"gt=0"`
+	Reference string                 `json:"reference,omitempty" validate:"max=255"`
+	Status    StockReservationStatus `json:"status" gorm:"index"`
+	ExpiresAt time.Time              `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+var (
+	ErrInvalidQuantity    = errors.New("invalid quantity")
+	ErrInvalidStockReason = errors.New("invalid stock reason")
+	ErrInvalidStockNote   = errors.New("invalid stock note")
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrReservationNotHeld = errors.New("the reservation is no longer held")
+)
+
+var stockErrors = map[string]error{
+	"quantity.ne":     ErrInvalidQuantity,
+	"quantity.gt":     ErrInvalidQuantity,
+	"reason.required": ErrInvalidStockReason,
+	"reason.oneof":    ErrInvalidStockReason,
+	"note.max":        ErrInvalidStockNote,
+	"reference.max":   ErrInvalidStockNote,
+}
+
+// NewStockAdjustment records a manual change of the quantity on hand, such
+// as a restock (positive) or damaged goods (negative).
+func NewStockAdjustment(productID entity.ID, quantity int64, reason StockReason, note, createdBy string) (*StockMovement, error) {
+	if reason == StockSale {
+		return nil, ErrInvalidStockReason
+	}
+	movement := &StockMovement{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		Quantity:  quantity,
+		Reason:    reason,
+		Note:      note,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+
+	err := validate(movement, stockErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// NewStockSale records the stock leaving with a committed reservation.
+func NewStockSale(reservation *StockReservation, createdBy string) *StockMovement {
+	return &StockMovement{
+		ID:            entity.NewID(),
+		ProductID:     reservation.ProductID,
+		Quantity:      -reservation.Quantity,
+		Reason:        StockSale,
+		ReservationID: &reservation.ID,
+		CreatedBy:     createdBy,
+		CreatedAt:     time.Now(),
+	}
+}
+
+func NewStockReservation(productID entity.ID, quantity int64, expiresAt time.Time, reference string) (*StockReservation, error) {
+	reservation := &StockReservation{
+		ID:        entity.NewID(),
+		ProductID: productID,
+		Quantity:  quantity,
+		Reference: reference,
+		Status:    StockReservationHeld,
+		ExpiresAt: expiresAt.UTC(),
+		CreatedAt: time.Now(),
+	}
+
+	err := validate(reservation, stockErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return reservation, nil
+}
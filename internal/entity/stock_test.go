@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStockAdjustment(t *testing.T) {
+	productID := entity.NewID()
+
+	movement, err := NewStockAdjustment(productID, 10, StockRestock, "supplier delivery", "admin@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, productID, movement.ProductID)
+	assert.Equal(t, int64(10), movement.Quantity)
+
+	movement, err = NewStockAdjustment(productID, -2, StockDamaged, "", "admin@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-2), movement.Quantity)
+}
+
+func TestNewStockAdjustmentWhenInvalid(t *testing.T) {
+	productID := entity.NewID()
+
+	_, err := NewStockAdjustment(productID, 0, StockRestock, "", "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+
+	_, err = NewStockAdjustment(productID, 1, "gift", "", "")
+	assert.ErrorIs(t, err, ErrInvalidStockReason)
+
+	_, err = NewStockAdjustment(productID, -1, StockSale, "", "")
+	assert.ErrorIs(t, err, ErrInvalidStockReason)
+}
+
+func TestNewStockReservation(t *testing.T) {
+	expiresAt := time.Now().Add(15 * time.Minute)
+	reservation, err := NewStockReservation(entity.NewID(), 2, expiresAt, "order-1")
+	assert.NoError(t, err)
+	assert.Equal(t, StockReservationHeld, reservation.Status)
+
+	sale := NewStockSale(reservation, "checkout")
+	assert.Equal(t, int64(-2), sale.Quantity)
+	assert.Equal(t, StockSale, sale.Reason)
+	assert.Equal(t, reservation.ID, *sale.ReservationID)
+
+	_, err = NewStockReservation(entity.NewID(), 0, expiresAt, "")
+	assert.ErrorIs(t, err, ErrInvalidQuantity)
+}
+
+func TestStockLevelAvailable(t *testing.T) {
+	level := StockLevel{OnHand: 10, Reserved: 3}
+	assert.Equal(t, int64(7), level.Available())
+}
@@ -0,0 +1,64 @@
+package entity
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+const maxTagLength = 50
+
+// ProductTag attaches a free-form tag to a product. Tags are stored as
+// lowercase slugs so "Summer Sale" and "summer-sale" are the same tag.
+type ProductTag struct {
+	ProductID entity.ID `json:"product_id" gorm:"primaryKey"`
+	Tag       string    `json:"tag" gorm:"primaryKey;index;size:50"`
+}
+
+// TagCount is a tag and the number of products using it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// NormalizeTag turns a tag into a lowercase slug: letters and digits are
+// kept, runs of anything else become a single dash.
+func NormalizeTag(tag string) (string, error) {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(tag)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if slug.Len() == 0 || slug.Len() > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	return slug.String(), nil
+}
+
+// NormalizeTags normalizes and deduplicates tags, keeping their order.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		slug, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			normalized = append(normalized, slug)
+		}
+	}
+	return normalized, nil
+}
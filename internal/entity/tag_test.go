@@ -0,0 +1,42 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := map[string]string{
+		"sale":            "sale",
+		"Summer Sale":     "summer-sale",
+		"  summer_sale  ": "summer-sale",
+		"4K--TV!":         "4k-tv",
+		"-eco-friendly-":  "eco-friendly",
+	}
+	for tag, expected := range tests {
+		slug, err := NormalizeTag(tag)
+		assert.NoError(t, err, tag)
+		assert.Equal(t, expected, slug, tag)
+	}
+
+	_, err := NormalizeTag(" -- ")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	_, err = NormalizeTag(strings.Repeat("a", 51))
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"Sale", "new", "sale", "SALE"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sale", "new"}, tags)
+
+	tags, err = NormalizeTags(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	_, err = NormalizeTags([]string{"sale", "!!"})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
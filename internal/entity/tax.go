@@ -0,0 +1,295 @@
+package entity
+
+import (
+	"errors"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// TaxRounding decides whether tax is rounded on each line or once for the
+// whole order.
+type TaxRounding string
+
+const (
+	TaxRoundLine  TaxRounding = "line"
+	TaxRoundOrder TaxRounding = "order"
+)
+
+// TaxRegion holds the tax rules of a country or subdivision, identified by
+// its ISO 3166 code such as BR or US-CA. When PricesIncludeTax is set the
+// catalog prices already carry the tax, which is then taken out of them
+// rather than added on top.
+type TaxRegion struct {
+	Code             string      `json:"code" gorm:"primaryKey;size:16"`
+	Name             string      `json:"name" validate:"max=120"`
+	PricesIncludeTax bool        `json:"prices_include_tax"`
+	Rounding         TaxRounding `json:"rounding" validate:"oneof=line order"`
+	Rates            []TaxRate   `json:"rates" gorm:"foreignKey:RegionCode"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// TaxRate is the percentage charged in a region on the products of a
+// category and its subcategories. A rate without category is the default
+// of the region.
+type TaxRate struct {
+	ID         entity.ID  `json:"id"`
+	RegionCode string     `json:"-" gorm:"size:16;index"`
+	CategoryID *entity.ID `json:"category_id,omitempty"`
+	Rate       string     `json:"rate"`
+}
+
+var regionCodePattern = regexp.MustCompile(`^[A-Z]{2}(-[A-Z0-9]{1,3})?$`)
+
+var (
+	ErrInvalidTaxRegion     = errors.New("tax regions are ISO 3166 codes such as BR or US-CA")
+	ErrInvalidTaxRegionName = errors.New("invalid tax region name")
+	ErrInvalidTaxRounding   = errors.New("invalid tax rounding")
+	ErrInvalidTaxRate       = errors.New("tax rates are percentages from 0 to 100")
+	ErrDuplicateTaxRate     = errors.New("a region has one rate per category")
+	ErrTaxRegionNotFound    = errors.New("no tax rules for the region")
+	ErrTaxQuoteIsEmpty      = errors.New("a tax quote needs at least one item")
+	ErrTaxCurrencyMismatch  = errors.New("all the items of a tax quote must use the same currency")
+)
+
+var taxRegionErrors = map[string]error{
+	"name.max":       ErrInvalidTaxRegionName,
+	"rounding.oneof": ErrInvalidTaxRounding,
+}
+
+func NewTaxRegion(code, name string, pricesIncludeTax bool, rounding TaxRounding) (*TaxRegion, error) {
+	region := &TaxRegion{
+		Code:             NormalizeTaxRegion(code),
+		Name:             strings.TrimSpace(name),
+		PricesIncludeTax: pricesIncludeTax,
+		Rounding:         rounding,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	err := region.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return region, nil
+}
+
+func NormalizeTaxRegion(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (r *TaxRegion) Validate() error {
+	if !regionCodePattern.MatchString(r.Code) {
+		return ErrInvalidTaxRegion
+	}
+	err := validate(r, taxRegionErrors)
+	if err != nil {
+		return err
+	}
+
+	categories := map[entity.ID]bool{}
+	var hasDefault bool
+	for _, rate := range r.Rates {
+		_, err := parseTaxRate(rate.Rate)
+		if err != nil {
+			return err
+		}
+		if rate.CategoryID == nil {
+			if hasDefault {
+				return ErrDuplicateTaxRate
+			}
+			hasDefault = true
+			continue
+		}
+		if categories[*rate.CategoryID] {
+			return ErrDuplicateTaxRate
+		}
+		categories[*rate.CategoryID] = true
+	}
+	return nil
+}
+
+// AddRate adds the rate of a category, or the default rate of the region
+// when categoryID is nil.
+func (r *TaxRegion) AddRate(categoryID *entity.ID, rate string) error {
+	r.Rates = append(r.Rates, TaxRate{
+		ID:         entity.NewID(),
+		RegionCode: r.Code,
+		CategoryID: categoryID,
+		Rate:       strings.TrimSpace(rate),
+	})
+	err := r.Validate()
+	if err != nil {
+		r.Rates = r.Rates[:len(r.Rates)-1]
+		return err
+	}
+	return nil
+}
+
+// RateFor returns the rate of the first of the categories that has one, so
+// categories are expected nearest first, or else the default rate of the
+// region. Products without any rate are not taxed.
+func (r *TaxRegion) RateFor(categories []entity.ID) string {
+	for _, category := range categories {
+		for _, rate := range r.Rates {
+			if rate.CategoryID != nil && *rate.CategoryID == category {
+				return rate.Rate
+			}
+		}
+	}
+	for _, rate := range r.Rates {
+		if rate.CategoryID == nil {
+			return rate.Rate
+		}
+	}
+	return "0"
+}
+
+func parseTaxRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() < 0 || r.Cmp(big.NewRat(100, 1)) > 0 || strings.ContainsAny(rate, "/eE") {
+		return nil, ErrInvalidTaxRate
+	}
+	return r, nil
+}
+
+// TaxableLine is an amount to tax, as priced in the catalog, with the
+// categories of its product nearest first.
+type TaxableLine struct {
+	ProductID  entity.ID
+	Amount     money.Money
+	Categories []entity.ID
+}
+
+type TaxQuoteLine struct {
+	ProductID entity.ID   `json:"product_id"`
+	Rate      string      `json:"rate"`
+	Net       money.Money `json:"net"`
+	Tax       money.Money `json:"tax"`
+	Gross     money.Money `json:"gross"`
+}
+
+// TaxQuote is the tax due on some lines in a region. Net amounts exclude the
+// tax and gross amounts include it.
+type TaxQuote struct {
+	Region           string         `json:"region"`
+	PricesIncludeTax bool           `json:"prices_include_tax"`
+	Rounding         TaxRounding    `json:"rounding"`
+	Lines            []TaxQuoteLine `json:"lines"`
+	Net              money.Money    `json:"net"`
+	Tax              money.Money    `json:"tax"`
+	Gross            money.Money    `json:"gross"`
+}
+
+// Quote computes the tax of the lines. Amounts are rounded half up to the
+// minor unit; with order rounding the tax of the whole order is rounded
+// once and spread over the lines so that they still add up to it.
+func (r *TaxRegion) Quote(lines []TaxableLine) (*TaxQuote, error) {
+	if len(lines) == 0 {
+		return nil, ErrTaxQuoteIsEmpty
+	}
+
+	currency := lines[0].Amount.Currency
+	exact := make([]*big.Rat, len(lines))
+	quote := &TaxQuote{
+		Region:           r.Code,
+		PricesIncludeTax: r.PricesIncludeTax,
+		Rounding:         r.Rounding,
+		Lines:            make([]TaxQuoteLine, len(lines)),
+	}
+	for i, line := range lines {
+		if line.Amount.Currency != currency {
+			return nil, ErrTaxCurrencyMismatch
+		}
+		rate := r.RateFor(line.Categories)
+		percent, err := parseTaxRate(rate)
+		if err != nil {
+			return nil, err
+		}
+
+		// the tax is amount × rate / 100 on top of a net price, or
+		// amount × rate / (100 + rate) out of a gross one
+		base := big.NewRat(100, 1)
+		if r.PricesIncludeTax {
+			base.Add(base, percent)
+		}
+		exact[i] = new(big.Rat).Mul(new(big.Rat).SetInt64(line.Amount.Amount), percent)
+		exact[i].Quo(exact[i], base)
+		quote.Lines[i] = TaxQuoteLine{ProductID: line.ProductID, Rate: rate}
+	}
+
+	taxes := roundTaxes(exact, r.Rounding)
+	quote.Net, quote.Tax, quote.Gross = money.New(0, currency), money.New(0, currency), money.New(0, currency)
+	for i, line := range lines {
+		tax := money.New(taxes[i], currency)
+		quoteLine := &quote.Lines[i]
+		quoteLine.Tax = tax
+		var err error
+		if r.PricesIncludeTax {
+			quoteLine.Gross = line.Amount
+			quoteLine.Net, err = line.Amount.Sub(tax)
+		} else {
+			quoteLine.Net = line.Amount
+			quoteLine.Gross, err = line.Amount.Add(tax)
+		}
+		if err != nil {
+			return nil, err
+		}
+		quote.Net, err = quote.Net.Add(quoteLine.Net)
+		if err != nil {
+			return nil, err
+		}
+		quote.Tax, err = quote.Tax.Add(quoteLine.Tax)
+		if err != nil {
+			return nil, err
+		}
+		quote.Gross, err = quote.Gross.Add(quoteLine.Gross)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return quote, nil
+}
+
+// roundTaxes rounds the exact tax of each line to minor units. With order
+// rounding the lines are rounded down and the units missing to reach the
+// rounded total go to the lines with the largest remainders.
+func roundTaxes(exact []*big.Rat, rounding TaxRounding) []int64 {
+	taxes := make([]int64, len(exact))
+	if rounding != TaxRoundOrder {
+		for i, tax := range exact {
+			taxes[i] = money.FromRat(tax, "", money.RoundHalfUp).Amount
+		}
+		return taxes
+	}
+
+	total := new(big.Rat)
+	remainders := make([]*big.Rat, len(exact))
+	var rounded int64
+	for i, tax := range exact {
+		total.Add(total, tax)
+		taxes[i] = money.FromRat(tax, "", money.RoundDown).Amount
+		rounded += taxes[i]
+		remainders[i] = new(big.Rat).Sub(tax, new(big.Rat).SetInt64(taxes[i]))
+	}
+
+	order := make([]int, len(exact))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]].Cmp(remainders[order[b]]) > 0
+	})
+	missing := money.FromRat(total, "", money.RoundHalfUp).Amount - rounded
+	for i := int64(0); i < missing; i++ {
+		taxes[order[i]]++
+	}
+	return taxes
+}
@@ -0,0 +1,104 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTaxRegion(t *testing.T) {
+	region, err := NewTaxRegion(" us-ca ", "California", false, TaxRoundLine)
+	assert.NoError(t, err)
+	assert.Equal(t, "US-CA", region.Code)
+
+	_, err = NewTaxRegion("California", "", false, TaxRoundLine)
+	assert.ErrorIs(t, err, ErrInvalidTaxRegion)
+	_, err = NewTaxRegion("BR", "", true, "invoice")
+	assert.ErrorIs(t, err, ErrInvalidTaxRounding)
+
+	books := entity.NewID()
+	assert.NoError(t, region.AddRate(nil, "7.25"))
+	assert.NoError(t, region.AddRate(&books, "0"))
+	assert.ErrorIs(t, region.AddRate(nil, "8"), ErrDuplicateTaxRate)
+	assert.ErrorIs(t, region.AddRate(&books, "5"), ErrDuplicateTaxRate)
+	assert.ErrorIs(t, region.AddRate(nil, "101"), ErrInvalidTaxRate)
+	assert.ErrorIs(t, region.AddRate(nil, "-1"), ErrInvalidTaxRate)
+	assert.Len(t, region.Rates, 2)
+}
+
+func TestTaxRegionRateFor(t *testing.T) {
+	media, books := entity.NewID(), entity.NewID()
+	region, _ := NewTaxRegion("DE", "Germany", true, TaxRoundLine)
+	assert.Equal(t, "0", region.RateFor(nil))
+
+	assert.NoError(t, region.AddRate(nil, "19"))
+	assert.NoError(t, region.AddRate(&media, "7"))
+	assert.Equal(t, "19", region.RateFor(nil))
+	assert.Equal(t, "7", region.RateFor([]entity.ID{books, media}))
+	assert.Equal(t, "19", region.RateFor([]entity.ID{books}))
+}
+
+func TestTaxQuoteExclusive(t *testing.T) {
+	food := entity.NewID()
+	region, _ := NewTaxRegion("US-CA", "", false, TaxRoundLine)
+	assert.NoError(t, region.AddRate(nil, "7.25"))
+	assert.NoError(t, region.AddRate(&food, "0"))
+
+	quote, err := region.Quote([]TaxableLine{
+		{Amount: money.MustParse("19.99", "USD")},
+		{Amount: money.MustParse("5.00", "USD"), Categories: []entity.ID{food}},
+	})
+	assert.NoError(t, err)
+	// 19.99 × 7.25% = 1.449275
+	assert.Equal(t, money.MustParse("1.45", "USD"), quote.Lines[0].Tax)
+	assert.Equal(t, money.MustParse("21.44", "USD"), quote.Lines[0].Gross)
+	assert.Equal(t, "0", quote.Lines[1].Rate)
+	assert.Equal(t, money.MustParse("0.00", "USD"), quote.Lines[1].Tax)
+	assert.Equal(t, money.MustParse("24.99", "USD"), quote.Net)
+	assert.Equal(t, money.MustParse("1.45", "USD"), quote.Tax)
+	assert.Equal(t, money.MustParse("26.44", "USD"), quote.Gross)
+}
+
+func TestTaxQuoteInclusive(t *testing.T) {
+	region, _ := NewTaxRegion("DE", "", true, TaxRoundLine)
+	assert.NoError(t, region.AddRate(nil, "19"))
+
+	quote, err := region.Quote([]TaxableLine{{Amount: money.MustParse("119.00", "EUR")}})
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("19.00", "EUR"), quote.Tax)
+	assert.Equal(t, money.MustParse("100.00", "EUR"), quote.Net)
+	assert.Equal(t, money.MustParse("119.00", "EUR"), quote.Gross)
+}
+
+func TestTaxQuoteRounding(t *testing.T) {
+	lines := []TaxableLine{
+		{Amount: money.MustParse("0.10", "USD")},
+		{Amount: money.MustParse("0.10", "USD")},
+		{Amount: money.MustParse("0.10", "USD")},
+	}
+
+	// 0.10 × 5% = 0.005 on each line
+	perLine, _ := NewTaxRegion("US-NY", "", false, TaxRoundLine)
+	assert.NoError(t, perLine.AddRate(nil, "5"))
+	quote, err := perLine.Quote(lines)
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("0.03", "USD"), quote.Tax)
+
+	perOrder, _ := NewTaxRegion("US-NY", "", false, TaxRoundOrder)
+	assert.NoError(t, perOrder.AddRate(nil, "5"))
+	quote, err = perOrder.Quote(lines)
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("0.02", "USD"), quote.Tax)
+	var sum int64
+	for _, line := range quote.Lines {
+		sum += line.Tax.Amount
+	}
+	assert.Equal(t, int64(2), sum)
+
+	_, err = perOrder.Quote(nil)
+	assert.ErrorIs(t, err, ErrTaxQuoteIsEmpty)
+	_, err = perOrder.Quote([]TaxableLine{{Amount: money.MustParse("1.00", "USD")}, {Amount: money.MustParse("1.00", "EUR")}})
+	assert.ErrorIs(t, err, ErrTaxCurrencyMismatch)
+}
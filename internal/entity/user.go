@@ -1,19 +1,39 @@
 package entity
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
 	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// RoleAdmin is the role of the users allowed to manage the catalog and
+// other users' data. Roles are never granted through the API.
+const RoleAdmin = "admin"
+
 type User struct {
 	ID       entity.ID `json:"id"`
 	Name     string    `json:"name" validate:"required"`
-	Email    string    `json:"email" validate:"required"`
+	Email    string    `json:"email" gorm:"uniqueIndex" validate:"required"`
 	Password string    `json:"-" validate:"required"`
+	Role     string    `json:"role,omitempty" gorm:"size:20"`
+}
+
+var (
+	ErrEmailAlreadyExists = errors.New("another user already has this email")
+	ErrInvalidRole        = errors.New("the role must be admin or empty")
+)
+
+// NormalizeEmail is the form emails are stored and looked up in, so that
+// an address has a single account whatever its case.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 func NewUser(name, email, password string) (*User, error) {
+	email = NormalizeEmail(email)
 	err := validator.GetValidatorInstance().Struct(&User{
 		Name:     name,
 		Email:    email,
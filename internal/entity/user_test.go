@@ -24,3 +24,9 @@ func TestValidatePassword(t *testing.T) {
 	assert.False(t, user.ValidatePassword("1233212"))
 	assert.NotEqual(t, "123321", user.Password)
 }
+
+func TestNewUserNormalizesEmail(t *testing.T) {
+	user, err := NewUser("Mr. Pipo", " Chandelier.Pipo@Gmail.com ", "123321")
+	assert.Nil(t, err)
+	assert.Equal(t, "chandelier.pipo@gmail.com", user.Email)
+}
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// Variant is a sellable version of a product, such as a size or a color,
+// identified by its SKU. Without a price of its own it sells at the product
+// price.
+type Variant struct {
+	ID        entity.ID         `json:"id"`
+	ProductID entity.ID         `json:"product_id" gorm:"index"`
+	SKU       string            `json:"sku" gorm:"size:64;uniqueIndex" validate:"required,max=64"`
+	Options   map[string]string `json:"options" gorm:"serializer:json"`
+	Price     *money.Money      `json:"price,omitempty" gorm:"embedded;embeddedPrefix:price_" validate:"omitempty,money_positive"`
+	Stock     int64             `json:"stock" validate:"gte=0"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+var skuPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9._-]*$`)
+
+var (
+	ErrSKUIsRequired        = errors.New("sku is required")
+	ErrInvalidSKU           = errors.New("invalid sku")
+	ErrSKUAlreadyExists     = errors.New("sku already exists")
+	ErrInvalidVariantOption = errors.New("variant options need a name and a value")
+	ErrInvalidStock         = errors.New("stock cannot be negative")
+	ErrVariantCurrency      = errors.New("variant price must use the product currency")
+)
+
+var variantErrors = map[string]error{
+	"sku.required":         ErrSKUIsRequired,
+	"sku.max":              ErrInvalidSKU,
+	"price.money_positive": ErrInvalidPrice,
+	"currency.required":    ErrInvalidCurrency,
+	"currency.iso4217":     ErrInvalidCurrency,
+	"stock.gte":            ErrInvalidStock,
+}
+
+// NormalizeSKU uppercases and trims a SKU so that lookups are case
+// insensitive.
+func NormalizeSKU(sku string) string {
+	return strings.ToUpper(strings.TrimSpace(sku))
+}
+
+func NewVariant(product *Product, sku string, options map[string]string, price *money.Money, stock int64) (*Variant, error) {
+	variant := &Variant{
+		ID:        entity.NewID(),
+		ProductID: product.ID,
+		SKU:       NormalizeSKU(sku),
+		Options:   options,
+		Price:     price,
+		Stock:     stock,
+		CreatedAt: time.Now(),
+	}
+
+	err := variant.Validate(product)
+	if err != nil {
+		return nil, err
+	}
+
+	return variant, nil
+}
+
+// Validate checks the variant against the product it belongs to.
+func (v *Variant) Validate(product *Product) error {
+	err := validate(v, variantErrors)
+	if err != nil {
+		return err
+	}
+	if !skuPattern.MatchString(v.SKU) {
+		return ErrInvalidSKU
+	}
+	for name, value := range v.Options {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+			return ErrInvalidVariantOption
+		}
+	}
+	if v.Price != nil && v.Price.Currency != product.Price.Currency {
+		return ErrVariantCurrency
+	}
+	return nil
+}
+
+// EffectivePrice is the price the variant sells at.
+func (v *Variant) EffectivePrice(product *Product) money.Money {
+	if v.Price != nil {
+		return *v.Price
+	}
+	return product.Price
+}
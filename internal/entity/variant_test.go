@@ -0,0 +1,49 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVariant(t *testing.T) {
+	product, err := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	assert.NoError(t, err)
+
+	variant, err := NewVariant(product, " tshirt-red-m ", map[string]string{"color": "red", "size": "M"}, nil, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "TSHIRT-RED-M", variant.SKU)
+	assert.Equal(t, product.ID, variant.ProductID)
+	assert.Equal(t, product.Price, variant.EffectivePrice(product))
+
+	price := money.MustParse("24.00", "USD")
+	variant, err = NewVariant(product, "TSHIRT-RED-XL", map[string]string{"size": "XL"}, &price, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, price, variant.EffectivePrice(product))
+}
+
+func TestNewVariantWhenInvalid(t *testing.T) {
+	product, err := NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	assert.NoError(t, err)
+
+	_, err = NewVariant(product, "", nil, nil, 0)
+	assert.ErrorIs(t, err, ErrSKUIsRequired)
+
+	_, err = NewVariant(product, "red shirt", nil, nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidSKU)
+
+	_, err = NewVariant(product, "SKU-1", map[string]string{"size": ""}, nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidVariantOption)
+
+	_, err = NewVariant(product, "SKU-1", nil, nil, -1)
+	assert.ErrorIs(t, err, ErrInvalidStock)
+
+	zero := money.New(0, "USD")
+	_, err = NewVariant(product, "SKU-1", nil, &zero, 0)
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	euros := money.MustParse("20.00", "EUR")
+	_, err = NewVariant(product, "SKU-1", nil, &euros, 0)
+	assert.ErrorIs(t, err, ErrVariantCurrency)
+}
@@ -0,0 +1,97 @@
+package entity
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// Wishlist is a named list of products a user wants. Sharing it gives it a
+// random token that lets anyone read it.
+type Wishlist struct {
+	ID         entity.ID      `json:"id"`
+	UserID     string         `json:"-" gorm:"index"`
+	Name       string         `json:"name" validate:"required,trimmed,max=100"`
+	ShareToken *string        `json:"share_token,omitempty" gorm:"size:64;uniqueIndex"`
+	Items      []WishlistItem `json:"items"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+type WishlistItem struct {
+	WishlistID entity.ID `json:"-" gorm:"primaryKey"`
+	ProductID  entity.ID `json:"product_id" gorm:"primaryKey;index"`
+	AddedAt    time.Time `json:"added_at"`
+}
+
+var (
+	ErrWishlistNameIsRequired = errors.New("wishlist name is required")
+	ErrInvalidWishlistName    = errors.New("invalid wishlist name")
+)
+
+var wishlistErrors = map[string]error{
+	"name.required": ErrWishlistNameIsRequired,
+	"name.trimmed":  ErrInvalidWishlistName,
+	"name.max":      ErrInvalidWishlistName,
+}
+
+func NewWishlist(userID, name string) (*Wishlist, error) {
+	wishlist := &Wishlist{
+		ID:        entity.NewID(),
+		UserID:    userID,
+		Name:      name,
+		Items:     []WishlistItem{},
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	err := wishlist.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return wishlist, nil
+}
+
+func (w *Wishlist) Validate() error {
+	return validate(w, wishlistErrors)
+}
+
+// Share gives the wishlist a new share token, which revokes the previous
+// link.
+func (w *Wishlist) Share() error {
+	buf := make([]byte, 24)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	w.ShareToken = &token
+	w.UpdatedAt = time.Now()
+	return nil
+}
+
+func (w *Wishlist) Unshare() {
+	w.ShareToken = nil
+	w.UpdatedAt = time.Now()
+}
+
+// PriceDrop tells a user that a product of one of their wishlists got
+// cheaper.
+type PriceDrop struct {
+	UserID        string      `json:"user_id"`
+	WishlistID    entity.ID   `json:"wishlist_id"`
+	WishlistName  string      `json:"wishlist_name"`
+	Product       Product     `json:"product"`
+	PreviousPrice money.Money `json:"previous_price"`
+}
+
+// IsPriceDrop tells whether price is lower than previous. Prices in
+// different currencies are not comparable and never count as a drop.
+func IsPriceDrop(previous, price money.Money) bool {
+	return previous.Currency == price.Currency && price.Amount < previous.Amount
+}
@@ -0,0 +1,43 @@
+package entity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewWishlist(t *testing.T) {
+	wishlist, err := NewWishlist("user-1", "Birthday")
+	assert.NoError(t, err)
+	assert.Nil(t, wishlist.ShareToken)
+
+	_, err = NewWishlist("user-1", "")
+	assert.ErrorIs(t, err, ErrWishlistNameIsRequired)
+	_, err = NewWishlist("user-1", " Birthday")
+	assert.ErrorIs(t, err, ErrInvalidWishlistName)
+	_, err = NewWishlist("user-1", strings.Repeat("a", 101))
+	assert.ErrorIs(t, err, ErrInvalidWishlistName)
+}
+
+func TestShareWishlist(t *testing.T) {
+	wishlist, _ := NewWishlist("user-1", "Birthday")
+
+	assert.NoError(t, wishlist.Share())
+	first := *wishlist.ShareToken
+	assert.Len(t, first, 32)
+
+	assert.NoError(t, wishlist.Share())
+	assert.NotEqual(t, first, *wishlist.ShareToken)
+
+	wishlist.Unshare()
+	assert.Nil(t, wishlist.ShareToken)
+}
+
+func TestIsPriceDrop(t *testing.T) {
+	assert.True(t, IsPriceDrop(money.MustParse("10.00", "USD"), money.MustParse("9.99", "USD")))
+	assert.False(t, IsPriceDrop(money.MustParse("10.00", "USD"), money.MustParse("10.00", "USD")))
+	assert.False(t, IsPriceDrop(money.MustParse("10.00", "USD"), money.MustParse("12.00", "USD")))
+	assert.False(t, IsPriceDrop(money.MustParse("10.00", "USD"), money.MustParse("5.00", "EUR")))
+}
@@ -0,0 +1,51 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "http://localhost:8000/blobs/")
+	ctx := context.Background()
+
+	assert.NoError(t, store.Put(ctx, "products/1/a.png", []byte("png"), "image/png"))
+
+	body, err := store.Get(ctx, "products/1/a.png")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, "png", string(data))
+	assert.Equal(t, "http://localhost:8000/blobs/products/1/a.png", store.URL("products/1/a.png"))
+
+	assert.NoError(t, store.Delete(ctx, "products/1/a.png"))
+	_, err = store.Get(ctx, "products/1/a.png")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.ErrorIs(t, store.Put(ctx, "../escape", nil, "text/plain"), ErrInvalidKey)
+	assert.ErrorIs(t, store.Put(ctx, "a/../../escape", nil, "text/plain"), ErrInvalidKey)
+	assert.ErrorIs(t, store.Put(ctx, "", nil, "text/plain"), ErrInvalidKey)
+}
+
+func TestLocalStoreHandler(t *testing.T) {
+	store := NewLocalStore(t.TempDir(), "http://localhost:8000/blobs")
+	assert.NoError(t, store.Put(context.Background(), "products/1/a.png", []byte("png"), "image/png"))
+	handler := store.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/products/1/a.png", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "png", rec.Body.String())
+
+	for _, dir := range []string{"/", "/products/", "/products/1", "/products/1/"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, dir, nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code, dir)
+		assert.NotContains(t, rec.Body.String(), "a.png", dir)
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+// CartDB keeps the cart items of each user, identified by the subject of
+// their token.
+type CartDB struct {
+	DB *gorm.DB
+}
+
+func NewCartDB(db *gorm.DB) *CartDB {
+	return &CartDB{DB: db}
+}
+
+func (cdb *CartDB) FindItems(userID string) ([]entity.CartItem, error) {
+	var items []entity.CartItem
+	err := cdb.DB.Where("user_id = ?", userID).Order("created_at").Find(&items).Error
+	return items, err
+}
+
+func (cdb *CartDB) FindItem(userID, itemID string) (*entity.CartItem, error) {
+	var item entity.CartItem
+	err := cdb.DB.First(&item, "id = ? AND user_id = ?", itemID, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AddItem puts the item in the cart of its user. When the cart already has
+// the same product and variant, the quantities are added up and the price
+// is refreshed on the existing item, which is returned.
+func (cdb *CartDB) AddItem(item *entity.CartItem) (*entity.CartItem, error) {
+	var added *entity.CartItem
+	err := cdb.DB.Transaction(func(tx *gorm.DB) error {
+		items, err := NewCartDB(tx).FindItems(item.UserID)
+		if err != nil {
+			return err
+		}
+
+		for i := range items {
+			if items[i].SameLine(item) {
+				items[i].Quantity += item.Quantity
+				items[i].UnitPrice = item.UnitPrice
+				err = items[i].Validate()
+				if err != nil {
+					return err
+				}
+				added = &items[i]
+				return tx.Save(added).Error
+			}
+		}
+
+		added = item
+		return tx.Create(item).Error
+	})
+	return added, err
+}
+
+func (cdb *CartDB) UpdateItem(item *entity.CartItem) error {
+	return cdb.DB.Save(item).Error
+}
+
+func (cdb *CartDB) DeleteItem(userID, itemID string) error {
+	item, err := cdb.FindItem(userID, itemID)
+	if err != nil {
+		return err
+	}
+	return cdb.DB.Delete(item).Error
+}
+
+func (cdb *CartDB) Clear(userID string) error {
+	return cdb.DB.Where("user_id = ?", userID).Delete(&entity.CartItem{}).Error
+}
@@ -0,0 +1,53 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCartItems(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	cartDB := NewCartDB(db)
+	userID := entityPkg.NewID().String()
+
+	product, _ := entity.NewProduct("Mug", money.MustParse("8.00", "USD"))
+	assert.NoError(t, NewProductDB(db).Create(product))
+
+	item, _ := entity.NewCartItem(userID, product, nil, 1)
+	added, err := cartDB.AddItem(item)
+	assert.NoError(t, err)
+	assert.Equal(t, item.ID, added.ID)
+
+	// adding the same product again merges the quantities
+	again, _ := entity.NewCartItem(userID, product, nil, 2)
+	added, err = cartDB.AddItem(again)
+	assert.NoError(t, err)
+	assert.Equal(t, item.ID, added.ID)
+	assert.Equal(t, int64(3), added.Quantity)
+
+	items, err := cartDB.FindItems(userID)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+
+	// carts are private to their user
+	_, err = cartDB.FindItem(entityPkg.NewID().String(), item.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, cartDB.DeleteItem(userID, item.ID.String()))
+	items, err = cartDB.FindItems(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	_, err = cartDB.AddItem(again)
+	assert.NoError(t, err)
+	assert.NoError(t, cartDB.Clear(userID))
+	items, err = cartDB.FindItems(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+}
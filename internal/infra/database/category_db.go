@@ -0,0 +1,193 @@
+package database
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// descendantsQuery selects the id of a category and of every category nested
+// under it.
+const descendantsQuery = `
+WITH RECURSIVE descendants(id) AS (
+	SELECT id FROM categories WHERE id = ?
+	UNION
+	SELECT categories.id FROM categories JOIN descendants ON categories.parent_id = descendants.id
+)
+SELECT id FROM descendants`
+
+// lineageQuery selects the categories of a product and every category they
+// are nested under, nearest first.
+const lineageQuery = `
+WITH RECURSIVE lineage(id, parent_id, depth) AS (
+	SELECT categories.id, categories.parent_id, 0 FROM categories
+	JOIN product_categories ON product_categories.category_id = categories.id
+	WHERE product_categories.product_id = ?
+	UNION ALL
+	SELECT categories.id, categories.parent_id, lineage.depth + 1 FROM categories JOIN lineage ON categories.id = lineage.parent_id
+)
+SELECT id FROM lineage GROUP BY id ORDER BY MIN(depth), id`
+
+type CategoryDB struct {
+	DB *gorm.DB
+}
+
+func NewCategoryDB(db *gorm.DB) *CategoryDB {
+	return &CategoryDB{DB: db}
+}
+
+func (cdb *CategoryDB) Create(category *entity.Category) error {
+	if category.ParentID != nil {
+		_, err := cdb.FindByID(category.ParentID.String())
+		if err != nil {
+			return err
+		}
+	}
+	return cdb.DB.Create(category).Error
+}
+
+func (cdb *CategoryDB) FindAll() ([]entity.Category, error) {
+	var categories []entity.Category
+	err := cdb.DB.Order("name").Find(&categories).Error
+	return categories, err
+}
+
+func (cdb *CategoryDB) FindByID(id string) (*entity.Category, error) {
+	var category entity.Category
+	err := cdb.DB.First(&category, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// FindSubtree returns the category and all of its descendants, flat.
+func (cdb *CategoryDB) FindSubtree(id string) ([]entity.Category, error) {
+	var categories []entity.Category
+	err := cdb.DB.Where("id IN (?)", cdb.DB.Raw(descendantsQuery, id)).Order("name").Find(&categories).Error
+	return categories, err
+}
+
+// Update saves the category, refusing to move it under itself or one of its
+// descendants.
+func (cdb *CategoryDB) Update(category *entity.Category) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := NewCategoryDB(tx).FindByID(category.ID.String())
+		if err != nil {
+			return err
+		}
+		category.CreatedAt = current.CreatedAt
+
+		if category.ParentID != nil {
+			_, err = NewCategoryDB(tx).FindByID(category.ParentID.String())
+			if err != nil {
+				return err
+			}
+			var cycles int64
+			err = tx.Raw("SELECT COUNT(*) FROM ("+descendantsQuery+") WHERE id = ?", category.ID, *category.ParentID).
+				Scan(&cycles).Error
+			if err != nil {
+				return err
+			}
+			if cycles > 0 {
+				return entity.ErrCategoryCycle
+			}
+		}
+
+		return tx.Save(category).Error
+	})
+}
+
+// Delete removes a category without subcategories along with its product
+// assignments. The products themselves are kept.
+func (cdb *CategoryDB) Delete(id string) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		category, err := NewCategoryDB(tx).FindByID(id)
+		if err != nil {
+			return err
+		}
+
+		var children int64
+		err = tx.Model(&entity.Category{}).Where("parent_id = ?", category.ID).Count(&children).Error
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return entity.ErrCategoryHasChildren
+		}
+
+		err = tx.Where("category_id = ?", category.ID).Delete(&entity.ProductCategory{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
+
+// AssignProducts adds the products to the category, ignoring the ones
+// already assigned.
+func (cdb *CategoryDB) AssignProducts(categoryID string, productIDs []string) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		category, err := NewCategoryDB(tx).FindByID(categoryID)
+		if err != nil {
+			return err
+		}
+
+		assignments := make([]entity.ProductCategory, len(productIDs))
+		for i, productID := range productIDs {
+			product, err := NewProductDB(tx).FindByID(productID)
+			if err != nil {
+				return err
+			}
+			assignments[i] = entity.ProductCategory{ProductID: product.ID, CategoryID: category.ID}
+		}
+		if len(assignments) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&assignments).Error
+	})
+}
+
+func (cdb *CategoryDB) UnassignProduct(categoryID, productID string) error {
+	result := cdb.DB.Where("category_id = ? AND product_id = ?", categoryID, productID).Delete(&entity.ProductCategory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// FindProducts lists the products assigned to the category and, when
+// includeDescendants is set, to any category nested under it.
+func (cdb *CategoryDB) FindProducts(categoryID string, includeDescendants bool, page, limit int) ([]entity.Product, error) {
+	category, err := cdb.FindByID(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	categories := cdb.DB.Model(&entity.ProductCategory{}).Select("product_id")
+	if includeDescendants {
+		categories = categories.Where("category_id IN (?)", cdb.DB.Raw(descendantsQuery, category.ID))
+	} else {
+		categories = categories.Where("category_id = ?", category.ID)
+	}
+
+	var products []entity.Product
+	query := cdb.DB.Where("id IN (?)", categories).Order("created_at")
+	if page != 0 && limit != 0 {
+		query = query.Limit(limit).Offset((page - 1) * limit)
+	}
+	err = query.Find(&products).Error
+	return products, err
+}
+
+// FindLineage returns the IDs of the categories of the product followed by
+// their ancestors, nearest first.
+func (cdb *CategoryDB) FindLineage(productID string) ([]entityPkg.ID, error) {
+	var ids []entityPkg.ID
+	err := cdb.DB.Raw(lineageQuery, productID).Scan(&ids).Error
+	return ids, err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createCategory(t *testing.T, categoryDB *CategoryDB, name string, parent *entity.Category) *entity.Category {
+	var category *entity.Category
+	var err error
+	if parent != nil {
+		category, err = entity.NewCategory(name, &parent.ID)
+	} else {
+		category, err = entity.NewCategory(name, nil)
+	}
+	assert.NoError(t, err)
+	assert.NoError(t, categoryDB.Create(category))
+	return category
+}
+
+func TestCategorySubtree(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	categoryDB := NewCategoryDB(db)
+
+	electronics := createCategory(t, categoryDB, "Electronics", nil)
+	phones := createCategory(t, categoryDB, "Phones", electronics)
+	createCategory(t, categoryDB, "Android", phones)
+	createCategory(t, categoryDB, "Books", nil)
+
+	subtree, err := categoryDB.FindSubtree(phones.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, subtree, 2)
+
+	tree := entity.BuildCategoryTree(subtree)
+	assert.Len(t, tree, 1)
+	assert.Equal(t, "Phones", tree[0].Name)
+	assert.Equal(t, "Android", tree[0].Children[0].Name)
+}
+
+func TestUpdateCategoryRejectsCycles(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	categoryDB := NewCategoryDB(db)
+
+	electronics := createCategory(t, categoryDB, "Electronics", nil)
+	phones := createCategory(t, categoryDB, "Phones", electronics)
+	android := createCategory(t, categoryDB, "Android", phones)
+
+	electronics.ParentID = &android.ID
+	assert.ErrorIs(t, categoryDB.Update(electronics), entity.ErrCategoryCycle)
+
+	books := createCategory(t, categoryDB, "Books", nil)
+	android.ParentID = &books.ID
+	assert.NoError(t, categoryDB.Update(android))
+}
+
+func TestDeleteCategory(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	categoryDB := NewCategoryDB(db)
+
+	electronics := createCategory(t, categoryDB, "Electronics", nil)
+	phones := createCategory(t, categoryDB, "Phones", electronics)
+
+	assert.ErrorIs(t, categoryDB.Delete(electronics.ID.String()), entity.ErrCategoryHasChildren)
+	assert.NoError(t, categoryDB.Delete(phones.ID.String()))
+	assert.NoError(t, categoryDB.Delete(electronics.ID.String()))
+
+	_, err = categoryDB.FindByID(electronics.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestFindCategoryProducts(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	categoryDB := NewCategoryDB(db)
+	productDB := NewProductDB(db)
+
+	electronics := createCategory(t, categoryDB, "Electronics", nil)
+	phones := createCategory(t, categoryDB, "Phones", electronics)
+	android := createCategory(t, categoryDB, "Android", phones)
+
+	tv, _ := entity.NewProduct("TV", money.MustParse("1000.00", "USD"))
+	phone, _ := entity.NewProduct("Phone", money.MustParse("500.00", "USD"))
+	assert.NoError(t, productDB.Create(tv))
+	assert.NoError(t, productDB.Create(phone))
+
+	assert.NoError(t, categoryDB.AssignProducts(electronics.ID.String(), []string{tv.ID.String()}))
+	assert.NoError(t, categoryDB.AssignProducts(android.ID.String(), []string{phone.ID.String()}))
+	// assigning twice is a no-op
+	assert.NoError(t, categoryDB.AssignProducts(android.ID.String(), []string{phone.ID.String()}))
+
+	products, err := categoryDB.FindProducts(electronics.ID.String(), false, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, tv.ID, products[0].ID)
+
+	products, err = categoryDB.FindProducts(electronics.ID.String(), true, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = categoryDB.FindProducts(phones.ID.String(), true, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, phone.ID, products[0].ID)
+
+	assert.NoError(t, categoryDB.UnassignProduct(android.ID.String(), phone.ID.String()))
+	assert.ErrorIs(t, categoryDB.UnassignProduct(android.ID.String(), phone.ID.String()), gorm.ErrRecordNotFound)
+
+	err = categoryDB.AssignProducts(electronics.ID.String(), []string{"3f8c1e0a-0000-4000-8000-000000000000"})
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CouponDB keeps the coupons, their redemptions and the coupon applied to
+// the cart of each user.
+type CouponDB struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+func NewCouponDB(db *gorm.DB) *CouponDB {
+	return &CouponDB{DB: db, Clock: clock.Real{}}
+}
+
+// Create stores the coupon, failing with ErrCouponAlreadyExists when another
+// coupon uses its code.
+func (cdb *CouponDB) Create(coupon *entity.Coupon) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := NewCouponDB(tx).checkCode(coupon)
+		if err != nil {
+			return err
+		}
+		return tx.Create(coupon).Error
+	})
+}
+
+func (cdb *CouponDB) FindByID(id string) (*entity.Coupon, error) {
+	var coupon entity.Coupon
+	err := cdb.DB.First(&coupon, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (cdb *CouponDB) FindByCode(code string) (*entity.Coupon, error) {
+	var coupon entity.Coupon
+	err := cdb.DB.First(&coupon, "code = ?", entity.NormalizeCouponCode(code)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (cdb *CouponDB) FindAll(page, limit int) ([]entity.Coupon, error) {
+	var coupons []entity.Coupon
+	db := cdb.DB.Order("code")
+	if page != 0 && limit != 0 {
+		db = db.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := db.Find(&coupons).Error
+	return coupons, err
+}
+
+// Update saves the coupon, leaving its redemption count alone since
+// checkouts may be counting concurrently.
+func (cdb *CouponDB) Update(coupon *entity.Coupon) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := NewCouponDB(tx).FindByID(coupon.ID.String())
+		if err != nil {
+			return err
+		}
+		err = NewCouponDB(tx).checkCode(coupon)
+		if err != nil {
+			return err
+		}
+		coupon.CreatedAt = current.CreatedAt
+		return tx.Model(coupon).Select("*").Omit("redemptions", "created_at").Updates(coupon).Error
+	})
+}
+
+// Delete removes the coupon and takes it off the carts it was applied to.
+// Orders keep the code and discount they were placed with.
+func (cdb *CouponDB) Delete(coupon *entity.Coupon) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("code = ?", coupon.Code).Delete(&entity.CartCoupon{}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("coupon_id = ?", coupon.ID).Delete(&entity.CouponRedemption{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(coupon).Error
+	})
+}
+
+// CountUserRedemptions tells how many times the user redeemed the coupon.
+func (cdb *CouponDB) CountUserRedemptions(couponID, userID string) (int, error) {
+	var count int64
+	err := cdb.DB.Model(&entity.CouponRedemption{}).Where("coupon_id = ? AND user_id = ?", couponID, userID).Count(&count).Error
+	return int(count), err
+}
+
+// EligibleProducts tells which of the products the restrictions of the
+// coupon allow: the listed products and those in the listed categories or
+// any category nested under them.
+func (cdb *CouponDB) EligibleProducts(coupon *entity.Coupon, productIDs []string) (map[entityPkg.ID]bool, error) {
+	eligible := map[entityPkg.ID]bool{}
+	for _, id := range coupon.ProductIDs {
+		parsed, err := entityPkg.ParseID(id)
+		if err == nil {
+			eligible[parsed] = true
+		}
+	}
+	if len(coupon.CategoryIDs) == 0 || len(productIDs) == 0 {
+		return eligible, nil
+	}
+
+	db := cdb.DB.Model(&entity.ProductCategory{}).Where("product_id IN ?", productIDs)
+	categories := cdb.DB.Where("1 = 0")
+	for _, categoryID := range coupon.CategoryIDs {
+		categories = categories.Or("category_id IN (?)", cdb.DB.Raw(descendantsQuery, categoryID))
+	}
+	var assigned []entity.ProductCategory
+	err := db.Where(categories).Find(&assigned).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assigned {
+		eligible[assignment.ProductID] = true
+	}
+	return eligible, nil
+}
+
+// Evaluate applies the coupon with the code to the cart lines of the user.
+// An unknown code is a rejected evaluation rather than an error.
+func (cdb *CouponDB) Evaluate(code, userID string, lines []entity.CartLine) (entity.CouponEvaluation, error) {
+	coupon, err := cdb.FindByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return entity.CouponEvaluation{
+			Code: entity.NormalizeCouponCode(code),
+			Rejections: []entity.CouponRejection{
+				{Code: entity.CouponNotFound, Message: "no coupon has this code"},
+			},
+		}, nil
+	}
+	if err != nil {
+		return entity.CouponEvaluation{}, err
+	}
+
+	check := entity.CouponCheck{Now: cdb.Clock.Now(), Lines: lines}
+	check.UserRedemptions, err = cdb.CountUserRedemptions(coupon.ID.String(), userID)
+	if err != nil {
+		return entity.CouponEvaluation{}, err
+	}
+	if coupon.Restricted() {
+		productIDs := make([]string, 0, len(lines))
+		for _, line := range lines {
+			productIDs = append(productIDs, line.Item.ProductID.String())
+		}
+		check.Eligible, err = cdb.EligibleProducts(coupon, productIDs)
+		if err != nil {
+			return entity.CouponEvaluation{}, err
+		}
+	}
+	return coupon.Evaluate(check)
+}
+
+// Redeem counts a use of the coupon for the order, failing with
+// ErrCouponRejected when the coupon is no longer usable or its limits were
+// reached meanwhile. The count is increased by a conditional update, so
+// concurrent checkouts cannot exceed the limits; callers run it in the
+// transaction that places the order.
+func (cdb *CouponDB) Redeem(code, userID string, orderID entityPkg.ID) error {
+	return cdb.DB.Transaction(func(tx *gorm.DB) error {
+		coupon, err := NewCouponDB(tx).FindByCode(code)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return entity.ErrCouponRejected
+		}
+		if err != nil {
+			return err
+		}
+
+		now := cdb.Clock.Now()
+		result := tx.Model(&entity.Coupon{}).
+			Where("id = ? AND active = ?", coupon.ID, true).
+			Where("max_redemptions = 0 OR redemptions < max_redemptions").
+			Where("starts_at IS NULL OR starts_at <= ?", now).
+			Where("ends_at IS NULL OR ends_at > ?", now).
+			Update("redemptions", gorm.Expr("redemptions + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrCouponRejected
+		}
+
+		// the update above locks the coupon, so redemptions of the same user
+		// are counted one checkout at a time
+		if coupon.MaxPerUser > 0 {
+			count, err := NewCouponDB(tx).CountUserRedemptions(coupon.ID.String(), userID)
+			if err != nil {
+				return err
+			}
+			if count >= coupon.MaxPerUser {
+				return entity.ErrCouponRejected
+			}
+		}
+
+		return tx.Create(&entity.CouponRedemption{
+			ID:        entityPkg.NewID(),
+			CouponID:  coupon.ID,
+			UserID:    userID,
+			OrderID:   orderID,
+			CreatedAt: now,
+		}).Error
+	})
+}
+
+// FindCartCoupon returns the code applied to the cart of the user, or an
+// empty code when there is none.
+func (cdb *CouponDB) FindCartCoupon(userID string) (string, error) {
+	var applied entity.CartCoupon
+	err := cdb.DB.First(&applied, "user_id = ?", userID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return applied.Code, err
+}
+
+// ApplyToCart sets the coupon of the cart of the user, replacing the one
+// applied before.
+func (cdb *CouponDB) ApplyToCart(userID, code string) error {
+	applied := entity.CartCoupon{UserID: userID, Code: entity.NormalizeCouponCode(code), AppliedAt: time.Now()}
+	return cdb.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&applied).Error
+}
+
+func (cdb *CouponDB) RemoveFromCart(userID string) error {
+	return cdb.DB.Where("user_id = ?", userID).Delete(&entity.CartCoupon{}).Error
+}
+
+// checkCode reports ErrCouponAlreadyExists when another coupon has the code.
+// The unique index still guards against concurrent inserts.
+func (cdb *CouponDB) checkCode(coupon *entity.Coupon) error {
+	existing, err := cdb.FindByCode(coupon.Code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != coupon.ID {
+		return entity.ErrCouponAlreadyExists
+	}
+	return nil
+}
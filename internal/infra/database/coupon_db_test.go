@@ -0,0 +1,175 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// uniqueCode returns a coupon code unique to this run since the test
+// database is shared.
+func uniqueCode(prefix string) string {
+	return prefix + "-" + entityPkg.NewID().String()[:8]
+}
+
+func TestCreateAndUpdateCoupon(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	couponDB := NewCouponDB(db)
+
+	code := uniqueCode("SAVE")
+	coupon, err := entity.NewCoupon(code, entity.CouponPercentage, 15, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, couponDB.Create(coupon))
+
+	found, err := couponDB.FindByCode(" " + code + " ")
+	assert.NoError(t, err)
+	assert.Equal(t, coupon.ID, found.ID)
+	assert.Equal(t, 15, found.Percent)
+	assert.Nil(t, found.Amount)
+
+	duplicate, _ := entity.NewCoupon(code, entity.CouponPercentage, 5, nil)
+	assert.ErrorIs(t, couponDB.Create(duplicate), entity.ErrCouponAlreadyExists)
+
+	// updates never overwrite the redemption count
+	assert.NoError(t, db.Model(coupon).Update("redemptions", 3).Error)
+	minOrder := money.MustParse("50.00", "USD")
+	found.MinOrder = &minOrder
+	found.MaxRedemptions = 10
+	found.Redemptions = 0
+	assert.NoError(t, couponDB.Update(found))
+
+	found, err = couponDB.FindByID(coupon.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, minOrder, *found.MinOrder)
+	assert.Equal(t, 10, found.MaxRedemptions)
+	assert.Equal(t, 3, found.Redemptions)
+
+	assert.NoError(t, couponDB.Delete(found))
+	_, err = couponDB.FindByID(coupon.ID.String())
+	assert.Error(t, err)
+}
+
+func TestCouponEligibleProductsIncludesSubcategories(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	couponDB := NewCouponDB(db)
+	categoryDB := NewCategoryDB(db)
+	productDB := NewProductDB(db)
+
+	clothing, _ := entity.NewCategory("Clothing", nil)
+	assert.NoError(t, categoryDB.Create(clothing))
+	shirts, _ := entity.NewCategory("Shirts", &clothing.ID)
+	assert.NoError(t, categoryDB.Create(shirts))
+
+	shirt, _ := entity.NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	mug, _ := entity.NewProduct("Mug", money.MustParse("8.00", "USD"))
+	book, _ := entity.NewProduct("Book", money.MustParse("30.00", "USD"))
+	for _, product := range []*entity.Product{shirt, mug, book} {
+		assert.NoError(t, productDB.Create(product))
+	}
+	assert.NoError(t, categoryDB.AssignProducts(shirts.ID.String(), []string{shirt.ID.String()}))
+
+	coupon, _ := entity.NewCoupon(uniqueCode("CLOTHES"), entity.CouponPercentage, 10, nil)
+	coupon.CategoryIDs = []string{clothing.ID.String()}
+	coupon.ProductIDs = []string{book.ID.String()}
+
+	eligible, err := couponDB.EligibleProducts(coupon, []string{shirt.ID.String(), mug.ID.String(), book.ID.String()})
+	assert.NoError(t, err)
+	assert.True(t, eligible[shirt.ID])
+	assert.True(t, eligible[book.ID])
+	assert.False(t, eligible[mug.ID])
+}
+
+func TestRedeemCouponEnforcesLimits(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	couponDB := NewCouponDB(db)
+
+	coupon, _ := entity.NewCoupon(uniqueCode("ONCE"), entity.CouponPercentage, 10, nil)
+	coupon.MaxRedemptions = 2
+	coupon.MaxPerUser = 1
+	assert.NoError(t, couponDB.Create(coupon))
+
+	assert.NoError(t, couponDB.Redeem(coupon.Code, "user-1", entityPkg.NewID()))
+	assert.ErrorIs(t, couponDB.Redeem(coupon.Code, "user-1", entityPkg.NewID()), entity.ErrCouponRejected)
+	assert.NoError(t, couponDB.Redeem(coupon.Code, "user-2", entityPkg.NewID()))
+	assert.ErrorIs(t, couponDB.Redeem(coupon.Code, "user-3", entityPkg.NewID()), entity.ErrCouponRejected)
+
+	// rejected redemptions are not counted
+	found, err := couponDB.FindByID(coupon.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, found.Redemptions)
+	count, err := couponDB.CountUserRedemptions(coupon.ID.String(), "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	expired, _ := entity.NewCoupon(uniqueCode("OLD"), entity.CouponPercentage, 10, nil)
+	endsAt := time.Now().Add(-time.Hour)
+	expired.EndsAt = &endsAt
+	assert.NoError(t, couponDB.Create(expired))
+	assert.ErrorIs(t, couponDB.Redeem(expired.Code, "user-1", entityPkg.NewID()), entity.ErrCouponRejected)
+}
+
+func TestCheckoutRedeemsCartCoupon(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	db := stockDB.DB
+	orderDB := NewOrderDB(db)
+	couponDB := NewCouponDB(db)
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	coupon, _ := entity.NewCoupon(uniqueCode("CHECKOUT"), entity.CouponPercentage, 10, nil)
+	coupon.MaxPerUser = 1
+	assert.NoError(t, couponDB.Create(coupon))
+
+	userID := uniqueCode("user")
+	item, _ := entity.NewCartItem(userID, product, nil, 2)
+	_, err = NewCartDB(db).AddItem(item)
+	assert.NoError(t, err)
+	assert.NoError(t, couponDB.ApplyToCart(userID, coupon.Code))
+	code, err := couponDB.FindCartCoupon(userID)
+	assert.NoError(t, err)
+	assert.Equal(t, coupon.Code, code)
+
+	lines := []entity.CartLine{{Item: *item, Product: product}}
+	evaluation, err := couponDB.Evaluate(code, userID, lines)
+	assert.NoError(t, err)
+	assert.True(t, evaluation.Valid)
+
+	order, _ := entity.NewOrder(userID, lines)
+	assert.NoError(t, order.ApplyCoupon(evaluation))
+	assert.NoError(t, orderDB.Checkout(order, time.Now().Add(time.Hour)))
+
+	found, err := orderDB.FindByID(order.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, coupon.Code, found.CouponCode)
+	assert.Equal(t, money.MustParse("18.00", "USD"), found.Total)
+
+	code, err = couponDB.FindCartCoupon(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, code)
+
+	// the user already used the coupon, so a second order is rolled back
+	again, _ := entity.NewOrder(userID, lines)
+	assert.NoError(t, again.ApplyCoupon(evaluation))
+	assert.ErrorIs(t, orderDB.Checkout(again, time.Now().Add(time.Hour)), entity.ErrCouponRejected)
+	_, err = orderDB.FindByID(again.ID.String())
+	assert.Error(t, err)
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), level.Reserved)
+
+	evaluation, err = couponDB.Evaluate(coupon.Code, userID, lines)
+	assert.NoError(t, err)
+	assert.False(t, evaluation.Valid)
+	assert.Equal(t, entity.CouponUserLimitReached, evaluation.Rejections[0].Code)
+
+	evaluation, err = couponDB.Evaluate("NOPE-"+userID, userID, lines)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.CouponNotFound, evaluation.Rejections[0].Code)
+}
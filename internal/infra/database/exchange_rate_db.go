@@ -0,0 +1,144 @@
+package database
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrMalformedCSV = errors.New("malformed csv")
+
+type ExchangeRateDB struct {
+	DB *gorm.DB
+}
+
+func NewExchangeRateDB(db *gorm.DB) *ExchangeRateDB {
+	return &ExchangeRateDB{DB: db}
+}
+
+// Save stores the rates, replacing any rate already stored for the same pair
+// and effective date.
+func (edb *ExchangeRateDB) Save(rates []entity.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+	return edb.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base_currency"}, {Name: "quote_currency"}, {Name: "effective_at"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+	}).Create(&rates).Error
+}
+
+func (edb *ExchangeRateDB) FindAll(base, quote string) ([]entity.ExchangeRate, error) {
+	var rates []entity.ExchangeRate
+	query := edb.DB.Order("base_currency, quote_currency, effective_at desc")
+	if base != "" {
+		query = query.Where("base_currency = ?", strings.ToUpper(base))
+	}
+	if quote != "" {
+		query = query.Where("quote_currency = ?", strings.ToUpper(quote))
+	}
+	err := query.Find(&rates).Error
+	return rates, err
+}
+
+// FindEffective returns the newest rate from base to quote that is in effect
+// at the given time. When only the opposite pair is stored its inverse is
+// returned.
+func (edb *ExchangeRateDB) FindEffective(base, quote string, at time.Time) (*entity.ExchangeRate, error) {
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+
+	rate, err := edb.findEffective(base, quote, at)
+	if err == nil {
+		return rate, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	rate, err = edb.findEffective(quote, base, at)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrExchangeRateNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rate.Inverse()
+}
+
+func (edb *ExchangeRateDB) findEffective(base, quote string, at time.Time) (*entity.ExchangeRate, error) {
+	var rate entity.ExchangeRate
+	err := edb.DB.
+		Where("base_currency = ? AND quote_currency = ? AND effective_at <= ?", base, quote, at.UTC()).
+		Order("effective_at desc").
+		First(&rate).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// ImportCSV reads rates with the header base_currency,quote_currency,rate,
+// effective_at (RFC 3339 or YYYY-MM-DD) and saves them under the given
+// source.
+func (edb *ExchangeRateDB) ImportCSV(r io.Reader, source string) ([]entity.ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrMalformedCSV, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"base_currency", "quote_currency", "rate", "effective_at"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing the %s column", ErrMalformedCSV, name)
+		}
+	}
+
+	var rates []entity.ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedCSV, line, err)
+		}
+
+		effectiveAt, err := parseEffectiveAt(record[columns["effective_at"]])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, entity.ErrEffectiveAtIsRequired)
+		}
+		rate, err := entity.NewExchangeRate(
+			record[columns["base_currency"]],
+			record[columns["quote_currency"]],
+			record[columns["rate"]],
+			effectiveAt,
+			source,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, *rate)
+	}
+
+	return rates, edb.Save(rates)
+}
+
+func parseEffectiveAt(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if at, err := time.Parse(time.RFC3339, value); err == nil {
+		return at, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
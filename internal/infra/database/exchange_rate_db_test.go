@@ -0,0 +1,76 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createExchangeRateDB(t *testing.T) *ExchangeRateDB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"))
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.ExchangeRate{}))
+	return NewExchangeRateDB(db)
+}
+
+func TestImportExchangeRatesCSV(t *testing.T) {
+	rateDB := createExchangeRateDB(t)
+
+	csv := "base_currency,quote_currency,rate,effective_at\n" +
+		"USD,EUR,0.90,2024-01-01\n" +
+		"USD,EUR,0.92,2024-02-01T00:00:00Z\n"
+	rates, err := rateDB.ImportCSV(strings.NewReader(csv), "rates.csv")
+	assert.NoError(t, err)
+	assert.Len(t, rates, 2)
+
+	// re-importing the same dates replaces instead of duplicating
+	_, err = rateDB.ImportCSV(strings.NewReader("base_currency,quote_currency,rate,effective_at\nUSD,EUR,0.91,2024-01-01\n"), "fix.csv")
+	assert.NoError(t, err)
+
+	all, err := rateDB.FindAll("usd", "")
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+	assert.Equal(t, "0.92", all[0].Rate)
+	assert.Equal(t, "0.91", all[1].Rate)
+	assert.Equal(t, "fix.csv", all[1].Source)
+}
+
+func TestImportExchangeRatesCSVWhenInvalid(t *testing.T) {
+	rateDB := createExchangeRateDB(t)
+
+	_, err := rateDB.ImportCSV(strings.NewReader("base,quote\nUSD,EUR\n"), "rates.csv")
+	assert.ErrorIs(t, err, ErrMalformedCSV)
+
+	_, err = rateDB.ImportCSV(strings.NewReader("base_currency,quote_currency,rate,effective_at\nUSD,EUR,zero,2024-01-01\n"), "rates.csv")
+	assert.ErrorIs(t, err, entity.ErrInvalidRate)
+}
+
+func TestFindEffectiveExchangeRate(t *testing.T) {
+	rateDB := createExchangeRateDB(t)
+
+	january, err := entity.NewExchangeRate("USD", "EUR", "0.90", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "ecb")
+	assert.NoError(t, err)
+	february, err := entity.NewExchangeRate("USD", "EUR", "0.80", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), "ecb")
+	assert.NoError(t, err)
+	assert.NoError(t, rateDB.Save([]entity.ExchangeRate{*january, *february}))
+
+	rate, err := rateDB.FindEffective("USD", "EUR", time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "0.90", rate.Rate)
+
+	rate, err = rateDB.FindEffective("usd", "eur", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "0.80", rate.Rate)
+
+	rate, err = rateDB.FindEffective("EUR", "USD", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "1.25", rate.Rate)
+
+	_, err = rateDB.FindEffective("USD", "EUR", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, entity.ErrExchangeRateNotFound)
+}
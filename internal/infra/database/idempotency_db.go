@@ -0,0 +1,61 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+// IdempotencyDB keeps the requests made with an idempotency key until they
+// expire. The unique index on the user and key lets a single request with
+// a key run at a time.
+type IdempotencyDB struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+func NewIdempotencyDB(db *gorm.DB) *IdempotencyDB {
+	return &IdempotencyDB{DB: db, Clock: clock.Real{}}
+}
+
+// Begin records the request and returns nil, unless another one with the
+// same key is kept, in which case that one is returned instead. An expired
+// request no longer holds its key.
+func (idb *IdempotencyDB) Begin(request *entity.IdempotentRequest) (*entity.IdempotentRequest, error) {
+	err := idb.DB.Where("user_id = ? AND idempotency_key = ? AND expires_at <= ?", request.UserID, request.Key, idb.Clock.Now()).
+		Delete(&entity.IdempotentRequest{}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = idb.DB.Create(request).Error
+	if !errors.Is(err, gorm.ErrDuplicatedKey) {
+		return nil, err
+	}
+	var kept entity.IdempotentRequest
+	err = idb.DB.First(&kept, "user_id = ? AND idempotency_key = ?", request.UserID, request.Key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &kept, nil
+}
+
+// Answer saves the response recorded on the request.
+func (idb *IdempotencyDB) Answer(request *entity.IdempotentRequest) error {
+	return idb.DB.Model(request).Select("status", "header", "body").Updates(request).Error
+}
+
+// Release forgets a request that could not be answered, so that it can be
+// retried with the same key.
+func (idb *IdempotencyDB) Release(request *entity.IdempotentRequest) error {
+	return idb.DB.Delete(&entity.IdempotentRequest{}, "id = ?", request.ID).Error
+}
+
+// DeleteExpired deletes the expired requests and returns how many there
+// were.
+func (idb *IdempotencyDB) DeleteExpired() (int64, error) {
+	result := idb.DB.Where("expires_at <= ?", idb.Clock.Now()).Delete(&entity.IdempotentRequest{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,88 @@
+package database
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createIdempotencyDB(t *testing.T, now time.Time) (*IdempotencyDB, *clock.Fake) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.IdempotentRequest{}))
+
+	fake := clock.NewFake(now)
+	idempotencyDB := NewIdempotencyDB(db)
+	idempotencyDB.Clock = fake
+	return idempotencyDB, fake
+}
+
+func TestBeginIdempotentRequest(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	idempotencyDB, fake := createIdempotencyDB(t, now)
+
+	first, _ := entity.NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", []byte("a"), now.Add(time.Hour))
+	kept, err := idempotencyDB.Begin(first)
+	assert.NoError(t, err)
+	assert.Nil(t, kept)
+
+	// the same key of another user is another request
+	other, _ := entity.NewIdempotentRequest("user-2", "key-1", http.MethodPost, "/products", []byte("a"), now.Add(time.Hour))
+	kept, err = idempotencyDB.Begin(other)
+	assert.NoError(t, err)
+	assert.Nil(t, kept)
+
+	retry, _ := entity.NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", []byte("a"), now.Add(time.Hour))
+	kept, err = idempotencyDB.Begin(retry)
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, kept.ID)
+	assert.False(t, kept.IsAnswered())
+
+	assert.NoError(t, first.Answer(http.StatusCreated, http.Header{"Location": {"/products/1"}}, []byte("created")))
+	assert.NoError(t, idempotencyDB.Answer(first))
+	kept, err = idempotencyDB.Begin(retry)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, kept.Status)
+	assert.Equal(t, "/products/1", kept.ResponseHeader().Get("Location"))
+	assert.Equal(t, "created", string(kept.Body))
+
+	fake.Advance(time.Hour)
+	kept, err = idempotencyDB.Begin(retry)
+	assert.NoError(t, err)
+	assert.Nil(t, kept)
+}
+
+func TestReleaseIdempotentRequest(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	idempotencyDB, _ := createIdempotencyDB(t, now)
+
+	request, _ := entity.NewIdempotentRequest("user-1", "key-1", http.MethodPost, "/products", nil, now.Add(time.Hour))
+	_, err := idempotencyDB.Begin(request)
+	assert.NoError(t, err)
+	assert.NoError(t, idempotencyDB.Release(request))
+
+	kept, err := idempotencyDB.Begin(request)
+	assert.NoError(t, err)
+	assert.Nil(t, kept)
+}
+
+func TestDeleteExpiredIdempotentRequests(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	idempotencyDB, _ := createIdempotencyDB(t, now)
+
+	for i, expiresAt := range []time.Time{now.Add(-time.Minute), now, now.Add(time.Minute)} {
+		request, _ := entity.NewIdempotentRequest("user-1", fmt.Sprintf("key-%d", i), http.MethodPost, "/products", nil, expiresAt)
+		assert.NoError(t, idempotencyDB.DB.Create(request).Error)
+	}
+
+	deleted, err := idempotencyDB.DeleteExpired()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ImageDB struct {
+	DB *gorm.DB
+}
+
+func NewImageDB(db *gorm.DB) *ImageDB {
+	return &ImageDB{DB: db}
+}
+
+// Create stores the image after the last image of its product.
+func (idb *ImageDB) Create(image *entity.ProductImage) error {
+	return idb.DB.Transaction(func(tx *gorm.DB) error {
+		var last int
+		err := tx.Model(&entity.ProductImage{}).
+			Where("product_id = ?", image.ProductID).
+			Select("COALESCE(MAX(position), 0)").
+			Scan(&last).Error
+		if err != nil {
+			return err
+		}
+		image.Position = last + 1
+		return tx.Create(image).Error
+	})
+}
+
+func (idb *ImageDB) FindByID(id string) (*entity.ProductImage, error) {
+	var image entity.ProductImage
+	err := idb.DB.First(&image, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &image, nil
+}
+
+func (idb *ImageDB) FindByProduct(productID string) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	err := idb.DB.Where("product_id = ?", productID).Order("position").Find(&images).Error
+	return images, err
+}
+
+// Reorder sets the position of each image of the product to its index in
+// imageIDs, which must list all of them.
+func (idb *ImageDB) Reorder(productID string, imageIDs []string) ([]entity.ProductImage, error) {
+	var images []entity.ProductImage
+	err := idb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		images, err = NewImageDB(tx).FindByProduct(productID)
+		if err != nil {
+			return err
+		}
+
+		positions := map[string]int{}
+		for i, id := range imageIDs {
+			positions[id] = i + 1
+		}
+		if len(positions) != len(imageIDs) || len(positions) != len(images) {
+			return entity.ErrInvalidImageOrder
+		}
+		for i := range images {
+			position, ok := positions[images[i].ID.String()]
+			if !ok {
+				return entity.ErrInvalidImageOrder
+			}
+			images[i].Position = position
+			err = tx.Model(&images[i]).Update("position", position).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		images, err = NewImageDB(tx).FindByProduct(productID)
+		return err
+	})
+	return images, err
+}
+
+func (idb *ImageDB) Delete(id string) error {
+	image, err := idb.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return idb.DB.Delete(image).Error
+}
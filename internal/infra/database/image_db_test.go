@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorderImages(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	imageDB := NewImageDB(db)
+
+	product, _ := entity.NewProduct("Camera", money.MustParse("300.00", "USD"))
+	assert.NoError(t, NewProductDB(db).Create(product))
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		image, err := entity.NewProductImage(product.ID, "image/png", "image/png", 100, 10, 10)
+		assert.NoError(t, err)
+		assert.NoError(t, imageDB.Create(image))
+		assert.Equal(t, i+1, image.Position)
+		ids = append(ids, image.ID.String())
+	}
+
+	images, err := imageDB.Reorder(product.ID.String(), []string{ids[2], ids[0], ids[1]})
+	assert.NoError(t, err)
+	assert.Equal(t, ids[2], images[0].ID.String())
+	assert.Equal(t, ids[0], images[1].ID.String())
+	assert.Equal(t, ids[1], images[2].ID.String())
+
+	_, err = imageDB.Reorder(product.ID.String(), []string{ids[0], ids[1]})
+	assert.ErrorIs(t, err, entity.ErrInvalidImageOrder)
+	_, err = imageDB.Reorder(product.ID.String(), []string{ids[0], ids[0], ids[1]})
+	assert.ErrorIs(t, err, entity.ErrInvalidImageOrder)
+
+	assert.NoError(t, imageDB.Delete(ids[0]))
+	images, err = imageDB.FindByProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, images, 2)
+}
@@ -1,16 +1,183 @@
 package database
 
-import "github.com/pedro-chandelier/go-expert-apis/internal/entity"
+import (
+	"io"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
 
 type UserInterface interface {
 	Create(user *entity.User) error
+	FindByID(id string) (*entity.User, error)
 	FindByEmail(email string) (*entity.User, error)
+	SetRole(email, role string) error
 }
 
 type ProductInterface interface {
 	Create(product *entity.Product) error
+	CreateBatch(products []entity.Product) error
+	Import(rows ProductRows, dryRun bool, batchSize int, report func(ImportResult) error) error
 	FindAll(page, limit int, sort string) ([]entity.Product, error)
+	Find(query ProductQuery) ([]entity.Product, error)
+	Stream(query ProductQuery, batchSize int, fn func([]entity.Product) error) error
 	FindByID(id string) (*entity.Product, error)
-	Update(product *entity.Product) error
+	Update(product *entity.Product, changedBy string) error
 	Delete(id string) error
+	Batch(ops []ProductOperation, atomic bool, changedBy string) []error
+	FindPriceHistory(id string) ([]entity.ProductPriceHistory, error)
+	FindPriceAt(id string, at time.Time) (money.Money, error)
+	SetTags(id string, tags []string) error
+	FindTags(ids []string) (map[string][]string, error)
+	CountTags() ([]entity.TagCount, error)
+}
+
+type ExchangeRateInterface interface {
+	Save(rates []entity.ExchangeRate) error
+	FindAll(base, quote string) ([]entity.ExchangeRate, error)
+	FindEffective(base, quote string, at time.Time) (*entity.ExchangeRate, error)
+	ImportCSV(r io.Reader, source string) ([]entity.ExchangeRate, error)
+}
+
+type ScheduledPriceInterface interface {
+	Create(scheduledPrice *entity.ScheduledPrice) error
+	FindByID(id string) (*entity.ScheduledPrice, error)
+	FindByProduct(productID string) ([]entity.ScheduledPrice, error)
+	Update(scheduledPrice *entity.ScheduledPrice) error
+	FindActivePromotions(productIDs []string, at time.Time) (map[string]entity.ScheduledPrice, error)
+	FindDue(at time.Time) ([]entity.ScheduledPrice, error)
+	FindExpired(at time.Time) ([]entity.ScheduledPrice, error)
+}
+
+type CategoryInterface interface {
+	Create(category *entity.Category) error
+	FindAll() ([]entity.Category, error)
+	FindByID(id string) (*entity.Category, error)
+	FindSubtree(id string) ([]entity.Category, error)
+	Update(category *entity.Category) error
+	Delete(id string) error
+	AssignProducts(categoryID string, productIDs []string) error
+	UnassignProduct(categoryID, productID string) error
+	FindProducts(categoryID string, includeDescendants bool, page, limit int) ([]entity.Product, error)
+	FindLineage(productID string) ([]entityPkg.ID, error)
+}
+
+type StockInterface interface {
+	FindLevel(productID string) (*entity.StockLevel, error)
+	FindLedger(productID string) ([]entity.StockMovement, error)
+	Adjust(movement *entity.StockMovement) (*entity.StockLevel, error)
+	Reserve(reservation *entity.StockReservation) error
+	FindReservation(id string) (*entity.StockReservation, error)
+	Release(id string) error
+	Extend(id string, until time.Time) error
+	Commit(id, createdBy string) error
+	ReleaseExpired() (int, error)
+	Reconcile(productID string) (*entity.StockLevel, error)
+}
+
+type VariantInterface interface {
+	Create(variant *entity.Variant) error
+	FindByID(id string) (*entity.Variant, error)
+	FindBySKU(sku string) (*entity.Variant, error)
+	FindByProduct(productID string) ([]entity.Variant, error)
+	FindByProducts(productIDs []string) (map[string][]entity.Variant, error)
+	Update(variant *entity.Variant) error
+	Delete(id string) error
+}
+
+type ImageInterface interface {
+	Create(image *entity.ProductImage) error
+	FindByID(id string) (*entity.ProductImage, error)
+	FindByProduct(productID string) ([]entity.ProductImage, error)
+	Reorder(productID string, imageIDs []string) ([]entity.ProductImage, error)
+	Delete(id string) error
+}
+
+type CartInterface interface {
+	FindItems(userID string) ([]entity.CartItem, error)
+	FindItem(userID, itemID string) (*entity.CartItem, error)
+	AddItem(item *entity.CartItem) (*entity.CartItem, error)
+	UpdateItem(item *entity.CartItem) error
+	DeleteItem(userID, itemID string) error
+	Clear(userID string) error
+}
+
+type OrderInterface interface {
+	Create(order *entity.Order, expiresAt time.Time) error
+	Checkout(order *entity.Order, expiresAt time.Time) error
+	FindByID(id string) (*entity.Order, error)
+	FindUserOrder(userID, id string) (*entity.Order, error)
+	Find(query OrderQuery) ([]entity.Order, error)
+	Transition(id string, status entity.OrderStatus, changedBy string) (*entity.Order, error)
+	StartPayment(id string, hold time.Duration) (*entity.Order, error)
+	FinishPayment(id string) error
+}
+
+type PaymentInterface interface {
+	Create(payment *entity.Payment) error
+	FindByID(id string) (*entity.Payment, error)
+	FindByKey(orderID, idempotencyKey string) (*entity.Payment, error)
+	FindByOrder(orderID string) ([]entity.Payment, error)
+	Settle(id string, status entity.PaymentStatus, changedBy string) (*entity.Payment, error)
+	ApplyEvent(eventID, eventType, provider, providerRef string, status entity.PaymentStatus) error
+}
+
+type ReviewInterface interface {
+	Create(review *entity.Review) error
+	FindByID(productID, id string) (*entity.Review, error)
+	FindByProduct(productID string, includeHidden bool, page, limit int) ([]entity.Review, error)
+	Update(review *entity.Review) error
+	Delete(review *entity.Review) error
+}
+
+type WishlistInterface interface {
+	Create(wishlist *entity.Wishlist) error
+	FindByUser(userID string) ([]entity.Wishlist, error)
+	FindByID(userID, id string) (*entity.Wishlist, error)
+	FindByShareToken(token string) (*entity.Wishlist, error)
+	FindWatching(productID string) ([]entity.Wishlist, error)
+	Update(wishlist *entity.Wishlist) error
+	Delete(wishlist *entity.Wishlist) error
+	AddItem(wishlist *entity.Wishlist, productID string) error
+	RemoveItem(wishlist *entity.Wishlist, productID string) error
+}
+
+type CouponInterface interface {
+	Create(coupon *entity.Coupon) error
+	FindByID(id string) (*entity.Coupon, error)
+	FindByCode(code string) (*entity.Coupon, error)
+	FindAll(page, limit int) ([]entity.Coupon, error)
+	Update(coupon *entity.Coupon) error
+	Delete(coupon *entity.Coupon) error
+	Evaluate(code, userID string, lines []entity.CartLine) (entity.CouponEvaluation, error)
+	FindCartCoupon(userID string) (string, error)
+	ApplyToCart(userID, code string) error
+	RemoveFromCart(userID string) error
+}
+
+type TaxInterface interface {
+	Save(region *entity.TaxRegion) error
+	FindByCode(code string) (*entity.TaxRegion, error)
+	FindRegion(code string) (*entity.TaxRegion, error)
+	FindRegions() ([]entity.TaxRegion, error)
+	Delete(code string) error
+}
+
+type JobInterface interface {
+	Create(job *entity.Job) error
+	FindByID(id string) (*entity.Job, error)
+	Claim() (*entity.Job, error)
+	SetProgress(id string, progress int64) (bool, error)
+	Finish(job *entity.Job) error
+	Cancel(id string) (*entity.Job, error)
+	Requeue() (int64, error)
+}
+
+type IdempotencyInterface interface {
+	Begin(request *entity.IdempotentRequest) (*entity.IdempotentRequest, error)
+	Answer(request *entity.IdempotentRequest) error
+	Release(request *entity.IdempotentRequest) error
+	DeleteExpired() (int64, error)
 }
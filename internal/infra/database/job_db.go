@@ -0,0 +1,148 @@
+package database
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+// JobDB is the queue of background jobs. Workers claim jobs with a
+// conditional update, so that each attempt runs on a single worker.
+type JobDB struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+func NewJobDB(db *gorm.DB) *JobDB {
+	return &JobDB{DB: db, Clock: clock.Real{}}
+}
+
+func (jdb *JobDB) Create(job *entity.Job) error {
+	return jdb.DB.Create(job).Error
+}
+
+func (jdb *JobDB) FindByID(id string) (*entity.Job, error) {
+	var job entity.Job
+	err := jdb.DB.First(&job, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// Claim moves the queued job that is due the longest to running and counts
+// the attempt. It fails with gorm.ErrRecordNotFound when no job is due.
+func (jdb *JobDB) Claim() (*entity.Job, error) {
+	for {
+		now := jdb.Clock.Now()
+		var job entity.Job
+		err := jdb.DB.Where("status = ? AND run_at <= ?", entity.JobQueued, now).
+			Order("run_at").First(&job).Error
+		if err != nil {
+			return nil, err
+		}
+
+		result := jdb.DB.Model(&entity.Job{}).
+			Where("id = ? AND status = ?", job.ID, entity.JobQueued).
+			Updates(map[string]any{
+				"status":     entity.JobRunning,
+				"attempts":   gorm.Expr("attempts + 1"),
+				"started_at": now,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// another worker claimed it first
+			continue
+		}
+		return jdb.FindByID(job.ID.String())
+	}
+}
+
+// SetProgress records how far a running job got and tells whether it was
+// asked to stop.
+func (jdb *JobDB) SetProgress(id string, progress int64) (bool, error) {
+	err := jdb.DB.Model(&entity.Job{}).Where("id = ?", id).
+		Updates(map[string]any{"progress": progress, "updated_at": jdb.Clock.Now()}).Error
+	if err != nil {
+		return false, err
+	}
+	job, err := jdb.FindByID(id)
+	if err != nil {
+		return false, err
+	}
+	return job.CancelRequested, nil
+}
+
+// Finish saves the outcome of an attempt, leaving alone the cancellation
+// requested while it ran.
+func (jdb *JobDB) Finish(job *entity.Job) error {
+	job.UpdatedAt = jdb.Clock.Now()
+	return jdb.DB.Model(job).
+		Select("status", "progress", "result", "file", "error", "run_at", "finished_at", "updated_at").
+		Updates(job).Error
+}
+
+// Cancel cancels a queued job right away and asks a running one to stop,
+// which it does the next time it reports progress. Finished jobs fail with
+// ErrJobNotCancelable.
+func (jdb *JobDB) Cancel(id string) (*entity.Job, error) {
+	job, err := jdb.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := jdb.Clock.Now()
+	result := jdb.DB.Model(&entity.Job{}).
+		Where("id = ? AND status = ?", job.ID, entity.JobQueued).
+		Updates(map[string]any{
+			"status":           entity.JobCancelled,
+			"cancel_requested": true,
+			"error":            entity.ErrJobCancelled.Error(),
+			"finished_at":      now,
+			"updated_at":       now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		result = jdb.DB.Model(&entity.Job{}).
+			Where("id = ? AND status = ?", job.ID, entity.JobRunning).
+			Updates(map[string]any{"cancel_requested": true, "updated_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, entity.ErrJobNotCancelable
+		}
+	}
+	return jdb.FindByID(id)
+}
+
+// Requeue puts back in the queue the jobs left running by a worker that
+// stopped, such as on a server restart. The interrupted attempt still
+// counts, so jobs without attempts left fail with ErrJobInterrupted.
+func (jdb *JobDB) Requeue() (int64, error) {
+	var requeued int64
+	err := jdb.DB.Transaction(func(tx *gorm.DB) error {
+		now := jdb.Clock.Now()
+		err := tx.Model(&entity.Job{}).
+			Where("status = ? AND attempts >= max_attempts", entity.JobRunning).
+			Updates(map[string]any{
+				"status":      entity.JobFailed,
+				"error":       entity.ErrJobInterrupted.Error(),
+				"finished_at": now,
+				"updated_at":  now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Model(&entity.Job{}).Where("status = ?", entity.JobRunning).
+			Updates(map[string]any{"status": entity.JobQueued, "run_at": now, "updated_at": now})
+		requeued = result.RowsAffected
+		return result.Error
+	})
+	return requeued, err
+}
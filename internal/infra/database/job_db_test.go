@@ -0,0 +1,115 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createJobDB(t *testing.T, now time.Time) (*JobDB, *clock.Fake) {
+	// the queue is not shared with other tests, so that they claim only
+	// their own jobs
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Job{}))
+
+	fake := clock.NewFake(now)
+	jobDB := NewJobDB(db)
+	jobDB.Clock = fake
+	return jobDB, fake
+}
+
+func TestClaimJob(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jobDB, _ := createJobDB(t, now)
+
+	later, _ := entity.NewJob(entity.JobProductExport, "user-1", nil, 3)
+	later.RunAt = now.Add(time.Minute)
+	first, _ := entity.NewJob(entity.JobProductExport, "user-1", nil, 3)
+	first.RunAt = now.Add(-time.Minute)
+	assert.NoError(t, jobDB.Create(later))
+	assert.NoError(t, jobDB.Create(first))
+
+	job, err := jobDB.Claim()
+	assert.NoError(t, err)
+	assert.Equal(t, first.ID, job.ID)
+	assert.Equal(t, entity.JobRunning, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+
+	// the other job is not due yet
+	_, err = jobDB.Claim()
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, job.Succeed(map[string]int{"products": 2}, now))
+	assert.NoError(t, jobDB.Finish(job))
+	found, err := jobDB.FindByID(job.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.JobSucceeded, found.Status)
+	assert.JSONEq(t, `{"products": 2}`, found.Result)
+}
+
+func TestCancelJob(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jobDB, _ := createJobDB(t, now)
+
+	queued, _ := entity.NewJob(entity.JobProductPurge, "user-1", nil, 3)
+	queued.RunAt = now.Add(time.Hour)
+	assert.NoError(t, jobDB.Create(queued))
+	cancelled, err := jobDB.Cancel(queued.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.JobCancelled, cancelled.Status)
+	_, err = jobDB.Cancel(queued.ID.String())
+	assert.ErrorIs(t, err, entity.ErrJobNotCancelable)
+
+	running, _ := entity.NewJob(entity.JobProductPurge, "user-1", nil, 3)
+	running.RunAt = now
+	assert.NoError(t, jobDB.Create(running))
+	job, err := jobDB.Claim()
+	assert.NoError(t, err)
+
+	stopping, err := jobDB.Cancel(job.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.JobRunning, stopping.Status)
+	requested, err := jobDB.SetProgress(job.ID.String(), 10)
+	assert.NoError(t, err)
+	assert.True(t, requested)
+
+	// finishing the attempt keeps the request
+	job.Fail(entity.ErrJobInterrupted, true, time.Second, now)
+	assert.NoError(t, jobDB.Finish(job))
+	found, err := jobDB.FindByID(job.ID.String())
+	assert.NoError(t, err)
+	assert.True(t, found.CancelRequested)
+}
+
+func TestRequeueJobs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jobDB, _ := createJobDB(t, now)
+
+	retried, _ := entity.NewJob(entity.JobProductExport, "user-1", nil, 2)
+	retried.RunAt = now.Add(-time.Minute)
+	exhausted, _ := entity.NewJob(entity.JobProductExport, "user-1", nil, 1)
+	exhausted.RunAt = now
+	assert.NoError(t, jobDB.Create(retried))
+	assert.NoError(t, jobDB.Create(exhausted))
+	_, err := jobDB.Claim()
+	assert.NoError(t, err)
+	_, err = jobDB.Claim()
+	assert.NoError(t, err)
+
+	requeued, err := jobDB.Requeue()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+
+	found, _ := jobDB.FindByID(retried.ID.String())
+	assert.Equal(t, entity.JobQueued, found.Status)
+	found, _ = jobDB.FindByID(exhausted.ID.String())
+	assert.Equal(t, entity.JobFailed, found.Status)
+	assert.Equal(t, entity.ErrJobInterrupted.Error(), found.Error)
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
+)
+
+// MigrateProductPrices moves the legacy float `price` column of products into
+// the price_amount/price_currency columns, assuming every legacy price is in
+// the given currency. It is a no-op once the legacy column is gone.
+func MigrateProductPrices(db *gorm.DB, currency string) error {
+	if !db.Migrator().HasColumn(&entity.Product{}, "price") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var legacy []struct {
+			ID    string
+			Price float64
+		}
+		err := tx.Table("products").Select("id, price").Where("price IS NOT NULL").Find(&legacy).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range legacy {
+			price := money.FromFloat(row.Price, currency)
+			err = tx.Table("products").Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"price_amount":   price.Amount,
+				"price_currency": price.Currency,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Migrator().DropColumn(&entity.Product{}, "price")
+	})
+}
+
+// MigrateUserEmails stores the emails of users in the normalized form they
+// are looked up by, so that the unique index on them can be built. When
+// several accounts share an address, the one already stored in that form,
+// or else the first by ID, keeps it; the others get the address
+// duplicate-<id>@invalid and are left for an admin to resolve. It runs
+// before the index is built and is a no-op before the users table exists.
+func MigrateUserEmails(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&entity.User{}) {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var users []struct {
+			ID    string
+			Email string
+		}
+		err := tx.Table("users").Select("id, email").Order("id").Find(&users).Error
+		if err != nil {
+			return err
+		}
+
+		owners := map[string]string{}
+		for _, user := range users {
+			if user.Email == entity.NormalizeEmail(user.Email) {
+				owners[user.Email] = user.ID
+			}
+		}
+		for _, user := range users {
+			email := entity.NormalizeEmail(user.Email)
+			if _, ok := owners[email]; !ok {
+				owners[email] = user.ID
+			}
+			if owners[email] != user.ID {
+				email = "duplicate-" + user.ID + "@invalid"
+			}
+			if email == user.Email {
+				continue
+			}
+			err = tx.Table("users").Where("id = ?", user.ID).Update("email", email).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
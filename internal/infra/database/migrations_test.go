@@ -0,0 +1,85 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type legacyProduct struct {
+	ID    string
+	Name  string
+	Price float64
+}
+
+func (legacyProduct) TableName() string {
+	return "products"
+}
+
+func TestMigrateProductPrices(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"))
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&legacyProduct{}))
+
+	id := entityPkg.NewID()
+	assert.NoError(t, db.Create(&legacyProduct{ID: id.String(), Name: "Legacy", Price: 19.99}).Error)
+
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}))
+	assert.NoError(t, MigrateProductPrices(db, "BRL"))
+	assert.False(t, db.Migrator().HasColumn(&entity.Product{}, "price"))
+
+	product, err := NewProductDB(db).FindByID(id.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Legacy", product.Name)
+	assert.Equal(t, money.New(1999, "BRL"), product.Price)
+
+	assert.NoError(t, MigrateProductPrices(db, "BRL"))
+}
+
+type legacyUser struct {
+	ID       string
+	Name     string
+	Email    string
+	Password string
+}
+
+func (legacyUser) TableName() string {
+	return "users"
+}
+
+func TestMigrateUserEmails(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, MigrateUserEmails(db))
+	assert.NoError(t, db.AutoMigrate(&legacyUser{}))
+
+	mixed, _ := entity.NewUser("Mr. Pipo", "pipo@gmail.com", "goexpert")
+	lower, _ := entity.NewUser("Pipo", "pipo@gmail.com", "goexpert")
+	other, _ := entity.NewUser("Mrs. Pipo", "mrs.pipo@gmail.com", "goexpert")
+	assert.NoError(t, db.Create(&legacyUser{ID: mixed.ID.String(), Name: mixed.Name, Email: "Pipo@Gmail.com", Password: mixed.Password}).Error)
+	assert.NoError(t, db.Create(&legacyUser{ID: lower.ID.String(), Name: lower.Name, Email: "pipo@gmail.com", Password: lower.Password}).Error)
+	assert.NoError(t, db.Create(&legacyUser{ID: other.ID.String(), Name: other.Name, Email: " Mrs.Pipo@Gmail.com", Password: other.Password}).Error)
+
+	assert.NoError(t, MigrateUserEmails(db))
+	assert.NoError(t, db.AutoMigrate(&entity.User{}))
+
+	userDB := NewUserDB(db)
+	found, err := userDB.FindByEmail("pipo@gmail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, lower.ID, found.ID)
+	found, err = userDB.FindByEmail("Mrs.Pipo@gmail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, other.ID, found.ID)
+	assert.True(t, found.ValidatePassword("goexpert"))
+	found, err = userDB.FindByEmail("duplicate-" + mixed.ID.String() + "@invalid")
+	assert.NoError(t, err)
+	assert.Equal(t, mixed.ID, found.ID)
+
+	assert.NoError(t, MigrateUserEmails(db))
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+// OrderQuery filters and pages the order list. An empty UserID lists the
+// orders of every user.
+type OrderQuery struct {
+	UserID string
+	Status entity.OrderStatus
+	Page   int
+	Limit  int
+}
+
+// OrderDB keeps the orders along with the stock they hold. Creating an order
+// reserves the stock of its items, paying commits the reservations and
+// cancelling gives the stock back, each in the same transaction as the
+// order change.
+type OrderDB struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+func NewOrderDB(db *gorm.DB) *OrderDB {
+	return &OrderDB{DB: db, Clock: clock.Real{}}
+}
+
+func (odb *OrderDB) stock(tx *gorm.DB) *StockDB {
+	return &StockDB{DB: tx, Clock: odb.Clock}
+}
+
+func (odb *OrderDB) coupons(tx *gorm.DB) *CouponDB {
+	return &CouponDB{DB: tx, Clock: odb.Clock}
+}
+
+// Create saves the order and reserves the stock of its items until
+// expiresAt, redeeming its coupon if any. Nothing is saved when any item is
+// out of stock or the coupon can no longer be redeemed.
+func (odb *OrderDB) Create(order *entity.Order, expiresAt time.Time) error {
+	return odb.DB.Transaction(func(tx *gorm.DB) error {
+		return odb.create(tx, order, expiresAt)
+	})
+}
+
+// Checkout creates the order like Create and empties the cart of its user,
+// coupon included.
+func (odb *OrderDB) Checkout(order *entity.Order, expiresAt time.Time) error {
+	return odb.DB.Transaction(func(tx *gorm.DB) error {
+		err := odb.create(tx, order, expiresAt)
+		if err != nil {
+			return err
+		}
+		err = odb.coupons(tx).RemoveFromCart(order.UserID)
+		if err != nil {
+			return err
+		}
+		return NewCartDB(tx).Clear(order.UserID)
+	})
+}
+
+func (odb *OrderDB) create(tx *gorm.DB, order *entity.Order, expiresAt time.Time) error {
+	for i := range order.Items {
+		item := &order.Items[i]
+		reservation, err := entity.NewStockReservation(item.ProductID, item.Quantity, expiresAt, "order-"+order.ID.String())
+		if err != nil {
+			return err
+		}
+		err = odb.stock(tx).Reserve(reservation)
+		if err != nil {
+			return err
+		}
+		item.ReservationID = reservation.ID
+	}
+	if order.CouponCode != "" {
+		err := odb.coupons(tx).Redeem(order.CouponCode, order.UserID, order.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Create(order).Error
+}
+
+func (odb *OrderDB) FindByID(id string) (*entity.Order, error) {
+	var order entity.Order
+	err := odb.DB.Preload("Items").First(&order, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// FindUserOrder finds an order only when it belongs to the user.
+func (odb *OrderDB) FindUserOrder(userID, id string) (*entity.Order, error) {
+	var order entity.Order
+	err := odb.DB.Preload("Items").First(&order, "id = ? AND user_id = ?", id, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Find lists the orders matching the query, newest first.
+func (odb *OrderDB) Find(query OrderQuery) ([]entity.Order, error) {
+	var orders []entity.Order
+	db := odb.DB.Preload("Items").Order("created_at desc")
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Page != 0 && query.Limit != 0 {
+		db = db.Limit(query.Limit).Offset((query.Page - 1) * query.Limit)
+	}
+	err := db.Find(&orders).Error
+	return orders, err
+}
+
+// StartPayment claims a pending order for a payment attempt lasting at most
+// hold, keeping the stock of its items reserved at least as long. Until
+// FinishPayment is called or the hold passes, other attempts fail with
+// ErrPaymentInProgress and the order cannot be cancelled. It fails with
+// ErrOrderNotPayable when the order is not pending and with
+// ErrReservationNotHeld when its stock was already released.
+func (odb *OrderDB) StartPayment(id string, hold time.Duration) (*entity.Order, error) {
+	var order *entity.Order
+	err := odb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = NewOrderDB(tx).FindByID(id)
+		if err != nil {
+			return err
+		}
+		if order.Status != entity.OrderPending {
+			return entity.ErrOrderNotPayable
+		}
+
+		now := odb.Clock.Now().UTC()
+		until := now.Add(hold)
+		result := tx.Model(&entity.Order{}).
+			Where("id = ? AND status = ?", order.ID, entity.OrderPending).
+			Where("paying_until IS NULL OR paying_until <= ?", now).
+			Update("paying_until", until)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrPaymentInProgress
+		}
+		order.PayingUntil = &until
+
+		for _, item := range order.Items {
+			err = odb.stock(tx).Extend(item.ReservationID.String(), until)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// FinishPayment lets go of the order claimed by StartPayment.
+func (odb *OrderDB) FinishPayment(id string) error {
+	return odb.DB.Model(&entity.Order{}).Where("id = ?", id).Update("paying_until", nil).Error
+}
+
+// Transition moves the order to status and settles its stock: paying
+// commits the reservations as sales, cancelling a pending order releases
+// them and cancelling a paid one returns the sold quantities to stock.
+// changedBy is recorded on the ledger movements.
+func (odb *OrderDB) Transition(id string, status entity.OrderStatus, changedBy string) (*entity.Order, error) {
+	var order *entity.Order
+	err := odb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = NewOrderDB(tx).FindByID(id)
+		if err != nil {
+			return err
+		}
+
+		from := order.Status
+		err = order.Transition(status)
+		if err != nil {
+			return err
+		}
+		order.UpdatedAt = odb.Clock.Now()
+
+		update := tx.Model(&entity.Order{}).Where("id = ? AND status = ?", order.ID, from)
+		if status == entity.OrderCancelled && from == entity.OrderPending {
+			// a payment attempt may be charging the order
+			now := odb.Clock.Now().UTC()
+			if order.PayingUntil != nil && order.PayingUntil.After(now) {
+				return entity.ErrPaymentInProgress
+			}
+			update = update.Where("paying_until IS NULL OR paying_until <= ?", now)
+		}
+		result := update.Updates(map[string]interface{}{"status": order.Status, "updated_at": order.UpdatedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrInvalidOrderTransition
+		}
+
+		switch {
+		case status == entity.OrderPaid:
+			for _, item := range order.Items {
+				err = odb.stock(tx).Commit(item.ReservationID.String(), changedBy)
+				if err != nil {
+					return err
+				}
+			}
+		case status == entity.OrderCancelled && from == entity.OrderPending:
+			for _, item := range order.Items {
+				err = odb.stock(tx).Release(item.ReservationID.String())
+				if err != nil && !errors.Is(err, entity.ErrReservationNotHeld) {
+					return err
+				}
+			}
+		case status == entity.OrderCancelled && from == entity.OrderPaid:
+			for _, item := range order.Items {
+				movement, err := entity.NewStockAdjustment(item.ProductID, item.Quantity, entity.StockReturn, fmt.Sprintf("order %s cancelled", order.ID), changedBy)
+				if err != nil {
+					return err
+				}
+				_, err = odb.stock(tx).Adjust(movement)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
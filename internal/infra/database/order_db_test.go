@@ -0,0 +1,126 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/stretchr/testify/assert"
+)
+
+func createOrder(t *testing.T, product *entity.Product, quantity int64) *entity.Order {
+	item, err := entity.NewCartItem("user-1", product, nil, quantity)
+	assert.NoError(t, err)
+	order, err := entity.NewOrder("user-1", []entity.CartLine{{Item: *item, Product: product}})
+	assert.NoError(t, err)
+	return order
+}
+
+func TestCreateOrderReservesStock(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	orderDB := NewOrderDB(stockDB.DB)
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	order := createOrder(t, product, 3)
+	assert.NoError(t, orderDB.Create(order, time.Now().Add(time.Hour)))
+
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), level.Reserved)
+
+	// nothing is saved when the stock runs out
+	tooMany := createOrder(t, product, 3)
+	assert.ErrorIs(t, orderDB.Create(tooMany, time.Now().Add(time.Hour)), entity.ErrInsufficientStock)
+	_, err = orderDB.FindByID(tooMany.ID.String())
+	assert.Error(t, err)
+
+	found, err := orderDB.FindUserOrder("user-1", order.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Items, 1)
+	assert.Equal(t, product.Name, found.Items[0].ProductName)
+
+	_, err = orderDB.FindUserOrder("user-2", order.ID.String())
+	assert.Error(t, err)
+
+	orders, err := orderDB.Find(OrderQuery{UserID: "user-1", Status: entity.OrderPending})
+	assert.NoError(t, err)
+	assert.Len(t, orders, 1)
+}
+
+func TestOrderTransitionsSettleStock(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	orderDB := NewOrderDB(stockDB.DB)
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	paid := createOrder(t, product, 2)
+	assert.NoError(t, orderDB.Create(paid, time.Now().Add(time.Hour)))
+	cancelled := createOrder(t, product, 1)
+	assert.NoError(t, orderDB.Create(cancelled, time.Now().Add(time.Hour)))
+
+	_, err = orderDB.Transition(paid.ID.String(), entity.OrderShipped, "admin")
+	assert.ErrorIs(t, err, entity.ErrInvalidOrderTransition)
+
+	order, err := orderDB.Transition(paid.ID.String(), entity.OrderPaid, "admin")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderPaid, order.Status)
+
+	_, err = orderDB.Transition(cancelled.ID.String(), entity.OrderCancelled, "user-1")
+	assert.NoError(t, err)
+
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), level.OnHand)
+	assert.Equal(t, int64(0), level.Reserved)
+
+	// cancelling a paid order puts the sold stock back
+	_, err = orderDB.Transition(paid.ID.String(), entity.OrderCancelled, "admin")
+	assert.NoError(t, err)
+	level, err = stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), level.OnHand)
+
+	found, err := orderDB.FindByID(paid.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderCancelled, found.Status)
+}
+
+func TestStartPaymentHoldsTheOrder(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	now := time.Now()
+	fake := clock.NewFake(now)
+	orderDB := NewOrderDB(stockDB.DB)
+	orderDB.Clock = fake
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	order := createOrder(t, product, 2)
+	assert.NoError(t, orderDB.Create(order, now.Add(time.Minute)))
+
+	_, err = orderDB.StartPayment(order.ID.String(), 10*time.Minute)
+	assert.NoError(t, err)
+	// the stock outlasts the attempt
+	reservation, err := stockDB.FindReservation(order.Items[0].ReservationID.String())
+	assert.NoError(t, err)
+	assert.WithinDuration(t, now.Add(10*time.Minute), reservation.ExpiresAt, time.Second)
+
+	_, err = orderDB.StartPayment(order.ID.String(), 10*time.Minute)
+	assert.ErrorIs(t, err, entity.ErrPaymentInProgress)
+	_, err = orderDB.Transition(order.ID.String(), entity.OrderCancelled, "user-1")
+	assert.ErrorIs(t, err, entity.ErrPaymentInProgress)
+
+	assert.NoError(t, orderDB.FinishPayment(order.ID.String()))
+	_, err = orderDB.StartPayment(order.ID.String(), 10*time.Minute)
+	assert.NoError(t, err)
+
+	// an attempt that never finished lets go once its hold passes
+	fake.Advance(11 * time.Minute)
+	_, err = orderDB.StartPayment(order.ID.String(), 10*time.Minute)
+	assert.ErrorIs(t, err, entity.ErrReservationNotHeld)
+	_, err = orderDB.Transition(order.ID.String(), entity.OrderCancelled, "user-1")
+	assert.NoError(t, err)
+	_, err = orderDB.StartPayment(order.ID.String(), 10*time.Minute)
+	assert.ErrorIs(t, err, entity.ErrOrderNotPayable)
+}
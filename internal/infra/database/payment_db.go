@@ -0,0 +1,137 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentDB records the payment attempts of the orders and moves each order
+// along with its payment.
+type PaymentDB struct {
+	DB *gorm.DB
+}
+
+func NewPaymentDB(db *gorm.DB) *PaymentDB {
+	return &PaymentDB{DB: db}
+}
+
+func (pdb *PaymentDB) Create(payment *entity.Payment) error {
+	return pdb.DB.Create(payment).Error
+}
+
+func (pdb *PaymentDB) FindByID(id string) (*entity.Payment, error) {
+	var payment entity.Payment
+	err := pdb.DB.First(&payment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// FindByKey finds the attempt made with an idempotency key for the order.
+func (pdb *PaymentDB) FindByKey(orderID, idempotencyKey string) (*entity.Payment, error) {
+	var payment entity.Payment
+	err := pdb.DB.First(&payment, "order_id = ? AND idempotency_key = ?", orderID, idempotencyKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (pdb *PaymentDB) FindByProviderRef(provider, providerRef string) (*entity.Payment, error) {
+	var payment entity.Payment
+	err := pdb.DB.First(&payment, "provider = ? AND provider_ref = ?", provider, providerRef).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (pdb *PaymentDB) FindByOrder(orderID string) ([]entity.Payment, error) {
+	var payments []entity.Payment
+	err := pdb.DB.Where("order_id = ?", orderID).Order("created_at").Find(&payments).Error
+	return payments, err
+}
+
+// Settle moves the payment to status and its order along with it: a
+// captured payment pays the order and a refunded one cancels it. Changes the
+// state machines do not allow are ignored, so settling twice is harmless.
+func (pdb *PaymentDB) Settle(id string, status entity.PaymentStatus, changedBy string) (*entity.Payment, error) {
+	var payment *entity.Payment
+	err := pdb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		payment, err = NewPaymentDB(tx).settle(id, status, changedBy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// ApplyEvent settles the payment of a provider webhook once, ignoring
+// events that were already applied.
+func (pdb *PaymentDB) ApplyEvent(eventID, eventType, provider, providerRef string, status entity.PaymentStatus) error {
+	return pdb.DB.Transaction(func(tx *gorm.DB) error {
+		payment, err := NewPaymentDB(tx).FindByProviderRef(provider, providerRef)
+		if err != nil {
+			return err
+		}
+
+		event := entity.PaymentEvent{ID: eventID, Type: eventType, ReceivedAt: time.Now()}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		_, err = NewPaymentDB(tx).settle(payment.ID.String(), status, "payment:"+provider)
+		return err
+	})
+}
+
+// settle must run inside a transaction.
+func (pdb *PaymentDB) settle(id string, status entity.PaymentStatus, changedBy string) (*entity.Payment, error) {
+	payment, err := pdb.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !payment.CanTransition(status) {
+		return payment, nil
+	}
+
+	from := payment.Status
+	payment.Status = status
+	payment.UpdatedAt = time.Now()
+	result := pdb.DB.Model(&entity.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, from).
+		Updates(map[string]interface{}{"status": payment.Status, "updated_at": payment.UpdatedAt})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		// settled concurrently
+		return pdb.FindByID(id)
+	}
+
+	orderStatus, ok := status.OrderStatus()
+	if !ok {
+		return payment, nil
+	}
+	order, err := NewOrderDB(pdb.DB).FindByID(payment.OrderID.String())
+	if err != nil {
+		return nil, err
+	}
+	if order.CanTransition(orderStatus) {
+		_, err = NewOrderDB(pdb.DB).Transition(order.ID.String(), orderStatus, changedBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return payment, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createPaidAttempt(t *testing.T) (*PaymentDB, *OrderDB, *entity.Order, *entity.Payment) {
+	stockDB, product := createStockDB(t)
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	orderDB := NewOrderDB(stockDB.DB)
+	order := createOrder(t, product, 2)
+	assert.NoError(t, orderDB.Create(order, time.Now().Add(time.Hour)))
+
+	paymentDB := NewPaymentDB(stockDB.DB)
+	payment, err := entity.NewPayment(order, "key-1", "fake", "fake_card")
+	assert.NoError(t, err)
+	payment.ProviderRef = "fake_" + payment.ID.String()
+	payment.Status = entity.PaymentAuthorized
+	assert.NoError(t, paymentDB.Create(payment))
+
+	return paymentDB, orderDB, order, payment
+}
+
+func TestPaymentAttemptsAreUniquePerKey(t *testing.T) {
+	paymentDB, _, order, payment := createPaidAttempt(t)
+
+	found, err := paymentDB.FindByKey(order.ID.String(), "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, payment.ID, found.ID)
+
+	retry, _ := entity.NewPayment(order, "key-1", "fake", "fake_card")
+	assert.Error(t, paymentDB.Create(retry))
+
+	_, err = paymentDB.FindByKey(order.ID.String(), "key-2")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSettlePaymentMovesOrder(t *testing.T) {
+	paymentDB, orderDB, order, payment := createPaidAttempt(t)
+
+	settled, err := paymentDB.Settle(payment.ID.String(), entity.PaymentCaptured, "tester")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.PaymentCaptured, settled.Status)
+	found, _ := orderDB.FindByID(order.ID.String())
+	assert.Equal(t, entity.OrderPaid, found.Status)
+
+	// settling again changes nothing
+	_, err = paymentDB.Settle(payment.ID.String(), entity.PaymentCaptured, "tester")
+	assert.NoError(t, err)
+
+	_, err = paymentDB.Settle(payment.ID.String(), entity.PaymentRefunded, "tester")
+	assert.NoError(t, err)
+	found, _ = orderDB.FindByID(order.ID.String())
+	assert.Equal(t, entity.OrderCancelled, found.Status)
+}
+
+func TestApplyPaymentEventOnce(t *testing.T) {
+	paymentDB, orderDB, order, payment := createPaidAttempt(t)
+
+	assert.NoError(t, paymentDB.ApplyEvent("evt_1", "payment.captured", "fake", payment.ProviderRef, entity.PaymentCaptured))
+	found, _ := orderDB.FindByID(order.ID.String())
+	assert.Equal(t, entity.OrderPaid, found.Status)
+
+	assert.NoError(t, paymentDB.ApplyEvent("evt_2", "payment.refunded", "fake", payment.ProviderRef, entity.PaymentRefunded))
+	// a redelivered event is ignored
+	assert.NoError(t, paymentDB.ApplyEvent("evt_1", "payment.captured", "fake", payment.ProviderRef, entity.PaymentCaptured))
+
+	settled, _ := paymentDB.FindByID(payment.ID.String())
+	assert.Equal(t, entity.PaymentRefunded, settled.Status)
+	found, _ = orderDB.FindByID(order.ID.String())
+	assert.Equal(t, entity.OrderCancelled, found.Status)
+
+	err := paymentDB.ApplyEvent("evt_3", "payment.captured", "fake", "fake_unknown", entity.PaymentCaptured)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
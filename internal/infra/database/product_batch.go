@@ -0,0 +1,96 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
+)
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+var (
+	ErrBatchAborted          = errors.New("the operation was not applied because another operation of the atomic batch failed")
+	ErrInvalidBatchOperation = errors.New("op must be create, update or delete")
+	ErrBatchProductRequired  = errors.New("product is required for create and update operations")
+)
+
+// ProductOperation is one of the operations of a batch. Product is the
+// product to create or the new state of the one to update; deletes only
+// need ID.
+type ProductOperation struct {
+	Op      string
+	ID      string
+	Product *entity.Product
+}
+
+// Batch runs the operations in order and returns the error of each of them,
+// nil for the ones applied. Atomic batches run in a single transaction,
+// rolled back as soon as an operation fails; the other operations then fail
+// with ErrBatchAborted. Price changes run the price hooks only once they
+// are committed.
+func (pdb *ProductDB) Batch(ops []ProductOperation, atomic bool, changedBy string) []error {
+	errs := make([]error, len(ops))
+	if !atomic {
+		for i, op := range ops {
+			errs[i] = pdb.apply(op, changedBy)
+		}
+		return errs
+	}
+
+	type priceChange struct {
+		product  *entity.Product
+		previous money.Money
+	}
+	var changes []priceChange
+	err := pdb.DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range ops {
+			var previous *money.Money
+			var err error
+			if op.Op == OpUpdate {
+				previous, err = update(tx, op.Product, changedBy)
+			} else {
+				err = NewProductDB(tx).apply(op, changedBy)
+			}
+			if err != nil {
+				errs[i] = err
+				return err
+			}
+			if previous != nil {
+				changes = append(changes, priceChange{product: op.Product, previous: *previous})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = ErrBatchAborted
+			}
+		}
+		return errs
+	}
+
+	for _, change := range changes {
+		pdb.runPriceHooks(change.product, change.previous)
+	}
+	return errs
+}
+
+func (pdb *ProductDB) apply(op ProductOperation, changedBy string) error {
+	switch op.Op {
+	case OpCreate:
+		return pdb.Create(op.Product)
+	case OpUpdate:
+		return pdb.Update(op.Product, changedBy)
+	case OpDelete:
+		return pdb.Delete(op.ID)
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidBatchOperation, op.Op)
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestBatchProducts(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	pdb := NewProductDB(db)
+	var hooked []string
+	pdb.PriceHooks = append(pdb.PriceHooks, func(product *entity.Product, previous money.Money) {
+		hooked = append(hooked, product.ID.String())
+	})
+
+	existing, _ := entity.NewProduct("Batch existing", money.MustParse("10.00", "USD"))
+	doomed, _ := entity.NewProduct("Batch doomed", money.MustParse("5.00", "USD"))
+	assert.NoError(t, pdb.Create(existing))
+	assert.NoError(t, pdb.Create(doomed))
+
+	created, _ := entity.NewProduct("Batch created", money.MustParse("1.00", "USD"))
+	changed := &entity.Product{ID: existing.ID, Name: "Batch changed", Price: money.MustParse("12.00", "USD")}
+	errs := pdb.Batch([]ProductOperation{
+		{Op: OpCreate, Product: created},
+		{Op: OpUpdate, Product: changed},
+		{Op: OpDelete, ID: entityPkg.NewID().String()},
+		{Op: OpDelete, ID: doomed.ID.String()},
+	}, false, "admin")
+	assert.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.ErrorIs(t, errs[2], gorm.ErrRecordNotFound)
+	assert.NoError(t, errs[3])
+
+	found, err := pdb.FindByID(existing.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Batch changed", found.Name)
+	_, err = pdb.FindByID(created.ID.String())
+	assert.NoError(t, err)
+	_, err = pdb.FindByID(doomed.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.Equal(t, []string{existing.ID.String()}, hooked)
+}
+
+func TestAtomicBatchProductsRollsBack(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	pdb := NewProductDB(db)
+	hooked := 0
+	pdb.PriceHooks = append(pdb.PriceHooks, func(product *entity.Product, previous money.Money) {
+		hooked++
+	})
+
+	existing, _ := entity.NewProduct("Atomic existing", money.MustParse("10.00", "USD"))
+	assert.NoError(t, pdb.Create(existing))
+
+	created, _ := entity.NewProduct("Atomic created", money.MustParse("1.00", "USD"))
+	changed := &entity.Product{ID: existing.ID, Name: "Atomic changed", Price: money.MustParse("12.00", "USD")}
+	errs := pdb.Batch([]ProductOperation{
+		{Op: OpCreate, Product: created},
+		{Op: OpUpdate, Product: changed},
+		{Op: OpDelete, ID: entityPkg.NewID().String()},
+		{Op: OpDelete, ID: existing.ID.String()},
+	}, true, "admin")
+	assert.Len(t, errs, 4)
+	assert.ErrorIs(t, errs[0], ErrBatchAborted)
+	assert.ErrorIs(t, errs[1], ErrBatchAborted)
+	assert.ErrorIs(t, errs[2], gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, errs[3], ErrBatchAborted)
+
+	found, err := pdb.FindByID(existing.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Atomic existing", found.Name)
+	_, err = pdb.FindByID(created.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	history, err := pdb.FindPriceHistory(existing.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+	assert.Zero(t, hooked)
+
+	errs = pdb.Batch([]ProductOperation{
+		{Op: OpCreate, Product: created},
+		{Op: OpUpdate, Product: changed},
+	}, true, "admin")
+	assert.Equal(t, []error{nil, nil}, errs)
+	found, err = pdb.FindByID(existing.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, "Atomic changed", found.Name)
+	assert.Equal(t, 1, hooked)
+}
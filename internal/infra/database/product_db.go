@@ -1,12 +1,41 @@
 package database
 
 import (
+	"errors"
+	"time"
+
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
 	"gorm.io/gorm"
 )
 
+const (
+	// TagModeAny matches products carrying at least one of the tags.
+	TagModeAny = "any"
+	// TagModeAll matches products carrying every tag.
+	TagModeAll = "all"
+)
+
+// ProductQuery filters and pages the product list. Tags are expected to be
+// normalized already. Sort is asc or desc by creation date, or rating and
+// -rating by rating average. MinRating leaves out products without reviews.
+type ProductQuery struct {
+	Page      int
+	Limit     int
+	Sort      string
+	Tags      []string
+	TagMode   string
+	MinRating float64
+}
+
+// PriceHook is told about a product whose price changed, once the change is
+// committed.
+type PriceHook func(product *entity.Product, previous money.Money)
+
 type ProductDB struct {
 	DB *gorm.DB
+	// PriceHooks run after Update changes the price of a product.
+	PriceHooks []PriceHook
 }
 
 func NewProductDB(db *gorm.DB) *ProductDB {
@@ -26,32 +55,281 @@ func (pdb *ProductDB) FindByID(id string) (*entity.Product, error) {
 	return &product, nil
 }
 
-func (pdb *ProductDB) Update(product *entity.Product) error {
-	_, err := pdb.FindByID(product.ID.String())
+// Update saves the product and, when its price changed, records the change
+// in the price history attributed to changedBy and runs the price hooks.
+func (pdb *ProductDB) Update(product *entity.Product, changedBy string) error {
+	var previous *money.Money
+	err := pdb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		previous, err = update(tx, product, changedBy)
+		return err
+	})
 	if err != nil {
 		return err
 	}
-	return pdb.DB.Save(product).Error
+
+	if previous != nil {
+		pdb.runPriceHooks(product, *previous)
+	}
+	return nil
+}
+
+// update saves the product on tx, returning its previous price when it
+// changed.
+func update(tx *gorm.DB, product *entity.Product, changedBy string) (*money.Money, error) {
+	current, err := NewProductDB(tx).FindByID(product.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	product.CreatedAt = current.CreatedAt
+	product.RatingAverage = current.RatingAverage
+	product.RatingCount = current.RatingCount
+
+	if current.Price.Currency != product.Price.Currency {
+		err = checkCurrencyChange(tx, product)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var previous *money.Money
+	if !current.Price.Equal(product.Price) {
+		history := entity.NewProductPriceHistory(product.ID, current.Price, product.Price, changedBy)
+		err = tx.Create(history).Error
+		if err != nil {
+			return nil, err
+		}
+		previous = &current.Price
+	}
+
+	return previous, tx.Save(product).Error
+}
+
+// checkCurrencyChange refuses to change the currency of a product while
+// variants have prices of their own or prices are still scheduled, since
+// those are in the old currency.
+func checkCurrencyChange(tx *gorm.DB, product *entity.Product) error {
+	var variants int64
+	err := tx.Model(&entity.Variant{}).
+		Where("product_id = ? AND price_currency IS NOT NULL AND price_currency <> ''", product.ID).
+		Count(&variants).Error
+	if err != nil {
+		return err
+	}
+	var scheduled int64
+	err = tx.Model(&entity.ScheduledPrice{}).
+		Where("product_id = ? AND status IN ?", product.ID, []entity.ScheduledPriceStatus{entity.ScheduledPriceScheduled, entity.ScheduledPriceActive}).
+		Count(&scheduled).Error
+	if err != nil {
+		return err
+	}
+	if variants > 0 || scheduled > 0 {
+		return entity.ErrCurrencyLocked
+	}
+	return nil
+}
+
+func (pdb *ProductDB) runPriceHooks(product *entity.Product, previous money.Money) {
+	for _, hook := range pdb.PriceHooks {
+		hook(product, previous)
+	}
+}
+
+func (pdb *ProductDB) FindPriceHistory(id string) ([]entity.ProductPriceHistory, error) {
+	var history []entity.ProductPriceHistory
+	err := pdb.DB.Where("product_id = ?", id).Order("changed_at desc").Find(&history).Error
+	return history, err
+}
+
+// FindPriceAt returns the price the product had at the given time.
+func (pdb *ProductDB) FindPriceAt(id string, at time.Time) (money.Money, error) {
+	product, err := pdb.FindByID(id)
+	if err != nil {
+		return money.Money{}, err
+	}
+	if at.Before(product.CreatedAt) {
+		return money.Money{}, entity.ErrPriceNotFound
+	}
+
+	var change entity.ProductPriceHistory
+	err = pdb.DB.Where("product_id = ? AND changed_at <= ?", id, at).Order("changed_at desc").First(&change).Error
+	if err == nil {
+		return change.NewPrice, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return money.Money{}, err
+	}
+
+	// no change happened yet at that time, so the price was the one replaced
+	// by the first change after it, or the current one if it never changed
+	err = pdb.DB.Where("product_id = ? AND changed_at > ?", id, at).Order("changed_at asc").First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return product.Price, nil
+	}
+	if err != nil {
+		return money.Money{}, err
+	}
+	return change.OldPrice, nil
 }
 
+// Delete removes the product along with everything that refers to it:
+// variants, images, category assignments, tags, wishlist and cart items,
+// reviews and its stock ledger, level and reservations. Orders keep their
+// own copy of the product and are left alone. Image blobs are left to the
+// blob store.
 func (pdb *ProductDB) Delete(id string) error {
 	product, err := pdb.FindByID(id)
 	if err != nil {
 		return err
 	}
-	return pdb.DB.Delete(product).Error
+	dependents := []interface{}{
+		&entity.ProductCategory{},
+		&entity.ProductTag{},
+		&entity.Variant{},
+		&entity.ProductImage{},
+		&entity.WishlistItem{},
+		&entity.CartItem{},
+		&entity.Review{},
+		&entity.StockReservation{},
+		&entity.StockMovement{},
+		&entity.StockLevel{},
+	}
+	return pdb.DB.Transaction(func(tx *gorm.DB) error {
+		for _, dependent := range dependents {
+			err := tx.Where("product_id = ?", product.ID).Delete(dependent).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Delete(product).Error
+	})
 }
 
 func (pdb *ProductDB) FindAll(page, limit int, sort string) ([]entity.Product, error) {
+	return pdb.Find(ProductQuery{Page: page, Limit: limit, Sort: sort})
+}
+
+// Find lists the products matching the query.
+func (pdb *ProductDB) Find(query ProductQuery) ([]entity.Product, error) {
 	var products []entity.Product
-	var err error
-	if sort != "" && sort != "asc" && sort != "desc" {
-		sort = "asc"
+	err := pdb.filter(query).Find(&products).Error
+	return products, err
+}
+
+// Stream reads the products matching the query through a cursor and passes
+// them to fn batchSize at a time, so the list is never held in memory as a
+// whole. The batch is reused between calls; an error from fn stops the
+// stream and is returned.
+func (pdb *ProductDB) Stream(query ProductQuery, batchSize int, fn func([]entity.Product) error) error {
+	rows, err := pdb.filter(query).Model(&entity.Product{}).Rows()
+	if err != nil {
+		return err
 	}
-	if page != 0 && limit != 0 {
-		err = pdb.DB.Limit(limit).Offset((page - 1) * limit).Order("created_at " + sort).Find(&products).Error
-	} else {
-		err = pdb.DB.Order("created_at " + sort).Find(&products).Error
+	defer rows.Close()
+
+	batch := make([]entity.Product, 0, batchSize)
+	for rows.Next() {
+		var product entity.Product
+		err := pdb.DB.ScanRows(rows, &product)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, product)
+		if len(batch) < batchSize {
+			continue
+		}
+		err = fn(batch)
+		if err != nil {
+			return err
+		}
+		batch = batch[:0]
 	}
-	return products, err
+	err = rows.Err()
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return fn(batch)
+}
+
+// filter builds the query shared by Find and Stream.
+func (pdb *ProductDB) filter(query ProductQuery) *gorm.DB {
+	var order string
+	switch query.Sort {
+	case "desc":
+		order = "created_at desc"
+	case "rating":
+		order = "rating_average asc, rating_count asc, created_at asc"
+	case "-rating":
+		order = "rating_average desc, rating_count desc, created_at asc"
+	default:
+		order = "created_at asc"
+	}
+
+	db := pdb.DB.Order(order)
+	if query.MinRating > 0 {
+		db = db.Where("rating_count > 0 AND rating_average >= ?", query.MinRating)
+	}
+	if query.Page != 0 && query.Limit != 0 {
+		db = db.Limit(query.Limit).Offset((query.Page - 1) * query.Limit)
+	}
+	if len(query.Tags) > 0 {
+		tagged := pdb.DB.Model(&entity.ProductTag{}).Select("product_id").Where("tag IN ?", query.Tags)
+		if query.TagMode == TagModeAll {
+			tagged = tagged.Group("product_id").Having("COUNT(DISTINCT tag) = ?", len(query.Tags))
+		}
+		db = db.Where("id IN (?)", tagged)
+	}
+	return db
+}
+
+// SetTags replaces the tags of the product.
+func (pdb *ProductDB) SetTags(id string, tags []string) error {
+	return pdb.DB.Transaction(func(tx *gorm.DB) error {
+		product, err := NewProductDB(tx).FindByID(id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Where("product_id = ?", product.ID).Delete(&entity.ProductTag{}).Error
+		if err != nil {
+			return err
+		}
+		if len(tags) == 0 {
+			return nil
+		}
+
+		productTags := make([]entity.ProductTag, len(tags))
+		for i, tag := range tags {
+			productTags[i] = entity.ProductTag{ProductID: product.ID, Tag: tag}
+		}
+		return tx.Create(&productTags).Error
+	})
+}
+
+// FindTags returns the tags of each of the given products, sorted.
+func (pdb *ProductDB) FindTags(ids []string) (map[string][]string, error) {
+	var productTags []entity.ProductTag
+	err := pdb.DB.Where("product_id IN ?", ids).Order("tag").Find(&productTags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	tags := map[string][]string{}
+	for _, productTag := range productTags {
+		id := productTag.ProductID.String()
+		tags[id] = append(tags[id], productTag.Tag)
+	}
+	return tags, nil
+}
+
+// CountTags returns every tag in use with the number of products carrying
+// it, most used first.
+func (pdb *ProductDB) CountTags() ([]entity.TagCount, error) {
+	var counts []entity.TagCount
+	err := pdb.DB.Model(&entity.ProductTag{}).
+		Select("tag, COUNT(*) AS count").
+		Group("tag").
+		Order("count desc, tag").
+		Scan(&counts).Error
+	return counts, err
 }
@@ -1,11 +1,14 @@
 package database
 
 import (
+	"errors"
 	"fmt"
-	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -15,7 +18,7 @@ func TestCreateProduct(t *testing.T) {
 	db, err := CreateGormDBAndAutoMigrate()
 	assert.NoError(t, err)
 
-	product, err := entity.NewProduct("Pimponeta", 10000.0)
+	product, err := entity.NewProduct("Pimponeta", money.MustParse("10000.00", "BRL"))
 	assert.NoError(t, err)
 
 	prodDB := NewProductDB(db)
@@ -35,7 +38,7 @@ func TestFindProductByID(t *testing.T) {
 	db, err := CreateGormDBAndAutoMigrate()
 	assert.NoError(t, err)
 
-	product, err := entity.NewProduct("TestFindProductByID", 1000.0)
+	product, err := entity.NewProduct("TestFindProductByID", money.MustParse("1000.00", "BRL"))
 	assert.NoError(t, err)
 
 	err = db.Create(product).Error
@@ -54,7 +57,7 @@ func TestUpdateProduct(t *testing.T) {
 	db, err := CreateGormDBAndAutoMigrate()
 	assert.NoError(t, err)
 
-	product, err := entity.NewProduct("TestUpdateProduct", 1000.0)
+	product, err := entity.NewProduct("TestUpdateProduct", money.MustParse("1000.00", "BRL"))
 	assert.NoError(t, err)
 
 	err = db.Create(product).Error
@@ -68,23 +71,124 @@ func TestUpdateProduct(t *testing.T) {
 	assert.Equal(t, product.Price, createdProductFound.Price)
 
 	product.Name = "UpdatedProduct"
-	product.Price = 100
+	product.Price = money.MustParse("100.00", "BRL")
 
 	prodDB := NewProductDB(db)
-	prodDB.Update(product)
+	err = prodDB.Update(product, "tester")
+	assert.NoError(t, err)
 
 	var updatedProductFound *entity.Product
 	err = db.First(&updatedProductFound, "id = ?", product.ID).Error
 	assert.NoError(t, err)
 	assert.Equal(t, updatedProductFound.Name, "UpdatedProduct")
-	assert.Equal(t, updatedProductFound.Price, 100.0)
+	assert.Equal(t, money.New(10000, "BRL"), updatedProductFound.Price)
+}
+
+func TestUpdateProductRecordsPriceHistory(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+
+	product, err := entity.NewProduct("TestUpdateProductRecordsPriceHistory", money.MustParse("10.00", "BRL"))
+	assert.NoError(t, err)
+	prodDB := NewProductDB(db)
+	assert.NoError(t, prodDB.Create(product))
+
+	product.Name = "Renamed"
+	assert.NoError(t, prodDB.Update(product, "tester"))
+	history, err := prodDB.FindPriceHistory(product.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	product.Price = money.MustParse("12.00", "BRL")
+	assert.NoError(t, prodDB.Update(product, "tester"))
+	history, err = prodDB.FindPriceHistory(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, money.MustParse("10.00", "BRL"), history[0].OldPrice)
+	assert.Equal(t, money.MustParse("12.00", "BRL"), history[0].NewPrice)
+	assert.Equal(t, "tester", history[0].ChangedBy)
+}
+
+func TestFindPriceAt(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	product, err := entity.NewProduct("TestFindPriceAt", money.MustParse("12.00", "BRL"))
+	assert.NoError(t, err)
+	product.CreatedAt = createdAt
+	assert.NoError(t, db.Create(product).Error)
+
+	for i, change := range []struct {
+		at       time.Time
+		old, new string
+	}{
+		{at: createdAt.AddDate(0, 1, 0), old: "10.00", new: "11.00"},
+		{at: createdAt.AddDate(0, 2, 0), old: "11.00", new: "12.00"},
+	} {
+		history := entity.NewProductPriceHistory(product.ID, money.MustParse(change.old, "BRL"), money.MustParse(change.new, "BRL"), "tester")
+		history.ChangedAt = change.at
+		assert.NoError(t, db.Create(history).Error, i)
+	}
+
+	prodDB := NewProductDB(db)
+	price, err := prodDB.FindPriceAt(product.ID.String(), createdAt.AddDate(0, 0, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("10.00", "BRL"), price)
+
+	price, err = prodDB.FindPriceAt(product.ID.String(), createdAt.AddDate(0, 1, 10))
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("11.00", "BRL"), price)
+
+	price, err = prodDB.FindPriceAt(product.ID.String(), createdAt.AddDate(1, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("12.00", "BRL"), price)
+
+	_, err = prodDB.FindPriceAt(product.ID.String(), createdAt.AddDate(0, 0, -1))
+	assert.ErrorIs(t, err, entity.ErrPriceNotFound)
+}
+
+func TestUpdateProductCurrencyIsLocked(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	prodDB := NewProductDB(db)
+
+	product, err := entity.NewProduct("TestUpdateProductCurrencyIsLocked", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, prodDB.Create(product))
+
+	// variants at the product price follow it to the new currency
+	variant, err := entity.NewVariant(product, "LOCKED-"+product.ID.String()[:8], nil, nil, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(variant).Error)
+	product.Price = money.MustParse("50.00", "BRL")
+	assert.NoError(t, prodDB.Update(product, "tester"))
+
+	price := money.MustParse("55.00", "BRL")
+	variant.Price = &price
+	assert.NoError(t, db.Save(variant).Error)
+	product.Price = money.MustParse("10.00", "USD")
+	assert.ErrorIs(t, prodDB.Update(product, "tester"), entity.ErrCurrencyLocked)
+	variant.Price = nil
+	assert.NoError(t, db.Save(variant).Error)
+
+	product.Price = money.MustParse("50.00", "BRL")
+	scheduled, err := entity.NewScheduledPrice(product, money.MustParse("45.00", "BRL"), time.Now().Add(time.Hour), nil, "tester")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(scheduled).Error)
+	product.Price = money.MustParse("10.00", "USD")
+	assert.ErrorIs(t, prodDB.Update(product, "tester"), entity.ErrCurrencyLocked)
+
+	// a price change in the same currency is fine
+	product.Price = money.MustParse("60.00", "BRL")
+	assert.NoError(t, prodDB.Update(product, "tester"))
 }
 
 func TestDeleteProduct(t *testing.T) {
 	db, err := CreateGormDBAndAutoMigrate()
 	assert.NoError(t, err)
 
-	product, err := entity.NewProduct("TestDeleteProduct", 1.0)
+	product, err := entity.NewProduct("TestDeleteProduct", money.MustParse("1.00", "BRL"))
 	assert.NoError(t, err)
 
 	err = db.Create(product).Error
@@ -105,12 +209,55 @@ func TestDeleteProduct(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestDeleteProductRemovesItsDependents(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	prodDB := NewProductDB(db)
+
+	product, err := entity.NewProduct("TestDeleteProductRemovesItsDependents", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, prodDB.Create(product))
+
+	item, err := entity.NewCartItem("buyer@example.com", product, nil, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(item).Error)
+
+	review, err := entity.NewReview(product.ID, "buyer@example.com", 5, "Great")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(review).Error)
+
+	stockDB := NewStockDB(db)
+	movement, err := entity.NewStockAdjustment(product.ID, 5, entity.StockRestock, "", "tester")
+	assert.NoError(t, err)
+	_, err = stockDB.Adjust(movement)
+	assert.NoError(t, err)
+	reservation, err := entity.NewStockReservation(product.ID, 2, time.Now().Add(time.Hour), "order")
+	assert.NoError(t, err)
+	assert.NoError(t, stockDB.Reserve(reservation))
+
+	assert.NoError(t, prodDB.Delete(product.ID.String()))
+
+	for name, dependent := range map[string]interface{}{
+		"cart items":         &entity.CartItem{},
+		"reviews":            &entity.Review{},
+		"stock ledger":       &entity.StockMovement{},
+		"stock level":        &entity.StockLevel{},
+		"stock reservations": &entity.StockReservation{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var count int64
+			assert.NoError(t, db.Model(dependent).Where("product_id = ?", product.ID).Count(&count).Error)
+			assert.Zero(t, count)
+		})
+	}
+}
+
 func TestFindAllProducts(t *testing.T) {
 	db, err := CreateGormDBAndAutoMigrate()
 	assert.NoError(t, err)
 
 	for i := 1; i < 24; i++ {
-		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), rand.Float64()*100)
+		product, err := entity.NewProduct(fmt.Sprintf("Product %d", i), money.New(int64(i)*100+99, "BRL"))
 		assert.NoError(t, err)
 		err = db.Create(product).Error
 		assert.NoError(t, err)
@@ -147,6 +294,112 @@ func CreateGormDBAndAutoMigrate() (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	db.AutoMigrate(&entity.Product{})
+	db.AutoMigrate(&entity.Product{}, &entity.ProductPriceHistory{}, &entity.Category{}, &entity.ProductCategory{}, &entity.ProductTag{}, &entity.Variant{}, &entity.ProductImage{}, &entity.CartItem{}, &entity.Review{}, &entity.Wishlist{}, &entity.WishlistItem{}, &entity.Coupon{}, &entity.CouponRedemption{}, &entity.CartCoupon{}, &entity.TaxRegion{}, &entity.TaxRate{}, &entity.StockLevel{}, &entity.StockMovement{}, &entity.StockReservation{}, &entity.ScheduledPrice{})
 	return db, nil
 }
+
+func TestFindProductsByTags(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	productDB := NewProductDB(db)
+
+	// the tags are unique to this run since the test database is shared
+	suffix := entityPkg.NewID().String()[:8]
+	sale, fresh := "sale-"+suffix, "new-"+suffix
+
+	tv, _ := entity.NewProduct("TV", money.MustParse("1000.00", "USD"))
+	phone, _ := entity.NewProduct("Phone", money.MustParse("500.00", "USD"))
+	radio, _ := entity.NewProduct("Radio", money.MustParse("50.00", "USD"))
+	for _, product := range []*entity.Product{tv, phone, radio} {
+		assert.NoError(t, productDB.Create(product))
+	}
+	assert.NoError(t, productDB.SetTags(tv.ID.String(), []string{sale, fresh}))
+	assert.NoError(t, productDB.SetTags(phone.ID.String(), []string{sale}))
+
+	products, err := productDB.Find(ProductQuery{Tags: []string{sale, fresh}, TagMode: TagModeAny})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+
+	products, err = productDB.Find(ProductQuery{Tags: []string{sale, fresh}, TagMode: TagModeAll})
+	assert.NoError(t, err)
+	assert.Len(t, products, 1)
+	assert.Equal(t, tv.ID, products[0].ID)
+
+	// setting tags replaces the previous ones
+	assert.NoError(t, productDB.SetTags(tv.ID.String(), []string{fresh}))
+	tags, err := productDB.FindTags([]string{tv.ID.String(), phone.ID.String(), radio.ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{fresh}, tags[tv.ID.String()])
+	assert.Equal(t, []string{sale}, tags[phone.ID.String()])
+	assert.Empty(t, tags[radio.ID.String()])
+
+	counts, err := productDB.CountTags()
+	assert.NoError(t, err)
+	assert.Contains(t, counts, entity.TagCount{Tag: sale, Count: 1})
+	assert.Contains(t, counts, entity.TagCount{Tag: fresh, Count: 1})
+}
+
+func TestSetTagsKeepsVariants(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	productDB := NewProductDB(db)
+	variantDB := NewVariantDB(db)
+	imageDB := NewImageDB(db)
+
+	product, _ := entity.NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	assert.NoError(t, productDB.Create(product))
+	variant, _ := entity.NewVariant(product, "TAGGED-"+entityPkg.NewID().String()[:8], nil, nil, 1)
+	assert.NoError(t, variantDB.Create(variant))
+	image, _ := entity.NewProductImage(product.ID, "image/png", "image/png", 100, 10, 10)
+	assert.NoError(t, imageDB.Create(image))
+
+	assert.NoError(t, productDB.SetTags(product.ID.String(), []string{"summer"}))
+
+	variants, err := variantDB.FindByProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, variants, 1)
+	images, err := imageDB.FindByProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, images, 1)
+}
+
+func TestStreamProducts(t *testing.T) {
+	// queries made while the cursor is open run on another connection, which
+	// only sees the same in-memory database with a shared cache
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.ProductTag{}))
+	productDB := NewProductDB(db)
+
+	tag := "stream-" + entityPkg.NewID().String()[:8]
+	var created []entityPkg.ID
+	for i := 0; i < 5; i++ {
+		product, _ := entity.NewProduct(fmt.Sprintf("Streamed %d", i), money.MustParse("10.00", "USD"))
+		product.CreatedAt = product.CreatedAt.Add(time.Duration(i) * time.Second)
+		assert.NoError(t, productDB.Create(product))
+		assert.NoError(t, productDB.SetTags(product.ID.String(), []string{tag}))
+		created = append(created, product.ID)
+	}
+
+	var batches []int
+	var streamed []entityPkg.ID
+	err = productDB.Stream(ProductQuery{Tags: []string{tag}}, 2, func(products []entity.Product) error {
+		batches = append(batches, len(products))
+		for _, product := range products {
+			tags, err := productDB.FindTags([]string{product.ID.String()})
+			assert.NoError(t, err)
+			assert.Equal(t, []string{tag}, tags[product.ID.String()])
+			streamed = append(streamed, product.ID)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 1}, batches)
+	assert.Equal(t, created, streamed)
+
+	stop := errors.New("stop")
+	err = productDB.Stream(ProductQuery{Tags: []string{tag}}, 2, func(products []entity.Product) error {
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+}
@@ -0,0 +1,231 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMalformedNDJSON   = errors.New("malformed ndjson")
+	ErrUnsupportedImport = errors.New("imports are text/csv or application/x-ndjson")
+	ErrImportTooLarge    = errors.New("the import file is too large")
+)
+
+// DefaultImportBatchSize is how many products an import inserts per
+// transaction.
+const DefaultImportBatchSize = 500
+
+// ProductRow is a product read from an import file. Err is set when the
+// row could not be parsed, which does not keep the next rows from being
+// read.
+type ProductRow struct {
+	Line  int
+	Name  string
+	Price money.Money
+	Err   error
+}
+
+// ProductRows reads the rows of an import one at a time, returning io.EOF
+// after the last one or the error that kept the file from being read.
+type ProductRows interface {
+	Next() (*ProductRow, error)
+}
+
+// NewProductRows reads the rows of a file of the given media type, either
+// text/csv or application/x-ndjson.
+func NewProductRows(mediaType string, r io.Reader) (ProductRows, error) {
+	switch mediaType {
+	case "text/csv":
+		return NewCSVProductRows(r)
+	case "application/x-ndjson", "application/ndjson":
+		return NewNDJSONProductRows(r), nil
+	}
+	return nil, ErrUnsupportedImport
+}
+
+type csvProductRows struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+// NewCSVProductRows reads products from a CSV with the header
+// name,price,currency, where price is a decimal amount such as 19.90.
+func NewCSVProductRows(r io.Reader) (ProductRows, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrMalformedCSV, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"name", "price", "currency"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing the %s column", ErrMalformedCSV, name)
+		}
+	}
+	return &csvProductRows{reader: reader, columns: columns}, nil
+}
+
+func (rows *csvProductRows) Next() (*ProductRow, error) {
+	record, err := rows.reader.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if !errors.As(err, &parseErr) {
+			return nil, err
+		}
+		return &ProductRow{
+			Line: parseErr.StartLine,
+			Err:  fmt.Errorf("%w: %v", ErrMalformedCSV, parseErr.Err),
+		}, nil
+	}
+
+	line, _ := rows.reader.FieldPos(0)
+	row := &ProductRow{Line: line, Name: record[rows.columns["name"]]}
+	row.Price, err = money.Parse(record[rows.columns["price"]], record[rows.columns["currency"]])
+	if err != nil {
+		row.Err = fmt.Errorf("%w: price: %v", ErrMalformedCSV, err)
+	}
+	return row, nil
+}
+
+type ndjsonProductRows struct {
+	scanner *bufio.Scanner
+	line    int
+	failed  bool
+}
+
+// NewNDJSONProductRows reads products from newline delimited JSON with one
+// object per line, shaped like the body of POST /products. Blank lines are
+// skipped.
+func NewNDJSONProductRows(r io.Reader) ProductRows {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	return &ndjsonProductRows{scanner: scanner}
+}
+
+func (rows *ndjsonProductRows) Next() (*ProductRow, error) {
+	for rows.scanner.Scan() {
+		rows.line++
+		data := bytes.TrimSpace(rows.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := &ProductRow{Line: rows.line}
+		var input struct {
+			Name  string      `json:"name"`
+			Price money.Money `json:"price"`
+		}
+		err := json.Unmarshal(data, &input)
+		if err != nil {
+			row.Err = fmt.Errorf("%w: %v", ErrMalformedNDJSON, err)
+			return row, nil
+		}
+		row.Name, row.Price = input.Name, input.Price
+		return row, nil
+	}
+	if err := rows.scanner.Err(); err != nil && !rows.failed {
+		// a line too long to read ends the file, but is reported as a row
+		rows.failed = true
+		if errors.Is(err, bufio.ErrTooLong) {
+			return &ProductRow{Line: rows.line + 1, Err: fmt.Errorf("%w: %v", ErrMalformedNDJSON, err)}, nil
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ImportResult is the outcome of one row of an import: the product it
+// created, or would create on a dry run, or the error that rejected it.
+type ImportResult struct {
+	Line    int
+	Product *entity.Product
+	Err     error
+}
+
+// Import validates each row with entity.NewProduct and inserts the valid
+// ones in transactions of batchSize products, calling report once per row
+// in file order. A batch that fails to insert reports the error on each of
+// its products and the import goes on with the next one. On a dry run
+// nothing is written. The import stops when the file can no longer be read
+// or report fails, returning the error once the rows of the batches already
+// saved are reported.
+func (pdb *ProductDB) Import(rows ProductRows, dryRun bool, batchSize int, report func(ImportResult) error) error {
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	var pending []ImportResult
+	var products []entity.Product
+	flush := func() error {
+		if len(products) > 0 && !dryRun {
+			err := pdb.CreateBatch(products)
+			if err != nil {
+				for i := range pending {
+					if pending[i].Product != nil {
+						pending[i].Product, pending[i].Err = nil, err
+					}
+				}
+			}
+		}
+		var err error
+		for _, result := range pending {
+			if err == nil {
+				err = report(result)
+			}
+		}
+		pending, products = pending[:0], products[:0]
+		return err
+	}
+
+	for {
+		row, err := rows.Next()
+		if err != nil {
+			flushErr := flush()
+			if err == io.EOF {
+				return flushErr
+			}
+			return err
+		}
+
+		result := ImportResult{Line: row.Line, Err: row.Err}
+		if result.Err == nil {
+			result.Product, result.Err = entity.NewProduct(strings.TrimSpace(row.Name), row.Price)
+		}
+		pending = append(pending, result)
+		if result.Product != nil {
+			products = append(products, *result.Product)
+		}
+		if len(products) == batchSize {
+			err = flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// CreateBatch inserts the products in a single transaction.
+func (pdb *ProductDB) CreateBatch(products []entity.Product) error {
+	return pdb.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(products, 100).Error
+	})
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func importProducts(t *testing.T, pdb *ProductDB, rows ProductRows, dryRun bool) []ImportResult {
+	var results []ImportResult
+	err := pdb.Import(rows, dryRun, 2, func(result ImportResult) error {
+		results = append(results, result)
+		return nil
+	})
+	assert.NoError(t, err)
+	return results
+}
+
+func TestImportProductsCSV(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	pdb := NewProductDB(db)
+
+	rows, err := NewCSVProductRows(strings.NewReader(
+		"Name,Price,Currency\n" +
+			"ImportCSV A,19.90,usd\n" +
+			",5.00,USD\n" +
+			"ImportCSV B,abc,USD\n" +
+			"\"ImportCSV\nC\",1.00,USD\n" +
+			"ImportCSV D,2.00\n" +
+			"ImportCSV E,3.00,USD\n"))
+	assert.NoError(t, err)
+
+	results := importProducts(t, pdb, rows, false)
+	assert.Len(t, results, 6)
+	assert.Equal(t, []int{2, 3, 4, 5, 7, 8}, []int{results[0].Line, results[1].Line, results[2].Line, results[3].Line, results[4].Line, results[5].Line})
+	assert.Equal(t, money.MustParse("19.90", "USD"), results[0].Product.Price)
+	assert.ErrorIs(t, results[1].Err, entity.ErrNameIsRequired)
+	assert.ErrorIs(t, results[2].Err, ErrMalformedCSV)
+	assert.NoError(t, results[3].Err)
+	assert.ErrorIs(t, results[4].Err, ErrMalformedCSV)
+	assert.NoError(t, results[5].Err)
+
+	for _, i := range []int{0, 3, 5} {
+		found, err := pdb.FindByID(results[i].Product.ID.String())
+		assert.NoError(t, err)
+		assert.Equal(t, results[i].Product.Name, found.Name)
+	}
+}
+
+func TestImportProductsCSVRequiresTheColumns(t *testing.T) {
+	_, err := NewCSVProductRows(strings.NewReader("name,amount\nA,1.00\n"))
+	assert.ErrorIs(t, err, ErrMalformedCSV)
+
+	_, err = NewProductRows("application/json", strings.NewReader("[]"))
+	assert.ErrorIs(t, err, ErrUnsupportedImport)
+}
+
+func TestImportProductsNDJSONDryRun(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	pdb := NewProductDB(db)
+
+	rows := NewNDJSONProductRows(strings.NewReader(
+		`{"name": "ImportNDJSON A", "price": {"amount": "10.00", "currency": "EUR"}}` + "\n" +
+			"\n" +
+			`{"name": "ImportNDJSON B", "price": {"amount": "-1.00", "currency": "EUR"}}` + "\n" +
+			`{"name": "ImportNDJSON C"` + "\n"))
+
+	results := importProducts(t, pdb, rows, true)
+	assert.Len(t, results, 3)
+	assert.Equal(t, 1, results[0].Line)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, 3, results[1].Line)
+	assert.ErrorIs(t, results[1].Err, entity.ErrInvalidPrice)
+	assert.Equal(t, 4, results[2].Line)
+	assert.ErrorIs(t, results[2].Err, ErrMalformedNDJSON)
+
+	var count int64
+	db.Model(&entity.Product{}).Where("name LIKE ?", "ImportNDJSON%").Count(&count)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+// ReviewDB keeps the product reviews. Every change recomputes the rating of
+// the product in the same transaction, so it always matches its published
+// reviews.
+type ReviewDB struct {
+	DB *gorm.DB
+}
+
+func NewReviewDB(db *gorm.DB) *ReviewDB {
+	return &ReviewDB{DB: db}
+}
+
+// Create saves the review, failing with ErrReviewAlreadyExists when the
+// user already reviewed the product.
+func (rdb *ReviewDB) Create(review *entity.Review) error {
+	return rdb.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := NewProductDB(tx).FindByID(review.ProductID.String())
+		if err != nil {
+			return err
+		}
+
+		var existing entity.Review
+		err = tx.First(&existing, "product_id = ? AND user_id = ?", review.ProductID, review.UserID).Error
+		if err == nil {
+			return entity.ErrReviewAlreadyExists
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Create(review).Error
+		if err != nil {
+			return err
+		}
+		return refreshRating(tx, review.ProductID.String())
+	})
+}
+
+// FindByID finds a review of the product.
+func (rdb *ReviewDB) FindByID(productID, id string) (*entity.Review, error) {
+	var review entity.Review
+	err := rdb.DB.First(&review, "id = ? AND product_id = ?", id, productID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// FindByProduct lists the reviews of the product, newest first. Hidden
+// reviews are only listed with includeHidden.
+func (rdb *ReviewDB) FindByProduct(productID string, includeHidden bool, page, limit int) ([]entity.Review, error) {
+	var reviews []entity.Review
+	db := rdb.DB.Where("product_id = ?", productID).Order("created_at desc")
+	if !includeHidden {
+		db = db.Where("status = ?", entity.ReviewPublished)
+	}
+	if page != 0 && limit != 0 {
+		db = db.Limit(limit).Offset((page - 1) * limit)
+	}
+	err := db.Find(&reviews).Error
+	return reviews, err
+}
+
+// Update saves an edited or moderated review.
+func (rdb *ReviewDB) Update(review *entity.Review) error {
+	return rdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Save(review).Error
+		if err != nil {
+			return err
+		}
+		return refreshRating(tx, review.ProductID.String())
+	})
+}
+
+func (rdb *ReviewDB) Delete(review *entity.Review) error {
+	return rdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(review).Error
+		if err != nil {
+			return err
+		}
+		return refreshRating(tx, review.ProductID.String())
+	})
+}
+
+// refreshRating recomputes the rating average and count of the product from
+// its published reviews.
+func refreshRating(tx *gorm.DB, productID string) error {
+	var rating struct {
+		Average float64
+		Count   int64
+	}
+	err := tx.Model(&entity.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, entity.ReviewPublished).
+		Scan(&rating).Error
+	if err != nil {
+		return err
+	}
+
+	return tx.Model(&entity.Product{}).
+		Where("id = ?", productID).
+		Updates(map[string]interface{}{"rating_average": rating.Average, "rating_count": rating.Count}).Error
+}
@@ -0,0 +1,93 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewsMaintainProductRating(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	productDB := NewProductDB(db)
+	reviewDB := NewReviewDB(db)
+
+	product, _ := entity.NewProduct("Mug", money.MustParse("8.00", "USD"))
+	assert.NoError(t, productDB.Create(product))
+
+	five, _ := entity.NewReview(product.ID, "user-1", 5, "Great")
+	assert.NoError(t, reviewDB.Create(five))
+	two, _ := entity.NewReview(product.ID, "user-2", 2, "Chipped")
+	assert.NoError(t, reviewDB.Create(two))
+
+	again, _ := entity.NewReview(product.ID, "user-1", 4, "Still great")
+	assert.ErrorIs(t, reviewDB.Create(again), entity.ErrReviewAlreadyExists)
+
+	found, _ := productDB.FindByID(product.ID.String())
+	assert.Equal(t, 3.5, found.RatingAverage)
+	assert.Equal(t, int64(2), found.RatingCount)
+
+	// hidden reviews leave the rating
+	two.Status = entity.ReviewHidden
+	assert.NoError(t, reviewDB.Update(two))
+	found, _ = productDB.FindByID(product.ID.String())
+	assert.Equal(t, 5.0, found.RatingAverage)
+	assert.Equal(t, int64(1), found.RatingCount)
+
+	reviews, err := reviewDB.FindByProduct(product.ID.String(), false, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, reviews, 1)
+	reviews, err = reviewDB.FindByProduct(product.ID.String(), true, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, reviews, 2)
+
+	assert.NoError(t, reviewDB.Delete(five))
+	found, _ = productDB.FindByID(product.ID.String())
+	assert.Equal(t, 0.0, found.RatingAverage)
+	assert.Equal(t, int64(0), found.RatingCount)
+
+	// updating the product keeps its rating
+	assert.NoError(t, reviewDB.Update(&entity.Review{ID: two.ID, ProductID: product.ID, UserID: "user-2", Rating: 2, Status: entity.ReviewPublished}))
+	product.RatingAverage = 0
+	assert.NoError(t, productDB.Update(product, "tester"))
+	found, _ = productDB.FindByID(product.ID.String())
+	assert.Equal(t, 2.0, found.RatingAverage)
+}
+
+func TestFindProductsByRating(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	productDB := NewProductDB(db)
+	reviewDB := NewReviewDB(db)
+
+	// the tag isolates this run since the test database is shared
+	tag := "rated-" + entityPkg.NewID().String()[:8]
+	ratings := map[string]int{"Good": 4, "Best": 5, "Poor": 1}
+	for name, rating := range ratings {
+		product, _ := entity.NewProduct(name, money.MustParse("1.00", "USD"))
+		assert.NoError(t, productDB.Create(product))
+		assert.NoError(t, productDB.SetTags(product.ID.String(), []string{tag}))
+		review, _ := entity.NewReview(product.ID, "user-1", rating, "")
+		assert.NoError(t, reviewDB.Create(review))
+	}
+	unrated, _ := entity.NewProduct("Unrated", money.MustParse("1.00", "USD"))
+	assert.NoError(t, productDB.Create(unrated))
+	assert.NoError(t, productDB.SetTags(unrated.ID.String(), []string{tag}))
+
+	products, err := productDB.Find(ProductQuery{Tags: []string{tag}, Sort: "-rating"})
+	assert.NoError(t, err)
+	assert.Len(t, products, 4)
+	assert.Equal(t, "Best", products[0].Name)
+	assert.Equal(t, "Good", products[1].Name)
+	assert.Equal(t, "Poor", products[2].Name)
+	assert.Equal(t, "Unrated", products[3].Name)
+
+	products, err = productDB.Find(ProductQuery{Tags: []string{tag}, Sort: "rating", MinRating: 4})
+	assert.NoError(t, err)
+	assert.Len(t, products, 2)
+	assert.Equal(t, "Good", products[0].Name)
+	assert.Equal(t, "Best", products[1].Name)
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+type ScheduledPriceDB struct {
+	DB *gorm.DB
+}
+
+func NewScheduledPriceDB(db *gorm.DB) *ScheduledPriceDB {
+	return &ScheduledPriceDB{DB: db}
+}
+
+func (sdb *ScheduledPriceDB) Create(scheduledPrice *entity.ScheduledPrice) error {
+	return sdb.DB.Create(scheduledPrice).Error
+}
+
+func (sdb *ScheduledPriceDB) FindByID(id string) (*entity.ScheduledPrice, error) {
+	var scheduledPrice entity.ScheduledPrice
+	err := sdb.DB.First(&scheduledPrice, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scheduledPrice, nil
+}
+
+func (sdb *ScheduledPriceDB) FindByProduct(productID string) ([]entity.ScheduledPrice, error) {
+	var scheduledPrices []entity.ScheduledPrice
+	err := sdb.DB.Where("product_id = ?", productID).Order("starts_at").Find(&scheduledPrices).Error
+	return scheduledPrices, err
+}
+
+func (sdb *ScheduledPriceDB) Update(scheduledPrice *entity.ScheduledPrice) error {
+	return sdb.DB.Save(scheduledPrice).Error
+}
+
+// FindActivePromotions returns, for each of the given products, the latest
+// started promotion whose window includes at. It goes by the dates rather
+// than the status so the result does not depend on when the scheduler last
+// ran.
+func (sdb *ScheduledPriceDB) FindActivePromotions(productIDs []string, at time.Time) (map[string]entity.ScheduledPrice, error) {
+	var promotions []entity.ScheduledPrice
+	err := sdb.DB.
+		Where("product_id IN ? AND status IN ?", productIDs, []entity.ScheduledPriceStatus{entity.ScheduledPriceScheduled, entity.ScheduledPriceActive}).
+		Where("ends_at IS NOT NULL AND starts_at <= ? AND ends_at > ?", at.UTC(), at.UTC()).
+		Order("starts_at").
+		Find(&promotions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	active := map[string]entity.ScheduledPrice{}
+	for _, promotion := range promotions {
+		active[promotion.ProductID.String()] = promotion
+	}
+	return active, nil
+}
+
+// FindDue returns the scheduled prices that should have started at the
+// given time.
+func (sdb *ScheduledPriceDB) FindDue(at time.Time) ([]entity.ScheduledPrice, error) {
+	var scheduledPrices []entity.ScheduledPrice
+	err := sdb.DB.
+		Where("status = ? AND starts_at <= ?", entity.ScheduledPriceScheduled, at.UTC()).
+		Order("starts_at").
+		Find(&scheduledPrices).Error
+	return scheduledPrices, err
+}
+
+// FindExpired returns the running promotions whose window is over at the
+// given time.
+func (sdb *ScheduledPriceDB) FindExpired(at time.Time) ([]entity.ScheduledPrice, error) {
+	var scheduledPrices []entity.ScheduledPrice
+	err := sdb.DB.
+		Where("status = ? AND ends_at <= ?", entity.ScheduledPriceActive, at.UTC()).
+		Find(&scheduledPrices).Error
+	return scheduledPrices, err
+}
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockDB keeps the stock ledger and the stock levels derived from it. Every
+// change goes through a single conditional UPDATE of the level, so
+// concurrent requests can never take the available stock below zero.
+type StockDB struct {
+	DB    *gorm.DB
+	Clock clock.Clock
+}
+
+func NewStockDB(db *gorm.DB) *StockDB {
+	return &StockDB{DB: db, Clock: clock.Real{}}
+}
+
+// on returns a StockDB working inside tx.
+func (sdb *StockDB) on(tx *gorm.DB) *StockDB {
+	return &StockDB{DB: tx, Clock: sdb.Clock}
+}
+
+// FindLevel returns the stock of the product, which is empty until the
+// first movement.
+func (sdb *StockDB) FindLevel(productID string) (*entity.StockLevel, error) {
+	product, err := NewProductDB(sdb.DB).FindByID(productID)
+	if err != nil {
+		return nil, err
+	}
+
+	level := entity.StockLevel{ProductID: product.ID}
+	err = sdb.DB.First(&level, "product_id = ?", product.ID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &level, nil
+}
+
+func (sdb *StockDB) FindLedger(productID string) ([]entity.StockMovement, error) {
+	var movements []entity.StockMovement
+	err := sdb.DB.Where("product_id = ?", productID).Order("created_at desc").Find(&movements).Error
+	return movements, err
+}
+
+// Adjust records a movement in the ledger and applies it to the quantity on
+// hand. A decrease fails with ErrInsufficientStock when it would leave less
+// on hand than is reserved.
+func (sdb *StockDB) Adjust(movement *entity.StockMovement) (*entity.StockLevel, error) {
+	var level *entity.StockLevel
+	err := sdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := sdb.on(tx).ensureLevel(movement.ProductID.String())
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.StockLevel{}).
+			Where("product_id = ? AND on_hand + ? >= reserved", movement.ProductID, movement.Quantity).
+			Updates(map[string]interface{}{
+				"on_hand":    gorm.Expr("on_hand + ?", movement.Quantity),
+				"updated_at": sdb.Clock.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrInsufficientStock
+		}
+
+		err = tx.Create(movement).Error
+		if err != nil {
+			return err
+		}
+
+		level, err = sdb.on(tx).FindLevel(movement.ProductID.String())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return level, nil
+}
+
+// Reserve holds stock for the reservation, failing with
+// ErrInsufficientStock when not enough is available. Expired reservations of
+// the product are released first so they never block new ones.
+func (sdb *StockDB) Reserve(reservation *entity.StockReservation) error {
+	return sdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := sdb.on(tx).ensureLevel(reservation.ProductID.String())
+		if err != nil {
+			return err
+		}
+		_, err = sdb.on(tx).releaseExpired(tx.Where("product_id = ?", reservation.ProductID))
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.StockLevel{}).
+			Where("product_id = ? AND on_hand - reserved >= ?", reservation.ProductID, reservation.Quantity).
+			Updates(map[string]interface{}{
+				"reserved":   gorm.Expr("reserved + ?", reservation.Quantity),
+				"updated_at": sdb.Clock.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrInsufficientStock
+		}
+
+		return tx.Create(reservation).Error
+	})
+}
+
+func (sdb *StockDB) FindReservation(id string) (*entity.StockReservation, error) {
+	var reservation entity.StockReservation
+	err := sdb.DB.First(&reservation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+// Release gives the stock held by a reservation back.
+func (sdb *StockDB) Release(id string) error {
+	return sdb.DB.Transaction(func(tx *gorm.DB) error {
+		reservation, err := sdb.on(tx).FindReservation(id)
+		if err != nil {
+			return err
+		}
+		return sdb.on(tx).finish(reservation, entity.StockReservationReleased)
+	})
+}
+
+// Extend keeps a held reservation until at least until, failing with
+// ErrReservationNotHeld when it was already released or has expired.
+func (sdb *StockDB) Extend(id string, until time.Time) error {
+	now := sdb.Clock.Now().UTC()
+	result := sdb.DB.Model(&entity.StockReservation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, entity.StockReservationHeld, now).
+		Where("expires_at < ?", until.UTC()).
+		Update("expires_at", until.UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+
+	// either it already lasts long enough or it is no longer held
+	var held int64
+	err := sdb.DB.Model(&entity.StockReservation{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, entity.StockReservationHeld, now).
+		Count(&held).Error
+	if err != nil {
+		return err
+	}
+	if held == 0 {
+		return entity.ErrReservationNotHeld
+	}
+	return nil
+}
+
+// Commit turns a held reservation into a sale, taking its quantity out of
+// the stock on hand.
+func (sdb *StockDB) Commit(id, createdBy string) error {
+	return sdb.DB.Transaction(func(tx *gorm.DB) error {
+		reservation, err := sdb.on(tx).FindReservation(id)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&entity.StockReservation{}).
+			Where("id = ? AND status = ? AND expires_at > ?", reservation.ID, entity.StockReservationHeld, sdb.Clock.Now().UTC()).
+			Update("status", entity.StockReservationCommitted)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return entity.ErrReservationNotHeld
+		}
+
+		err = tx.Model(&entity.StockLevel{}).
+			Where("product_id = ?", reservation.ProductID).
+			Updates(map[string]interface{}{
+				"on_hand":    gorm.Expr("on_hand - ?", reservation.Quantity),
+				"reserved":   gorm.Expr("reserved - ?", reservation.Quantity),
+				"updated_at": sdb.Clock.Now(),
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(entity.NewStockSale(reservation, createdBy)).Error
+	})
+}
+
+// ReleaseExpired releases every held reservation past its expiry and returns
+// how many there were.
+func (sdb *StockDB) ReleaseExpired() (int, error) {
+	var released int
+	err := sdb.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		released, err = sdb.on(tx).releaseExpired(tx)
+		return err
+	})
+	return released, err
+}
+
+// Reconcile rebuilds the stock level of a product from the ledger and the
+// held reservations.
+func (sdb *StockDB) Reconcile(productID string) (*entity.StockLevel, error) {
+	var level *entity.StockLevel
+	err := sdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := sdb.on(tx).ensureLevel(productID)
+		if err != nil {
+			return err
+		}
+
+		var onHand, reserved int64
+		err = tx.Model(&entity.StockMovement{}).
+			Where("product_id = ?", productID).
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&onHand).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&entity.StockReservation{}).
+			Where("product_id = ? AND status = ?", productID, entity.StockReservationHeld).
+			Select("COALESCE(SUM(quantity), 0)").
+			Scan(&reserved).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&entity.StockLevel{}).
+			Where("product_id = ?", productID).
+			Updates(map[string]interface{}{"on_hand": onHand, "reserved": reserved, "updated_at": sdb.Clock.Now()}).Error
+		if err != nil {
+			return err
+		}
+
+		level, err = sdb.on(tx).FindLevel(productID)
+		return err
+	})
+	return level, err
+}
+
+// ensureLevel creates the empty stock level of an existing product.
+func (sdb *StockDB) ensureLevel(productID string) error {
+	product, err := NewProductDB(sdb.DB).FindByID(productID)
+	if err != nil {
+		return err
+	}
+	level := entity.StockLevel{ProductID: product.ID, UpdatedAt: sdb.Clock.Now()}
+	return sdb.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&level).Error
+}
+
+// releaseExpired expires the held reservations matched by scope whose expiry
+// has passed.
+func (sdb *StockDB) releaseExpired(scope *gorm.DB) (int, error) {
+	var expired []entity.StockReservation
+	err := scope.
+		Where("status = ? AND expires_at <= ?", entity.StockReservationHeld, sdb.Clock.Now().UTC()).
+		Find(&expired).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range expired {
+		err = sdb.finish(&expired[i], entity.StockReservationExpired)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(expired), nil
+}
+
+// finish moves a held reservation to status and gives its stock back. It
+// must run inside a transaction.
+func (sdb *StockDB) finish(reservation *entity.StockReservation, status entity.StockReservationStatus) error {
+	result := sdb.DB.Model(&entity.StockReservation{}).
+		Where("id = ? AND status = ?", reservation.ID, entity.StockReservationHeld).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return entity.ErrReservationNotHeld
+	}
+	reservation.Status = status
+
+	return sdb.DB.Model(&entity.StockLevel{}).
+		Where("product_id = ?", reservation.ProductID).
+		Updates(map[string]interface{}{
+			"reserved":   gorm.Expr("reserved - ?", reservation.Quantity),
+			"updated_at": sdb.Clock.Now(),
+		}).Error
+}
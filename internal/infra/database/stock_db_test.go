@@ -0,0 +1,150 @@
+package database
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func createStockDB(t *testing.T) (*StockDB, *entity.Product) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.StockLevel{}, &entity.StockMovement{}, &entity.StockReservation{}, &entity.Order{}, &entity.OrderItem{}, &entity.Payment{}, &entity.PaymentEvent{}))
+
+	product, err := entity.NewProduct("Stocked product", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, NewProductDB(db).Create(product))
+
+	return NewStockDB(db), product
+}
+
+func adjustStock(t *testing.T, stockDB *StockDB, product *entity.Product, quantity int64, reason entity.StockReason) (*entity.StockLevel, error) {
+	movement, err := entity.NewStockAdjustment(product.ID, quantity, reason, "", "tester")
+	assert.NoError(t, err)
+	return stockDB.Adjust(movement)
+}
+
+func TestAdjustStock(t *testing.T) {
+	stockDB, product := createStockDB(t)
+
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), level.OnHand)
+
+	level, err = adjustStock(t, stockDB, product, 10, entity.StockRestock)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10), level.OnHand)
+
+	level, err = adjustStock(t, stockDB, product, -3, entity.StockDamaged)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), level.OnHand)
+
+	_, err = adjustStock(t, stockDB, product, -8, entity.StockLost)
+	assert.ErrorIs(t, err, entity.ErrInsufficientStock)
+
+	ledger, err := stockDB.FindLedger(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, ledger, 2)
+}
+
+func TestReserveAndCommitStock(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	_, err := adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	reservation, err := entity.NewStockReservation(product.ID, 3, time.Now().Add(time.Hour), "order-1")
+	assert.NoError(t, err)
+	assert.NoError(t, stockDB.Reserve(reservation))
+
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), level.Available())
+
+	// reserved stock cannot be adjusted away nor reserved twice
+	_, err = adjustStock(t, stockDB, product, -3, entity.StockLost)
+	assert.ErrorIs(t, err, entity.ErrInsufficientStock)
+	other, _ := entity.NewStockReservation(product.ID, 3, time.Now().Add(time.Hour), "order-2")
+	assert.ErrorIs(t, stockDB.Reserve(other), entity.ErrInsufficientStock)
+
+	assert.NoError(t, stockDB.Commit(reservation.ID.String(), "checkout"))
+	assert.ErrorIs(t, stockDB.Commit(reservation.ID.String(), "checkout"), entity.ErrReservationNotHeld)
+	assert.ErrorIs(t, stockDB.Release(reservation.ID.String()), entity.ErrReservationNotHeld)
+
+	level, err = stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), level.OnHand)
+	assert.Equal(t, int64(0), level.Reserved)
+
+	// the ledger agrees with the stored level
+	reconciled, err := stockDB.Reconcile(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, level.OnHand, reconciled.OnHand)
+	assert.Equal(t, level.Reserved, reconciled.Reserved)
+}
+
+func TestExpiredReservationsReleaseStock(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	fake := clock.NewFake(time.Now())
+	stockDB.Clock = fake
+	_, err := adjustStock(t, stockDB, product, 2, entity.StockRestock)
+	assert.NoError(t, err)
+
+	reservation, _ := entity.NewStockReservation(product.ID, 2, fake.Now().Add(15*time.Minute), "order-1")
+	assert.NoError(t, stockDB.Reserve(reservation))
+
+	fake.Advance(15 * time.Minute)
+	assert.ErrorIs(t, stockDB.Commit(reservation.ID.String(), "checkout"), entity.ErrReservationNotHeld)
+
+	// a new reservation releases the expired one on its way
+	other, _ := entity.NewStockReservation(product.ID, 2, fake.Now().Add(15*time.Minute), "order-2")
+	assert.NoError(t, stockDB.Reserve(other))
+	reservation, err = stockDB.FindReservation(reservation.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.StockReservationExpired, reservation.Status)
+
+	fake.Advance(time.Hour)
+	released, err := stockDB.ReleaseExpired()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, released, 1)
+
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), level.Available())
+}
+
+func TestConcurrentReservationsNeverOversell(t *testing.T) {
+	stockDB, product := createStockDB(t)
+	sqlDB, err := stockDB.DB.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+
+	_, err = adjustStock(t, stockDB, product, 5, entity.StockRestock)
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, _ := entity.NewStockReservation(product.ID, 1, time.Now().Add(time.Hour), "")
+			if stockDB.Reserve(reservation) == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, succeeded)
+	level, err := stockDB.FindLevel(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), level.Available())
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+// TaxDB keeps the tax regions along with their rates.
+type TaxDB struct {
+	DB *gorm.DB
+}
+
+func NewTaxDB(db *gorm.DB) *TaxDB {
+	return &TaxDB{DB: db}
+}
+
+// Save creates the region or replaces it along with all of its rates.
+func (tdb *TaxDB) Save(region *entity.TaxRegion) error {
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := NewTaxDB(tx).FindByCode(region.Code)
+		if err == nil {
+			region.CreatedAt = current.CreatedAt
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		err = tx.Where("region_code = ?", region.Code).Delete(&entity.TaxRate{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Save(region).Error
+	})
+}
+
+func (tdb *TaxDB) FindByCode(code string) (*entity.TaxRegion, error) {
+	var region entity.TaxRegion
+	err := tdb.DB.Preload("Rates").First(&region, "code = ?", entity.NormalizeTaxRegion(code)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &region, nil
+}
+
+// FindRegion finds the region like FindByCode, failing with
+// ErrTaxRegionNotFound when there are no rules for it.
+func (tdb *TaxDB) FindRegion(code string) (*entity.TaxRegion, error) {
+	region, err := tdb.FindByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, entity.ErrTaxRegionNotFound
+	}
+	return region, err
+}
+
+func (tdb *TaxDB) FindRegions() ([]entity.TaxRegion, error) {
+	var regions []entity.TaxRegion
+	err := tdb.DB.Preload("Rates").Order("code").Find(&regions).Error
+	return regions, err
+}
+
+func (tdb *TaxDB) Delete(code string) error {
+	region, err := tdb.FindByCode(code)
+	if err != nil {
+		return err
+	}
+	return tdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("region_code = ?", region.Code).Delete(&entity.TaxRate{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(region).Error
+	})
+}
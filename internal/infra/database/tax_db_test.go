@@ -0,0 +1,65 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveTaxRegionReplacesRates(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	taxDB := NewTaxDB(db)
+
+	books := entityPkg.NewID()
+	region, _ := entity.NewTaxRegion("BR-SP", "São Paulo", true, entity.TaxRoundOrder)
+	assert.NoError(t, region.AddRate(nil, "18"))
+	assert.NoError(t, region.AddRate(&books, "0"))
+	assert.NoError(t, taxDB.Save(region))
+
+	found, err := taxDB.FindRegion("br-sp")
+	assert.NoError(t, err)
+	assert.True(t, found.PricesIncludeTax)
+	assert.Len(t, found.Rates, 2)
+	assert.Equal(t, "0", found.RateFor([]entityPkg.ID{books}))
+
+	replaced, _ := entity.NewTaxRegion("BR-SP", "São Paulo", true, entity.TaxRoundLine)
+	assert.NoError(t, replaced.AddRate(nil, "17"))
+	assert.NoError(t, taxDB.Save(replaced))
+	found, err = taxDB.FindRegion("BR-SP")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TaxRoundLine, found.Rounding)
+	assert.Len(t, found.Rates, 1)
+	assert.Equal(t, "17", found.RateFor([]entityPkg.ID{books}))
+
+	assert.NoError(t, taxDB.Delete("BR-SP"))
+	_, err = taxDB.FindRegion("BR-SP")
+	assert.ErrorIs(t, err, entity.ErrTaxRegionNotFound)
+}
+
+func TestFindCategoryLineage(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	categoryDB := NewCategoryDB(db)
+
+	media, _ := entity.NewCategory("Media", nil)
+	assert.NoError(t, categoryDB.Create(media))
+	books, _ := entity.NewCategory("Books", &media.ID)
+	assert.NoError(t, categoryDB.Create(books))
+	gifts, _ := entity.NewCategory("Gifts", nil)
+	assert.NoError(t, categoryDB.Create(gifts))
+
+	product, _ := entity.NewProduct("Novel", money.MustParse("30.00", "USD"))
+	assert.NoError(t, NewProductDB(db).Create(product))
+	assert.NoError(t, categoryDB.AssignProducts(books.ID.String(), []string{product.ID.String()}))
+	assert.NoError(t, categoryDB.AssignProducts(gifts.ID.String(), []string{product.ID.String()}))
+
+	lineage, err := categoryDB.FindLineage(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, lineage, 3)
+	assert.ElementsMatch(t, []entityPkg.ID{books.ID, gifts.ID}, lineage[:2])
+	assert.Equal(t, media.ID, lineage[2])
+}
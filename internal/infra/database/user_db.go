@@ -1,6 +1,8 @@
 package database
 
 import (
+	"errors"
+
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
 	"gorm.io/gorm"
 )
@@ -13,14 +15,55 @@ func NewUserDB(db *gorm.DB) *UserDB {
 	return &UserDB{DB: db}
 }
 
+// Create stores the user, failing with ErrEmailAlreadyExists when another
+// user has its email. The unique index still guards against concurrent
+// inserts.
 func (udb *UserDB) Create(user *entity.User) error {
-	return udb.DB.Create(user).Error
+	_, err := udb.FindByEmail(user.Email)
+	if err == nil {
+		return entity.ErrEmailAlreadyExists
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	err = udb.DB.Create(user).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return entity.ErrEmailAlreadyExists
+	}
+	return err
+}
+
+// SetRole gives the user with the email the role, or takes it away when
+// role is empty.
+func (udb *UserDB) SetRole(email, role string) error {
+	if role != "" && role != entity.RoleAdmin {
+		return entity.ErrInvalidRole
+	}
+	result := udb.DB.Model(&entity.User{}).Where("email = ?", entity.NormalizeEmail(email)).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (udb *UserDB) FindByID(id string) (*entity.User, error) {
+	var user entity.User
+
+	err := udb.DB.First(&user, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 func (udb *UserDB) FindByEmail(email string) (*entity.User, error) {
 	var user entity.User
 
-	err := udb.DB.Where("email = ?", email).First(&user).Error
+	err := udb.DB.Where("email = ?", entity.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
@@ -10,7 +11,7 @@ import (
 )
 
 func TestCreateUser(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory::"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
 	if err != nil {
 		t.Error(err)
 	}
@@ -37,7 +38,7 @@ func TestCreateUser(t *testing.T) {
 }
 
 func TestUserFindByEmail(t *testing.T) {
-	db, err := gorm.Open(sqlite.Open("file::memory::"), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
 	if err != nil {
 		t.Error(err)
 	}
@@ -60,3 +61,46 @@ func TestUserFindByEmail(t *testing.T) {
 	assert.NotNil(t, userFound.Password)
 	assert.NotEmpty(t, userFound.Password)
 }
+
+func TestCreateUserWithTakenEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.User{}))
+	userDB := NewUserDB(db)
+
+	admin, _ := entity.NewUser("Mr. Pipo", "chandelier.pipo@gmail.com", "pipolino")
+	assert.NoError(t, userDB.Create(admin))
+
+	// a second account under the same address, whatever its case
+	impostor, _ := entity.NewUser("Impostor", " Chandelier.Pipo@Gmail.com", "impostor")
+	assert.ErrorIs(t, userDB.Create(impostor), entity.ErrEmailAlreadyExists)
+	impostor.Email = admin.Email
+	assert.ErrorIs(t, db.Create(impostor).Error, gorm.ErrDuplicatedKey)
+
+	found, err := userDB.FindByEmail("CHANDELIER.PIPO@gmail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, admin.ID, found.ID)
+}
+
+func TestSetUserRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.User{}))
+	userDB := NewUserDB(db)
+
+	user, _ := entity.NewUser("Mr. Pipo", "chandelier.pipo@gmail.com", "pipolino")
+	assert.NoError(t, userDB.Create(user))
+	assert.Empty(t, user.Role)
+
+	assert.NoError(t, userDB.SetRole("Chandelier.Pipo@gmail.com", entity.RoleAdmin))
+	found, err := userDB.FindByEmail(user.Email)
+	assert.NoError(t, err)
+	assert.Equal(t, entity.RoleAdmin, found.Role)
+
+	assert.NoError(t, userDB.SetRole(user.Email, ""))
+	found, _ = userDB.FindByEmail(user.Email)
+	assert.Empty(t, found.Role)
+
+	assert.ErrorIs(t, userDB.SetRole(user.Email, "owner"), entity.ErrInvalidRole)
+	assert.ErrorIs(t, userDB.SetRole("nobody@gmail.com", entity.RoleAdmin), gorm.ErrRecordNotFound)
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+)
+
+type VariantDB struct {
+	DB *gorm.DB
+}
+
+func NewVariantDB(db *gorm.DB) *VariantDB {
+	return &VariantDB{DB: db}
+}
+
+// Create stores the variant, failing with ErrSKUAlreadyExists when another
+// variant uses its SKU.
+func (vdb *VariantDB) Create(variant *entity.Variant) error {
+	return vdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := NewVariantDB(tx).checkSKU(variant)
+		if err != nil {
+			return err
+		}
+		return tx.Create(variant).Error
+	})
+}
+
+func (vdb *VariantDB) FindByID(id string) (*entity.Variant, error) {
+	var variant entity.Variant
+	err := vdb.DB.First(&variant, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (vdb *VariantDB) FindBySKU(sku string) (*entity.Variant, error) {
+	var variant entity.Variant
+	err := vdb.DB.First(&variant, "sku = ?", entity.NormalizeSKU(sku)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &variant, nil
+}
+
+func (vdb *VariantDB) FindByProduct(productID string) ([]entity.Variant, error) {
+	variants, err := vdb.FindByProducts([]string{productID})
+	return variants[productID], err
+}
+
+// FindByProducts returns the variants of each of the given products ordered
+// by SKU.
+func (vdb *VariantDB) FindByProducts(productIDs []string) (map[string][]entity.Variant, error) {
+	var variants []entity.Variant
+	err := vdb.DB.Where("product_id IN ?", productIDs).Order("sku").Find(&variants).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byProduct := map[string][]entity.Variant{}
+	for _, variant := range variants {
+		id := variant.ProductID.String()
+		byProduct[id] = append(byProduct[id], variant)
+	}
+	return byProduct, nil
+}
+
+func (vdb *VariantDB) Update(variant *entity.Variant) error {
+	return vdb.DB.Transaction(func(tx *gorm.DB) error {
+		current, err := NewVariantDB(tx).FindByID(variant.ID.String())
+		if err != nil {
+			return err
+		}
+		variant.ProductID = current.ProductID
+		variant.CreatedAt = current.CreatedAt
+
+		err = NewVariantDB(tx).checkSKU(variant)
+		if err != nil {
+			return err
+		}
+		return tx.Save(variant).Error
+	})
+}
+
+func (vdb *VariantDB) Delete(id string) error {
+	variant, err := vdb.FindByID(id)
+	if err != nil {
+		return err
+	}
+	return vdb.DB.Delete(variant).Error
+}
+
+// checkSKU reports ErrSKUAlreadyExists when another variant has the SKU. The
+// unique index still guards against concurrent inserts.
+func (vdb *VariantDB) checkSKU(variant *entity.Variant) error {
+	existing, err := vdb.FindBySKU(variant.SKU)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.ID != variant.ID {
+		return entity.ErrSKUAlreadyExists
+	}
+	return nil
+}
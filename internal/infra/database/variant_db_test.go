@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCreateVariant(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	variantDB := NewVariantDB(db)
+
+	product, _ := entity.NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	assert.NoError(t, NewProductDB(db).Create(product))
+
+	// SKUs are unique across runs since the test database is shared
+	sku := "TSHIRT-" + entityPkg.NewID().String()[:8]
+	price := money.MustParse("24.00", "USD")
+	variant, err := entity.NewVariant(product, sku, map[string]string{"size": "XL"}, &price, 3)
+	assert.NoError(t, err)
+	assert.NoError(t, variantDB.Create(variant))
+
+	plain, err := entity.NewVariant(product, sku+"-M", map[string]string{"size": "M"}, nil, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, variantDB.Create(plain))
+
+	found, err := variantDB.FindBySKU(sku)
+	assert.NoError(t, err)
+	assert.Equal(t, variant.ID, found.ID)
+	assert.Equal(t, price, *found.Price)
+	assert.Equal(t, "XL", found.Options["size"])
+
+	found, err = variantDB.FindByID(plain.ID.String())
+	assert.NoError(t, err)
+	assert.Nil(t, found.Price)
+
+	duplicate, _ := entity.NewVariant(product, sku, nil, nil, 0)
+	assert.ErrorIs(t, variantDB.Create(duplicate), entity.ErrSKUAlreadyExists)
+
+	variants, err := variantDB.FindByProduct(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, variants, 2)
+}
+
+func TestUpdateAndDeleteVariant(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	variantDB := NewVariantDB(db)
+
+	product, _ := entity.NewProduct("T-shirt", money.MustParse("20.00", "USD"))
+	assert.NoError(t, NewProductDB(db).Create(product))
+
+	sku := "TSHIRT-" + entityPkg.NewID().String()[:8]
+	first, _ := entity.NewVariant(product, sku+"-S", nil, nil, 0)
+	second, _ := entity.NewVariant(product, sku+"-M", nil, nil, 0)
+	assert.NoError(t, variantDB.Create(first))
+	assert.NoError(t, variantDB.Create(second))
+
+	second.SKU = first.SKU
+	assert.ErrorIs(t, variantDB.Update(second), entity.ErrSKUAlreadyExists)
+
+	second.SKU = sku + "-L"
+	second.Stock = 7
+	assert.NoError(t, variantDB.Update(second))
+	found, err := variantDB.FindByID(second.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), found.Stock)
+
+	// deleting the product deletes its variants
+	assert.NoError(t, NewProductDB(db).Delete(product.ID.String()))
+	_, err = variantDB.FindByID(first.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
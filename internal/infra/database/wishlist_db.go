@@ -0,0 +1,97 @@
+package database
+
+import (
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WishlistDB struct {
+	DB *gorm.DB
+}
+
+func NewWishlistDB(db *gorm.DB) *WishlistDB {
+	return &WishlistDB{DB: db}
+}
+
+func (wdb *WishlistDB) Create(wishlist *entity.Wishlist) error {
+	return wdb.DB.Create(wishlist).Error
+}
+
+func (wdb *WishlistDB) FindByUser(userID string) ([]entity.Wishlist, error) {
+	var wishlists []entity.Wishlist
+	err := wdb.DB.Preload("Items", itemOrder).Where("user_id = ?", userID).Order("created_at").Find(&wishlists).Error
+	return wishlists, err
+}
+
+// FindByID finds a wishlist only when it belongs to the user.
+func (wdb *WishlistDB) FindByID(userID, id string) (*entity.Wishlist, error) {
+	var wishlist entity.Wishlist
+	err := wdb.DB.Preload("Items", itemOrder).First(&wishlist, "id = ? AND user_id = ?", id, userID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+func (wdb *WishlistDB) FindByShareToken(token string) (*entity.Wishlist, error) {
+	var wishlist entity.Wishlist
+	err := wdb.DB.Preload("Items", itemOrder).First(&wishlist, "share_token = ?", token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &wishlist, nil
+}
+
+// FindWatching lists the wishlists holding the product, without their items.
+func (wdb *WishlistDB) FindWatching(productID string) ([]entity.Wishlist, error) {
+	var wishlists []entity.Wishlist
+	listed := wdb.DB.Model(&entity.WishlistItem{}).Select("wishlist_id").Where("product_id = ?", productID)
+	err := wdb.DB.Where("id IN (?)", listed).Find(&wishlists).Error
+	return wishlists, err
+}
+
+// Update saves the name and share token of the wishlist.
+func (wdb *WishlistDB) Update(wishlist *entity.Wishlist) error {
+	return wdb.DB.Model(wishlist).Select("name", "share_token", "updated_at").Updates(wishlist).Error
+}
+
+func (wdb *WishlistDB) Delete(wishlist *entity.Wishlist) error {
+	return wdb.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("wishlist_id = ?", wishlist.ID).Delete(&entity.WishlistItem{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(wishlist).Error
+	})
+}
+
+// AddItem adds an existing product to the wishlist; adding it twice keeps
+// the first one.
+func (wdb *WishlistDB) AddItem(wishlist *entity.Wishlist, productID string) error {
+	return wdb.DB.Transaction(func(tx *gorm.DB) error {
+		product, err := NewProductDB(tx).FindByID(productID)
+		if err != nil {
+			return err
+		}
+		item := entity.WishlistItem{WishlistID: wishlist.ID, ProductID: product.ID, AddedAt: time.Now()}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&item).Error
+	})
+}
+
+func (wdb *WishlistDB) RemoveItem(wishlist *entity.Wishlist, productID string) error {
+	result := wdb.DB.Where("wishlist_id = ? AND product_id = ?", wishlist.ID, productID).Delete(&entity.WishlistItem{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func itemOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("added_at")
+}
@@ -0,0 +1,62 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestWishlists(t *testing.T) {
+	db, err := CreateGormDBAndAutoMigrate()
+	assert.NoError(t, err)
+	wishlistDB := NewWishlistDB(db)
+	productDB := NewProductDB(db)
+	userID := entityPkg.NewID().String()
+
+	product, _ := entity.NewProduct("Lamp", money.MustParse("30.00", "USD"))
+	assert.NoError(t, productDB.Create(product))
+
+	wishlist, _ := entity.NewWishlist(userID, "Home")
+	assert.NoError(t, wishlistDB.Create(wishlist))
+	assert.NoError(t, wishlistDB.AddItem(wishlist, product.ID.String()))
+	// adding twice keeps one item
+	assert.NoError(t, wishlistDB.AddItem(wishlist, product.ID.String()))
+	assert.ErrorIs(t, wishlistDB.AddItem(wishlist, entityPkg.NewID().String()), gorm.ErrRecordNotFound)
+
+	found, err := wishlistDB.FindByID(userID, wishlist.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, found.Items, 1)
+	_, err = wishlistDB.FindByID("someone-else", wishlist.ID.String())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	assert.NoError(t, wishlist.Share())
+	assert.NoError(t, wishlistDB.Update(wishlist))
+	shared, err := wishlistDB.FindByShareToken(*wishlist.ShareToken)
+	assert.NoError(t, err)
+	assert.Equal(t, wishlist.ID, shared.ID)
+
+	token := *wishlist.ShareToken
+	wishlist.Unshare()
+	assert.NoError(t, wishlistDB.Update(wishlist))
+	_, err = wishlistDB.FindByShareToken(token)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	watching, err := wishlistDB.FindWatching(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, watching, 1)
+
+	// deleting the product takes it off the wishlists
+	assert.NoError(t, productDB.Delete(product.ID.String()))
+	found, _ = wishlistDB.FindByID(userID, wishlist.ID.String())
+	assert.Empty(t, found.Items)
+
+	assert.ErrorIs(t, wishlistDB.RemoveItem(wishlist, product.ID.String()), gorm.ErrRecordNotFound)
+	assert.NoError(t, wishlistDB.Delete(wishlist))
+	wishlists, err := wishlistDB.FindByUser(userID)
+	assert.NoError(t, err)
+	assert.Empty(t, wishlists)
+}
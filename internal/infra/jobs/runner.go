@@ -0,0 +1,177 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+// Progress records how many items a job processed so far. It fails with
+// entity.ErrJobCancelled once the job is asked to stop, and the handler is
+// expected to return that error.
+type Progress func(done int64) error
+
+// Handler runs an attempt of a job and returns the result stored with it,
+// which is encoded as JSON.
+type Handler func(ctx context.Context, job *entity.Job, progress Progress) (any, error)
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error that another attempt would not fix, such as a
+// malformed file, so that the job fails without being retried.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Runner runs the queued jobs with Workers workers, each looking for due
+// jobs every PollInterval. Failed attempts are retried after Backoff,
+// doubled for each attempt made, up to MaxBackoff.
+type Runner struct {
+	JobDB        database.JobInterface
+	Handlers     map[string]Handler
+	Workers      int
+	PollInterval time.Duration
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+	Clock        clock.Clock
+}
+
+func NewRunner(db database.JobInterface, workers int, pollInterval time.Duration) *Runner {
+	return &Runner{
+		JobDB:        db,
+		Handlers:     map[string]Handler{},
+		Workers:      workers,
+		PollInterval: pollInterval,
+		Backoff:      10 * time.Second,
+		MaxBackoff:   10 * time.Minute,
+		Clock:        clock.Real{},
+	}
+}
+
+// Register sets the handler of the jobs of a type.
+func (r *Runner) Register(jobType string, handler Handler) {
+	r.Handlers[jobType] = handler
+}
+
+// Run puts back in the queue the jobs interrupted by the last shutdown and
+// then works until ctx is done.
+func (r *Runner) Run(ctx context.Context) {
+	if _, err := r.JobDB.Requeue(); err != nil {
+		log.Printf("job runner: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < r.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (r *Runner) work(ctx context.Context) {
+	ticker := time.NewTicker(r.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			ran, err := r.RunNext(ctx)
+			if err != nil {
+				log.Printf("job runner: %v", err)
+			}
+			if !ran || err != nil || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext claims the next due job and runs an attempt of it, telling whether
+// there was one.
+func (r *Runner) RunNext(ctx context.Context) (bool, error) {
+	job, err := r.JobDB.Claim()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.run(ctx, job)
+	return true, r.JobDB.Finish(job)
+}
+
+func (r *Runner) run(ctx context.Context, job *entity.Job) {
+	if job.CancelRequested {
+		// cancelled while waiting for a retry
+		job.Cancel(r.Clock.Now())
+		return
+	}
+	handler, ok := r.Handlers[job.Type]
+	if !ok {
+		job.Fail(fmt.Errorf("no handler for %s jobs", job.Type), false, 0, r.Clock.Now())
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progress := func(done int64) error {
+		job.Progress = done
+		requested, err := r.JobDB.SetProgress(job.ID.String(), done)
+		if err != nil {
+			return err
+		}
+		if requested {
+			cancel()
+			return entity.ErrJobCancelled
+		}
+		return ctx.Err()
+	}
+
+	result, err := safeRun(ctx, handler, job, progress)
+	now := r.Clock.Now()
+	if err == nil {
+		err = job.Succeed(result, now)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(err, entity.ErrJobCancelled):
+		job.Cancel(now)
+	default:
+		var permanent permanentError
+		job.Fail(err, !errors.As(err, &permanent), job.Backoff(r.Backoff, r.MaxBackoff), now)
+	}
+}
+
+// safeRun turns a panic of the handler into a failed attempt, so that a
+// single bad job does not stop the worker.
+func safeRun(ctx context.Context, handler Handler, job *entity.Job, progress Progress) (result any, err error) {
+	defer func() {
+		if rvr := recover(); rvr != nil {
+			err = fmt.Errorf("job panicked: %v", rvr)
+		}
+	}()
+	return handler(ctx, job, progress)
+}
@@ -0,0 +1,148 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createRunner(t *testing.T, now time.Time) (*Runner, *clock.Fake) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Job{}))
+
+	fake := clock.NewFake(now)
+	jobDB := database.NewJobDB(db)
+	jobDB.Clock = fake
+	runner := NewRunner(jobDB, 1, time.Second)
+	runner.Backoff, runner.MaxBackoff = time.Second, time.Minute
+	runner.Clock = fake
+	return runner, fake
+}
+
+func queue(t *testing.T, runner *Runner, jobType string, now time.Time) *entity.Job {
+	job, err := entity.NewJob(jobType, "user-1", nil, 3)
+	assert.NoError(t, err)
+	job.RunAt = now
+	assert.NoError(t, runner.JobDB.Create(job))
+	return job
+}
+
+func TestRunNextSucceeds(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	runner, _ := createRunner(t, now)
+	runner.Register("count", func(ctx context.Context, job *entity.Job, progress Progress) (any, error) {
+		assert.NoError(t, progress(5))
+		return map[string]int{"counted": 5}, nil
+	})
+	job := queue(t, runner, "count", now)
+
+	ran, err := runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ran)
+
+	found, _ := runner.JobDB.FindByID(job.ID.String())
+	assert.Equal(t, entity.JobSucceeded, found.Status)
+	assert.Equal(t, int64(5), found.Progress)
+	assert.JSONEq(t, `{"counted": 5}`, found.Result)
+
+	ran, err = runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, ran)
+}
+
+func TestRunNextRetriesWithBackoff(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	runner, fake := createRunner(t, now)
+	attempts := 0
+	runner.Register("flaky", func(ctx context.Context, job *entity.Job, progress Progress) (any, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errors.New("temporarily unavailable")
+		}
+		return nil, nil
+	})
+	job := queue(t, runner, "flaky", now)
+
+	_, err := runner.RunNext(context.Background())
+	assert.NoError(t, err)
+	found, _ := runner.JobDB.FindByID(job.ID.String())
+	assert.Equal(t, entity.JobQueued, found.Status)
+	assert.Equal(t, now.Add(time.Second), found.RunAt.UTC())
+	assert.Equal(t, "temporarily unavailable", found.Error)
+
+	// not due until the backoff passed
+	ran, _ := runner.RunNext(context.Background())
+	assert.False(t, ran)
+
+	fake.Set(now.Add(time.Second))
+	runner.RunNext(context.Background())
+	found, _ = runner.JobDB.FindByID(job.ID.String())
+	assert.Equal(t, now.Add(3*time.Second), found.RunAt.UTC())
+
+	fake.Set(now.Add(3 * time.Second))
+	runner.RunNext(context.Background())
+	found, _ = runner.JobDB.FindByID(job.ID.String())
+	assert.Equal(t, entity.JobSucceeded, found.Status)
+	assert.Equal(t, 3, found.Attempts)
+}
+
+func TestRunNextFailures(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	runner, _ := createRunner(t, now)
+	runner.Register("malformed", func(ctx context.Context, job *entity.Job, progress Progress) (any, error) {
+		return nil, Permanent(errors.New("malformed file"))
+	})
+	runner.Register("panics", func(ctx context.Context, job *entity.Job, progress Progress) (any, error) {
+		panic("boom")
+	})
+
+	malformed := queue(t, runner, "malformed", now)
+	runner.RunNext(context.Background())
+	found, _ := runner.JobDB.FindByID(malformed.ID.String())
+	assert.Equal(t, entity.JobFailed, found.Status)
+	assert.Equal(t, 1, found.Attempts)
+
+	unknown := queue(t, runner, "unknown", now)
+	runner.RunNext(context.Background())
+	found, _ = runner.JobDB.FindByID(unknown.ID.String())
+	assert.Equal(t, entity.JobFailed, found.Status)
+
+	panics := queue(t, runner, "panics", now)
+	runner.RunNext(context.Background())
+	found, _ = runner.JobDB.FindByID(panics.ID.String())
+	assert.Equal(t, entity.JobQueued, found.Status)
+	assert.Equal(t, "job panicked: boom", found.Error)
+}
+
+func TestRunNextCancels(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	runner, _ := createRunner(t, now)
+	runner.Register("long", func(ctx context.Context, job *entity.Job, progress Progress) (any, error) {
+		_, err := runner.JobDB.Cancel(job.ID.String())
+		assert.NoError(t, err)
+		for done := int64(1); ; done++ {
+			err := progress(done)
+			if err != nil {
+				assert.Error(t, ctx.Err())
+				return nil, err
+			}
+		}
+	})
+	job := queue(t, runner, "long", now)
+
+	runner.RunNext(context.Background())
+	found, _ := runner.JobDB.FindByID(job.ID.String())
+	assert.Equal(t, entity.JobCancelled, found.Status)
+	assert.Equal(t, int64(1), found.Progress)
+	assert.NotNil(t, found.FinishedAt)
+}
@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"log"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// Notifier delivers price drops to the users watching the product.
+type Notifier interface {
+	NotifyPriceDrop(drop entity.PriceDrop) error
+}
+
+// LogNotifier writes the price drops to the log, standing in for email or
+// push delivery.
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyPriceDrop(drop entity.PriceDrop) error {
+	log.Printf("price drop: user %s, wishlist %q: %s went from %s to %s",
+		drop.UserID, drop.WishlistName, drop.Product.Name, drop.PreviousPrice, drop.Product.Price)
+	return nil
+}
+
+// PriceDropWatcher turns price changes into price drops for every wishlist
+// holding the product. Its PriceChanged method is a database.PriceHook.
+type PriceDropWatcher struct {
+	Wishlists database.WishlistInterface
+	Notifiers []Notifier
+}
+
+func NewPriceDropWatcher(wishlists database.WishlistInterface, notifiers ...Notifier) *PriceDropWatcher {
+	return &PriceDropWatcher{Wishlists: wishlists, Notifiers: notifiers}
+}
+
+func (w *PriceDropWatcher) PriceChanged(product *entity.Product, previous money.Money) {
+	if !entity.IsPriceDrop(previous, product.Price) {
+		return
+	}
+
+	wishlists, err := w.Wishlists.FindWatching(product.ID.String())
+	if err != nil {
+		log.Printf("price drop: finding the wishlists of product %s: %v", product.ID, err)
+		return
+	}
+
+	for _, wishlist := range wishlists {
+		drop := entity.PriceDrop{
+			UserID:        wishlist.UserID,
+			WishlistID:    wishlist.ID,
+			WishlistName:  wishlist.Name,
+			Product:       *product,
+			PreviousPrice: previous,
+		}
+		for _, notifier := range w.Notifiers {
+			err = notifier.NotifyPriceDrop(drop)
+			if err != nil {
+				log.Printf("price drop: notifying user %s: %v", drop.UserID, err)
+			}
+		}
+	}
+}
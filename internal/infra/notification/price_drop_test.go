@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type recorder struct {
+	drops []entity.PriceDrop
+}
+
+func (r *recorder) NotifyPriceDrop(drop entity.PriceDrop) error {
+	r.drops = append(r.drops, drop)
+	return nil
+}
+
+func TestPriceDropsReachWishlists(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.ProductPriceHistory{}, &entity.Wishlist{}, &entity.WishlistItem{}))
+
+	wishlistDB := database.NewWishlistDB(db)
+	notified := &recorder{}
+	productDB := database.NewProductDB(db)
+	productDB.PriceHooks = append(productDB.PriceHooks, NewPriceDropWatcher(wishlistDB, notified).PriceChanged)
+
+	product, _ := entity.NewProduct("Headphones", money.MustParse("100.00", "USD"))
+	assert.NoError(t, productDB.Create(product))
+	other, _ := entity.NewProduct("Cable", money.MustParse("10.00", "USD"))
+	assert.NoError(t, productDB.Create(other))
+
+	birthday, _ := entity.NewWishlist("user-1", "Birthday")
+	assert.NoError(t, wishlistDB.Create(birthday))
+	assert.NoError(t, wishlistDB.AddItem(birthday, product.ID.String()))
+	gifts, _ := entity.NewWishlist("user-2", "Gifts")
+	assert.NoError(t, wishlistDB.Create(gifts))
+	assert.NoError(t, wishlistDB.AddItem(gifts, product.ID.String()))
+
+	// price rises and unlisted products notify nobody
+	product.Price = money.MustParse("120.00", "USD")
+	assert.NoError(t, productDB.Update(product, "admin"))
+	other.Price = money.MustParse("5.00", "USD")
+	assert.NoError(t, productDB.Update(other, "admin"))
+	assert.Empty(t, notified.drops)
+
+	product.Price = money.MustParse("80.00", "USD")
+	assert.NoError(t, productDB.Update(product, "admin"))
+	assert.Len(t, notified.drops, 2)
+	for _, drop := range notified.drops {
+		assert.Equal(t, money.MustParse("120.00", "USD"), drop.PreviousPrice)
+		assert.Equal(t, money.MustParse("80.00", "USD"), drop.Product.Price)
+	}
+	assert.ElementsMatch(t, []string{"user-1", "user-2"}, []string{notified.drops[0].UserID, notified.drops[1].UserID})
+
+	// an update that keeps the price runs no hook
+	product.Name = "Wireless headphones"
+	assert.NoError(t, productDB.Update(product, "admin"))
+	assert.Len(t, notified.drops, 2)
+}
@@ -0,0 +1,164 @@
+package payment
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+const (
+	// SignatureHeader carries the signature of the fake webhooks as
+	// "t=<unix time>,v1=<hex HMAC-SHA256 of "<time>.<payload>">".
+	SignatureHeader = "X-Fake-Signature"
+
+	// DeclinedMethod is the payment method the fake provider always declines.
+	DeclinedMethod = "fake_card_declined"
+)
+
+// FakeProvider is a payment gateway that runs in memory, for development
+// and tests. Every method but DeclinedMethod is authorized. When WebhookURL
+// is set, captures and refunds are notified there with signed webhooks, like
+// a real gateway would.
+type FakeProvider struct {
+	Secret     []byte
+	WebhookURL string
+	// Tolerance is how old a webhook signature may be.
+	Tolerance time.Duration
+	Client    *http.Client
+
+	mu       sync.Mutex
+	payments map[string]*Result
+	keys     map[string]string
+}
+
+func NewFakeProvider(secret, webhookURL string) *FakeProvider {
+	return &FakeProvider{
+		Secret:     []byte(secret),
+		WebhookURL: webhookURL,
+		Tolerance:  5 * time.Minute,
+		Client:     http.DefaultClient,
+		payments:   map[string]*Result{},
+		keys:       map[string]string{},
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) Authorize(ctx context.Context, req AuthorizeRequest) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ref, ok := p.keys[req.IdempotencyKey]; ok {
+		return *p.payments[ref], nil
+	}
+
+	result := &Result{ProviderRef: "fake_" + entity.NewID().String(), Status: StatusAuthorized}
+	if req.Method == DeclinedMethod {
+		result.Status = StatusDeclined
+	}
+	p.payments[result.ProviderRef] = result
+	p.keys[req.IdempotencyKey] = result.ProviderRef
+	return *result, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, providerRef string) (Result, error) {
+	return p.move(providerRef, StatusAuthorized, StatusCaptured, EventCaptured)
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, providerRef string) (Result, error) {
+	return p.move(providerRef, StatusCaptured, StatusRefunded, EventRefunded)
+}
+
+func (p *FakeProvider) move(providerRef string, from, to Status, event EventType) (Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.payments[providerRef]
+	if !ok {
+		return Result{}, ErrNotFound
+	}
+	if result.Status == to {
+		return *result, nil
+	}
+	if result.Status != from {
+		return *result, ErrInvalidState
+	}
+	result.Status = to
+
+	if p.WebhookURL != "" {
+		go p.notify(Event{ID: "evt_" + entity.NewID().String(), Type: event, ProviderRef: providerRef})
+	}
+	return *result, nil
+}
+
+// notify posts a signed event to WebhookURL.
+func (p *FakeProvider) notify(event Event) {
+	payload, _ := json.Marshal(event)
+	req, err := http.NewRequest(http.MethodPost, p.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, p.Sign(payload, time.Now()))
+
+	resp, err := p.Client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+}
+
+// Sign returns the signature header of a webhook payload sent at t.
+func (p *FakeProvider) Sign(payload []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + p.mac(timestamp, payload)
+}
+
+func (p *FakeProvider) mac(timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, p.Secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, header http.Header) (*Event, error) {
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(SignatureHeader), ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > p.Tolerance || age < -p.Tolerance {
+		return nil, ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(p.mac(timestamp, payload))) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	err = json.Unmarshal(payload, &event)
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
@@ -0,0 +1,101 @@
+package payment
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeProviderPayments(t *testing.T) {
+	provider := NewFakeProvider("secret", "")
+	ctx := context.Background()
+	req := AuthorizeRequest{Amount: money.MustParse("10.00", "USD"), Method: "fake_card", Reference: "order-1", IdempotencyKey: "key-1"}
+
+	authorized, err := provider.Authorize(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusAuthorized, authorized.Status)
+
+	// the same key gives the same payment back
+	again, err := provider.Authorize(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, authorized.ProviderRef, again.ProviderRef)
+
+	_, err = provider.Refund(ctx, authorized.ProviderRef)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	captured, err := provider.Capture(ctx, authorized.ProviderRef)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCaptured, captured.Status)
+
+	refunded, err := provider.Refund(ctx, authorized.ProviderRef)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusRefunded, refunded.Status)
+
+	req.Method, req.IdempotencyKey = DeclinedMethod, "key-2"
+	declined, err := provider.Authorize(ctx, req)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDeclined, declined.Status)
+	_, err = provider.Capture(ctx, declined.ProviderRef)
+	assert.ErrorIs(t, err, ErrInvalidState)
+
+	_, err = provider.Capture(ctx, "fake_unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestFakeProviderVerifyWebhook(t *testing.T) {
+	provider := NewFakeProvider("secret", "")
+	payload := []byte(`{"id":"evt_1","type":"payment.captured","provider_ref":"fake_1"}`)
+	header := http.Header{}
+
+	header.Set(SignatureHeader, provider.Sign(payload, time.Now()))
+	event, err := provider.VerifyWebhook(payload, header)
+	assert.NoError(t, err)
+	assert.Equal(t, EventCaptured, event.Type)
+	assert.Equal(t, "fake_1", event.ProviderRef)
+
+	_, err = provider.VerifyWebhook([]byte(`{"id":"evt_1","type":"payment.refunded"}`), header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	header.Set(SignatureHeader, NewFakeProvider("other", "").Sign(payload, time.Now()))
+	_, err = provider.VerifyWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	header.Set(SignatureHeader, provider.Sign(payload, time.Now().Add(-time.Hour)))
+	_, err = provider.VerifyWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	header.Del(SignatureHeader)
+	_, err = provider.VerifyWebhook(payload, header)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestFakeProviderNotifies(t *testing.T) {
+	received := make(chan *Event, 1)
+	var provider *FakeProvider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		payload, _ := io.ReadAll(req.Body)
+		event, err := provider.VerifyWebhook(payload, req.Header)
+		assert.NoError(t, err)
+		received <- event
+	}))
+	defer server.Close()
+	provider = NewFakeProvider("secret", server.URL)
+
+	authorized, _ := provider.Authorize(context.Background(), AuthorizeRequest{Amount: money.MustParse("1.00", "USD"), IdempotencyKey: "key"})
+	_, err := provider.Capture(context.Background(), authorized.ProviderRef)
+	assert.NoError(t, err)
+
+	select {
+	case event := <-received:
+		assert.Equal(t, EventCaptured, event.Type)
+		assert.Equal(t, authorized.ProviderRef, event.ProviderRef)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook received")
+	}
+}
@@ -0,0 +1,70 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+var (
+	ErrDeclined         = errors.New("the payment was declined")
+	ErrNotFound         = errors.New("payment not found")
+	ErrInvalidState     = errors.New("the payment is not in a state that allows the operation")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+type Status string
+
+const (
+	StatusAuthorized Status = "authorized"
+	StatusCaptured   Status = "captured"
+	StatusDeclined   Status = "declined"
+	StatusRefunded   Status = "refunded"
+	StatusFailed     Status = "failed"
+)
+
+type EventType string
+
+const (
+	EventCaptured EventType = "payment.captured"
+	EventFailed   EventType = "payment.failed"
+	EventRefunded EventType = "payment.refunded"
+)
+
+// AuthorizeRequest asks the provider to hold Amount on the payment method.
+// Providers return the same result when a key is authorized again, so
+// retries never charge twice.
+type AuthorizeRequest struct {
+	Amount         money.Money
+	Method         string
+	Reference      string
+	IdempotencyKey string
+}
+
+// Result is the state of a payment at the provider.
+type Result struct {
+	ProviderRef string
+	Status      Status
+}
+
+// Event is a payment change the provider notifies through a webhook.
+type Event struct {
+	ID          string    `json:"id"`
+	Type        EventType `json:"type"`
+	ProviderRef string    `json:"provider_ref"`
+	Reference   string    `json:"reference"`
+}
+
+// PaymentProvider charges payment methods through a payment gateway.
+type PaymentProvider interface {
+	// Name identifies the provider on the recorded payments.
+	Name() string
+	Authorize(ctx context.Context, req AuthorizeRequest) (Result, error)
+	Capture(ctx context.Context, providerRef string) (Result, error)
+	Refund(ctx context.Context, providerRef string) (Result, error)
+	// VerifyWebhook checks the signature of a webhook request and returns
+	// its event, failing with ErrInvalidSignature.
+	VerifyWebhook(payload []byte, header http.Header) (*Event, error)
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+// IdempotencySweeper deletes the requests kept for their idempotency key
+// once they expire.
+type IdempotencySweeper struct {
+	IdempotencyDB database.IdempotencyInterface
+	Interval      time.Duration
+}
+
+func NewIdempotencySweeper(db database.IdempotencyInterface, interval time.Duration) *IdempotencySweeper {
+	return &IdempotencySweeper{
+		IdempotencyDB: db,
+		Interval:      interval,
+	}
+}
+
+// Run sweeps every Interval until ctx is done.
+func (s *IdempotencySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.IdempotencyDB.DeleteExpired(); err != nil {
+			log.Printf("idempotency sweeper: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+// ChangedBy is recorded in the price history for list prices changed by the
+// scheduler.
+const ChangedBy = "scheduler"
+
+// PriceScheduler starts and ends scheduled prices. Permanent changes are
+// written to the product list price; promotions only move between the
+// scheduled, active and ended statuses.
+type PriceScheduler struct {
+	ScheduledPriceDB database.ScheduledPriceInterface
+	ProductDB        database.ProductInterface
+	Clock            clock.Clock
+	Interval         time.Duration
+}
+
+func NewPriceScheduler(scheduledPrices database.ScheduledPriceInterface, products database.ProductInterface, interval time.Duration) *PriceScheduler {
+	return &PriceScheduler{
+		ScheduledPriceDB: scheduledPrices,
+		ProductDB:        products,
+		Clock:            clock.Real{},
+		Interval:         interval,
+	}
+}
+
+// Run ticks every Interval until ctx is done.
+func (s *PriceScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Tick(); err != nil {
+			log.Printf("price scheduler: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Tick applies every scheduled price that is due and ends every promotion
+// that is over at the current time.
+func (s *PriceScheduler) Tick() error {
+	now := s.Clock.Now()
+
+	due, err := s.ScheduledPriceDB.FindDue(now)
+	if err != nil {
+		return err
+	}
+	for i := range due {
+		err = s.start(&due[i], now)
+		if err != nil {
+			return err
+		}
+	}
+
+	expired, err := s.ScheduledPriceDB.FindExpired(now)
+	if err != nil {
+		return err
+	}
+	for i := range expired {
+		expired[i].Status = entity.ScheduledPriceEnded
+		err = s.ScheduledPriceDB.Update(&expired[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PriceScheduler) start(scheduledPrice *entity.ScheduledPrice, now time.Time) error {
+	if scheduledPrice.IsPromotion() {
+		scheduledPrice.Status = entity.ScheduledPriceActive
+		if !now.Before(*scheduledPrice.EndsAt) {
+			scheduledPrice.Status = entity.ScheduledPriceEnded
+		}
+		return s.ScheduledPriceDB.Update(scheduledPrice)
+	}
+
+	product, err := s.ProductDB.FindByID(scheduledPrice.ProductID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// the product was deleted after the price was scheduled
+		scheduledPrice.Status = entity.ScheduledPriceCancelled
+		return s.ScheduledPriceDB.Update(scheduledPrice)
+	}
+	if err != nil {
+		return err
+	}
+	product.Price = scheduledPrice.Price
+	err = s.ProductDB.Update(product, ChangedBy)
+	if err != nil {
+		return err
+	}
+
+	scheduledPrice.Status = entity.ScheduledPriceApplied
+	return s.ScheduledPriceDB.Update(scheduledPrice)
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createPriceScheduler(t *testing.T, now time.Time) (*PriceScheduler, *clock.Fake) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.ProductPriceHistory{}, &entity.ScheduledPrice{}))
+
+	fake := clock.NewFake(now)
+	scheduler := NewPriceScheduler(database.NewScheduledPriceDB(db), database.NewProductDB(db), time.Minute)
+	scheduler.Clock = fake
+	return scheduler, fake
+}
+
+func TestTickAppliesPermanentPriceChange(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scheduler, fake := createPriceScheduler(t, now)
+
+	product, err := entity.NewProduct("Product 1", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.ProductDB.Create(product))
+
+	change, err := entity.NewScheduledPrice(product, money.MustParse("12.00", "USD"), now.Add(time.Hour), nil, "admin@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.ScheduledPriceDB.Create(change))
+
+	assert.NoError(t, scheduler.Tick())
+	found, err := scheduler.ProductDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("10.00", "USD"), found.Price)
+
+	fake.Advance(time.Hour)
+	assert.NoError(t, scheduler.Tick())
+	found, err = scheduler.ProductDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("12.00", "USD"), found.Price)
+
+	change, err = scheduler.ScheduledPriceDB.FindByID(change.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ScheduledPriceApplied, change.Status)
+
+	history, err := scheduler.ProductDB.FindPriceHistory(product.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, ChangedBy, history[0].ChangedBy)
+}
+
+func TestTickStartsAndEndsPromotion(t *testing.T) {
+	now := time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC)
+	scheduler, fake := createPriceScheduler(t, now)
+
+	product, err := entity.NewProduct("Product 1", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.ProductDB.Create(product))
+
+	startsAt := now.Add(24 * time.Hour)
+	endsAt := startsAt.Add(72 * time.Hour)
+	promotion, err := entity.NewScheduledPrice(product, money.MustParse("7.50", "USD"), startsAt, &endsAt, "admin@example.com")
+	assert.NoError(t, err)
+	assert.NoError(t, scheduler.ScheduledPriceDB.Create(promotion))
+
+	fake.Set(startsAt)
+	assert.NoError(t, scheduler.Tick())
+	promotion, err = scheduler.ScheduledPriceDB.FindByID(promotion.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ScheduledPriceActive, promotion.Status)
+
+	active, err := scheduler.ScheduledPriceDB.FindActivePromotions([]string{product.ID.String()}, fake.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("7.50", "USD"), active[product.ID.String()].Price)
+
+	// the list price is left alone
+	found, err := scheduler.ProductDB.FindByID(product.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("10.00", "USD"), found.Price)
+
+	fake.Set(endsAt)
+	assert.NoError(t, scheduler.Tick())
+	promotion, err = scheduler.ScheduledPriceDB.FindByID(promotion.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.ScheduledPriceEnded, promotion.Status)
+
+	active, err = scheduler.ScheduledPriceDB.FindActivePromotions([]string{product.ID.String()}, fake.Now())
+	assert.NoError(t, err)
+	assert.Empty(t, active)
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+// ReservationSweeper releases the stock held by reservations that expired
+// without being committed.
+type ReservationSweeper struct {
+	StockDB  database.StockInterface
+	Interval time.Duration
+}
+
+func NewReservationSweeper(stock database.StockInterface, interval time.Duration) *ReservationSweeper {
+	return &ReservationSweeper{
+		StockDB:  stock,
+		Interval: interval,
+	}
+}
+
+// Run sweeps every Interval until ctx is done.
+func (s *ReservationSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.StockDB.ReleaseExpired(); err != nil {
+			log.Printf("reservation sweeper: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package tax
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"gopkg.in/yaml.v3"
+)
+
+// FileRules are tax rules read from a YAML file such as:
+//
+//	regions:
+//	  - code: US-CA
+//	    name: California
+//	    prices_include_tax: false
+//	    rounding: line
+//	    rates:
+//	      - rate: "7.25"
+//	      - category_id: 0d6b3c52-3f0a-4a8e-9d57-1c2b3a4d5e6f
+//	        rate: "0"
+//
+// Rates without category_id are the default of their region.
+type FileRules struct {
+	regions map[string]entity.TaxRegion
+}
+
+type fileRegion struct {
+	Code             string     `yaml:"code"`
+	Name             string     `yaml:"name"`
+	PricesIncludeTax bool       `yaml:"prices_include_tax"`
+	Rounding         string     `yaml:"rounding"`
+	Rates            []fileRate `yaml:"rates"`
+}
+
+type fileRate struct {
+	CategoryID string `yaml:"category_id"`
+	Rate       string `yaml:"rate"`
+}
+
+func LoadFile(path string) (*FileRules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseYAML(file)
+}
+
+// ParseYAML reads the rules, failing on the first invalid region or rate.
+func ParseYAML(r io.Reader) (*FileRules, error) {
+	var document struct {
+		Regions []fileRegion `yaml:"regions"`
+	}
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+	err := decoder.Decode(&document)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	rules := &FileRules{regions: map[string]entity.TaxRegion{}}
+	for _, input := range document.Regions {
+		rounding := entity.TaxRounding(input.Rounding)
+		if rounding == "" {
+			rounding = entity.TaxRoundLine
+		}
+		region, err := entity.NewTaxRegion(input.Code, input.Name, input.PricesIncludeTax, rounding)
+		if err != nil {
+			return nil, fmt.Errorf("tax region %q: %w", input.Code, err)
+		}
+		if _, ok := rules.regions[region.Code]; ok {
+			return nil, fmt.Errorf("tax region %q is listed twice", region.Code)
+		}
+
+		for _, rate := range input.Rates {
+			var categoryID *entityPkg.ID
+			if rate.CategoryID != "" {
+				id, err := entityPkg.ParseID(rate.CategoryID)
+				if err != nil {
+					return nil, fmt.Errorf("tax region %q: invalid category %q", region.Code, rate.CategoryID)
+				}
+				categoryID = &id
+			}
+			err = region.AddRate(categoryID, rate.Rate)
+			if err != nil {
+				return nil, fmt.Errorf("tax region %q: %w", region.Code, err)
+			}
+		}
+		rules.regions[region.Code] = *region
+	}
+	return rules, nil
+}
+
+func (f *FileRules) FindRegion(code string) (*entity.TaxRegion, error) {
+	region, ok := f.regions[entity.NormalizeTaxRegion(code)]
+	if !ok {
+		return nil, entity.ErrTaxRegionNotFound
+	}
+	return &region, nil
+}
+
+func (f *FileRules) FindRegions() ([]entity.TaxRegion, error) {
+	regions := make([]entity.TaxRegion, 0, len(f.regions))
+	for _, region := range f.regions {
+		regions = append(regions, region)
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Code < regions[j].Code })
+	return regions, nil
+}
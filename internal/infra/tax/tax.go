@@ -0,0 +1,63 @@
+package tax
+
+import (
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// Rules finds the tax regions. database.TaxDB keeps them in the database
+// and FileRules reads them from a YAML file.
+type Rules interface {
+	// FindRegion fails with entity.ErrTaxRegionNotFound for unknown regions.
+	FindRegion(code string) (*entity.TaxRegion, error)
+	FindRegions() ([]entity.TaxRegion, error)
+}
+
+// Calculator quotes the tax of cart lines, picking the rate of each product
+// from its categories.
+type Calculator struct {
+	Rules      Rules
+	Categories database.CategoryInterface
+}
+
+func NewCalculator(rules Rules, categories database.CategoryInterface) *Calculator {
+	return &Calculator{Rules: rules, Categories: categories}
+}
+
+// Quote computes the tax due on the available lines in the region, at their
+// current prices. Discounts, when given, are taken off the line of the same
+// index before it is taxed.
+func (c *Calculator) Quote(region string, lines []entity.CartLine, discounts []money.Money) (*entity.TaxQuote, error) {
+	rules, err := c.Rules.FindRegion(region)
+	if err != nil {
+		return nil, err
+	}
+
+	taxable := make([]entity.TaxableLine, 0, len(lines))
+	for i, line := range lines {
+		if !line.Available() {
+			continue
+		}
+		categories, err := c.Categories.FindLineage(line.Product.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		amount, err := line.Total()
+		if err != nil {
+			return nil, err
+		}
+		if discounts != nil {
+			amount, err = amount.Sub(discounts[i])
+			if err != nil {
+				return nil, err
+			}
+		}
+		taxable = append(taxable, entity.TaxableLine{
+			ProductID:  line.Product.ID,
+			Amount:     amount,
+			Categories: categories,
+		})
+	}
+	return rules.Quote(taxable)
+}
@@ -0,0 +1,103 @@
+package tax
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestParseYAML(t *testing.T) {
+	rules, err := ParseYAML(strings.NewReader(`
+regions:
+  - code: us-ca
+    name: California
+    rates:
+      - rate: "7.25"
+      - category_id: 0d6b3c52-3f0a-4a8e-9d57-1c2b3a4d5e6f
+        rate: "0"
+  - code: DE
+    prices_include_tax: true
+    rounding: order
+    rates:
+      - rate: "19"
+`))
+	assert.NoError(t, err)
+
+	region, err := rules.FindRegion("US-CA")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.TaxRoundLine, region.Rounding)
+	assert.Len(t, region.Rates, 2)
+
+	regions, err := rules.FindRegions()
+	assert.NoError(t, err)
+	assert.Equal(t, "DE", regions[0].Code)
+	assert.True(t, regions[0].PricesIncludeTax)
+
+	_, err = rules.FindRegion("FR")
+	assert.ErrorIs(t, err, entity.ErrTaxRegionNotFound)
+
+	_, err = ParseYAML(strings.NewReader("regions:\n  - code: DE\n    rates:\n      - rate: \"150\"\n"))
+	assert.ErrorIs(t, err, entity.ErrInvalidTaxRate)
+	_, err = ParseYAML(strings.NewReader("regions:\n  - code: DE\n    vat: 19\n"))
+	assert.Error(t, err)
+}
+
+func TestCalculatorUsesCategoryRates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.Category{}, &entity.ProductCategory{}, &entity.TaxRegion{}, &entity.TaxRate{}))
+	categoryDB := database.NewCategoryDB(db)
+
+	media, _ := entity.NewCategory("Media", nil)
+	assert.NoError(t, categoryDB.Create(media))
+	books, _ := entity.NewCategory("Books", &media.ID)
+	assert.NoError(t, categoryDB.Create(books))
+
+	novel, _ := entity.NewProduct("Novel", money.MustParse("10.00", "EUR"))
+	lamp, _ := entity.NewProduct("Lamp", money.MustParse("50.00", "EUR"))
+	for _, product := range []*entity.Product{novel, lamp} {
+		assert.NoError(t, database.NewProductDB(db).Create(product))
+	}
+	assert.NoError(t, categoryDB.AssignProducts(books.ID.String(), []string{novel.ID.String()}))
+
+	region, _ := entity.NewTaxRegion("DE", "Germany", false, entity.TaxRoundLine)
+	assert.NoError(t, region.AddRate(nil, "19"))
+	assert.NoError(t, region.AddRate(&media.ID, "7"))
+	assert.NoError(t, database.NewTaxDB(db).Save(region))
+
+	novelItem, _ := entity.NewCartItem("user-1", novel, nil, 2)
+	lampItem, _ := entity.NewCartItem("user-1", lamp, nil, 1)
+	calculator := NewCalculator(database.NewTaxDB(db), categoryDB)
+	quote, err := calculator.Quote("de", []entity.CartLine{
+		{Item: *novelItem, Product: novel},
+		{Item: *lampItem, Product: lamp},
+		{Item: *lampItem},
+	}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, quote.Lines, 2)
+	assert.Equal(t, "7", quote.Lines[0].Rate)
+	assert.Equal(t, money.MustParse("1.40", "EUR"), quote.Lines[0].Tax)
+	assert.Equal(t, "19", quote.Lines[1].Rate)
+	assert.Equal(t, money.MustParse("9.50", "EUR"), quote.Lines[1].Tax)
+	assert.Equal(t, money.MustParse("80.90", "EUR"), quote.Gross)
+
+	// discounts are taken off before the tax
+	quote, err = calculator.Quote("de", []entity.CartLine{
+		{Item: *novelItem, Product: novel},
+		{Item: *lampItem, Product: lamp},
+	}, []money.Money{money.MustParse("2.00", "EUR"), money.MustParse("10.00", "EUR")})
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("1.26", "EUR"), quote.Lines[0].Tax)
+	assert.Equal(t, money.MustParse("7.60", "EUR"), quote.Lines[1].Tax)
+	assert.Equal(t, money.MustParse("58.00", "EUR"), quote.Net)
+
+	_, err = calculator.Quote("FR", nil, nil)
+	assert.ErrorIs(t, err, entity.ErrTaxRegionNotFound)
+}
@@ -0,0 +1,270 @@
+package validator
+
+// catalog holds the messages of one locale. Messages are keyed by validation
+// tag and receive the field label as {0} and the rule parameter as {1};
+// fields are keyed by resource and JSON name. Details are the fixed
+// descriptions of problems, keyed by the error they describe.
+type catalog struct {
+	messages map[string]string
+	fields   map[string]string
+	details  map[string]string
+}
+
+var catalogs = map[string]catalog{
+	"en": {
+		messages: map[string]string{
+			"invalid":          "{0} is invalid",
+			"required":         "{0} is required",
+			"email":            "{0} must be a valid email address",
+			"uuid":             "{0} must be a valid UUID",
+			"gt":               "{0} must be greater than {1}",
+			"max":              "{0} must be at most {1} characters long",
+			"uuid_id":          "{0} must be a valid UUID",
+			"trimmed":          "{0} must not start or end with spaces",
+			"money_positive":   "{0} must be a positive amount",
+			"decimals":         "{0} must have at most {1} decimal places",
+			"iso4217":          "{0} must be an ISO 4217 currency code",
+			"nefield":          "{0} must be different from {1}",
+			"positive_decimal": "{0} must be a positive decimal number",
+			"datetime":         "{0} must be an RFC 3339 date and time",
+			"gtfield":          "{0} must be after {1}",
+			"eqfield":          "{0} must match the {1}",
+			"boolean":          "{0} must be true or false",
+			"oneof":            "{0} must be one of {1}",
+			"gte":              "{0} must be at least {1}",
+			"max_items":        "{0} must have at most {1} items",
+		},
+		fields: map[string]string{
+			"user.name":                     "name",
+			"user.email":                    "email",
+			"user.password":                 "password",
+			"product.id":                    "id",
+			"product.name":                  "name",
+			"product.price":                 "price",
+			"product.price.currency":        "currency",
+			"product.tags":                  "tags",
+			"exchangerate.base_currency":    "base currency",
+			"exchangerate.quote_currency":   "quote currency",
+			"exchangerate.rate":             "rate",
+			"exchangerate.effective_at":     "effective date",
+			"scheduledprice.price":          "price",
+			"scheduledprice.price.currency": "currency",
+			"scheduledprice.starts_at":      "start date",
+			"scheduledprice.ends_at":        "end date",
+			"category.name":                 "name",
+			"variant.options":               "options",
+			"variant.stock":                 "stock",
+			"variant.price":                 "price",
+			"variant.price.currency":        "currency",
+			"stock.quantity":                "quantity",
+			"stock.reason":                  "reason",
+			"stock.note":                    "note",
+			"cart.quantity":                 "quantity",
+			"order.items":                   "items",
+			"order.status":                  "status",
+			"order.total":                   "total",
+			"payment.idempotency_key":       "idempotency key",
+			"review.rating":                 "rating",
+			"review.text":                   "text",
+			"review.status":                 "status",
+			"wishlist.name":                 "name",
+			"coupon.code":                   "code",
+			"coupon.type":                   "type",
+			"coupon.percent":                "percent",
+			"coupon.amount":                 "amount",
+			"coupon.min_order":              "minimum order",
+			"coupon.max_redemptions":        "usage limit",
+			"coupon.ends_at":                "end date",
+			"coupon.product_ids":            "product restrictions",
+			"coupon.category_ids":           "category restrictions",
+			"tax.region":                    "region",
+			"tax.name":                      "name",
+			"tax.rounding":                  "rounding",
+			"tax.rate":                      "rate",
+			"tax.items":                     "items",
+			"batch.operations":              "operations",
+			"batch.op":                      "operation",
+			"batch.product":                 "product",
+		},
+		details: map[string]string{
+			"invalid_fields":                 "the request has invalid fields",
+			"invalid_params":                 "the request has invalid parameters",
+			"not_found":                      "the requested resource does not exist",
+			"already_exists":                 "the resource already exists",
+			"unexpected":                     "an unexpected error occurred",
+			"invalid_credentials":            "the credentials are invalid",
+			"admin_required":                 "this operation requires the admin role",
+			"exchange_rate_not_found":        "there is no exchange rate between the currencies",
+			"amount_overflow":                "the amount is too large",
+			"malformed_csv":                  "the CSV file is malformed",
+			"malformed_ndjson":               "the NDJSON file is malformed",
+			"unsupported_import":             "imports must be text/csv or application/x-ndjson",
+			"import_too_large":               "the import file is too large",
+			"batch_aborted":                  "the operation was not applied because another operation of the atomic batch failed",
+			"price_not_found":                "the product had no price at that date",
+			"currency_locked":                "the currency of a product with variant or scheduled prices cannot change",
+			"scheduled_price_not_cancelable": "only scheduled prices that have not started can be cancelled",
+			"sku_taken":                      "another variant already has this SKU",
+			"image_too_large":                "the image is too large",
+			"unsupported_image":              "images must be JPEG, PNG or GIF",
+			"invalid_image_order":            "the order must list every image of the product exactly once",
+			"variant_not_in_product":         "the variant does not belong to the product",
+			"order_currency_mismatch":        "all the items of an order must use the same currency",
+			"invalid_order_transition":       "the order cannot move to the requested status",
+			"cart_item_unavailable":          "the cart has items that are no longer available",
+			"cart_prices_changed":            "the cart has prices that changed; accept them before checking out",
+			"email_taken":                    "another user already has this email",
+			"coupon_taken":                   "another coupon already has this code",
+			"coupon_rejected":                "the coupon cannot be applied",
+			"review_taken":                   "the product was already reviewed by this user",
+			"not_review_author":              "only the author can edit a review",
+			"payment_declined":               "the payment was declined",
+			"order_not_payable":              "only pending orders can be paid",
+			"payment_in_progress":            "another payment of the order is in progress",
+			"payment_not_refundable":         "the order has no captured payment to refund",
+			"invalid_signature":              "the webhook signature is invalid",
+			"invalid_payment_state":          "the payment is not in a state that allows the operation",
+			"payment_not_found":              "the payment does not exist",
+			"insufficient_stock":             "there is not enough stock",
+			"reservation_not_held":           "the reservation is no longer held",
+			"tax_region_not_found":           "there are no tax rules for the region",
+			"tax_currency_mismatch":          "all the items of a tax quote must use the same currency",
+			"duplicate_tax_rate":             "a region has one rate per category",
+			"idempotency_key_reused":         "the idempotency key was already used with a different request",
+			"request_in_progress":            "a request with the same idempotency key is still being handled",
+			"idempotent_request_too_large":   "the request is too large to be made with an idempotency key",
+			"job_not_cancelable":             "the job already finished",
+			"job_has_no_file":                "the job has no file to download",
+			"category_cycle":                 "a category cannot be nested under itself or its descendants",
+			"category_has_children":          "the category has subcategories",
+		},
+	},
+	"pt": {
+		messages: map[string]string{
+			"invalid":          "{0} é inválido",
+			"required":         "{0} é obrigatório",
+			"email":            "{0} deve ser um endereço de e-mail válido",
+			"uuid":             "{0} deve ser um UUID válido",
+			"gt":               "{0} deve ser maior que {1}",
+			"max":              "{0} deve ter no máximo {1} caracteres",
+			"uuid_id":          "{0} deve ser um UUID válido",
+			"trimmed":          "{0} não deve começar nem terminar com espaços",
+			"money_positive":   "{0} deve ser um valor positivo",
+			"decimals":         "{0} deve ter no máximo {1} casas decimais",
+			"iso4217":          "{0} deve ser um código de moeda ISO 4217",
+			"nefield":          "{0} deve ser diferente de {1}",
+			"positive_decimal": "{0} deve ser um número decimal positivo",
+			"datetime":         "{0} deve ser uma data e hora RFC 3339",
+			"gtfield":          "{0} deve ser posterior a {1}",
+			"eqfield":          "{0} deve ser igual a {1}",
+			"boolean":          "{0} deve ser true ou false",
+			"oneof":            "{0} deve ser um de {1}",
+			"gte":              "{0} deve ser no mínimo {1}",
+			"max_items":        "{0} deve ter no máximo {1} itens",
+		},
+		fields: map[string]string{
+			"user.name":                     "nome",
+			"user.email":                    "e-mail",
+			"user.password":                 "senha",
+			"product.id":                    "id",
+			"product.name":                  "nome",
+			"product.price":                 "preço",
+			"product.price.currency":        "moeda",
+			"product.tags":                  "tags",
+			"exchangerate.base_currency":    "moeda base",
+			"exchangerate.quote_currency":   "moeda cotada",
+			"exchangerate.rate":             "taxa",
+			"exchangerate.effective_at":     "data de vigência",
+			"scheduledprice.price":          "preço",
+			"scheduledprice.price.currency": "moeda",
+			"scheduledprice.starts_at":      "data de início",
+			"scheduledprice.ends_at":        "data de término",
+			"category.name":                 "nome",
+			"variant.options":               "opções",
+			"variant.stock":                 "estoque",
+			"variant.price":                 "preço",
+			"variant.price.currency":        "moeda",
+			"stock.quantity":                "quantidade",
+			"stock.reason":                  "motivo",
+			"stock.note":                    "observação",
+			"cart.quantity":                 "quantidade",
+			"order.items":                   "itens",
+			"order.status":                  "status",
+			"order.total":                   "total",
+			"payment.idempotency_key":       "chave de idempotência",
+			"review.rating":                 "nota",
+			"review.text":                   "texto",
+			"review.status":                 "status",
+			"wishlist.name":                 "nome",
+			"coupon.code":                   "código",
+			"coupon.type":                   "tipo",
+			"coupon.percent":                "percentual",
+			"coupon.amount":                 "valor",
+			"coupon.min_order":              "pedido mínimo",
+			"coupon.max_redemptions":        "limite de uso",
+			"coupon.ends_at":                "data de término",
+			"coupon.product_ids":            "restrições de produto",
+			"coupon.category_ids":           "restrições de categoria",
+			"tax.region":                    "região",
+			"tax.name":                      "nome",
+			"tax.rounding":                  "arredondamento",
+			"tax.rate":                      "alíquota",
+			"tax.items":                     "itens",
+			"batch.operations":              "operações",
+			"batch.op":                      "operação",
+			"batch.product":                 "produto",
+		},
+		details: map[string]string{
+			"invalid_fields":                 "a requisição tem campos inválidos",
+			"invalid_params":                 "a requisição tem parâmetros inválidos",
+			"not_found":                      "o recurso pedido não existe",
+			"already_exists":                 "o recurso já existe",
+			"unexpected":                     "ocorreu um erro inesperado",
+			"invalid_credentials":            "as credenciais são inválidas",
+			"admin_required":                 "esta operação exige o papel de administrador",
+			"exchange_rate_not_found":        "não há taxa de câmbio entre as moedas",
+			"amount_overflow":                "o valor é grande demais",
+			"malformed_csv":                  "o arquivo CSV está malformado",
+			"malformed_ndjson":               "o arquivo NDJSON está malformado",
+			"unsupported_import":             "as importações devem ser text/csv ou application/x-ndjson",
+			"import_too_large":               "o arquivo de importação é grande demais",
+			"batch_aborted":                  "a operação não foi aplicada porque outra operação do lote atômico falhou",
+			"price_not_found":                "o produto não tinha preço nessa data",
+			"currency_locked":                "a moeda de um produto com preços de variantes ou agendados não pode mudar",
+			"scheduled_price_not_cancelable": "só preços agendados que ainda não começaram podem ser cancelados",
+			"sku_taken":                      "outra variação já tem esse SKU",
+			"image_too_large":                "a imagem é grande demais",
+			"unsupported_image":              "as imagens devem ser JPEG, PNG ou GIF",
+			"invalid_image_order":            "a ordem deve listar cada imagem do produto exatamente uma vez",
+			"variant_not_in_product":         "a variação não pertence ao produto",
+			"order_currency_mismatch":        "todos os itens de um pedido devem usar a mesma moeda",
+			"invalid_order_transition":       "o pedido não pode passar para o status pedido",
+			"cart_item_unavailable":          "o carrinho tem itens que não estão mais disponíveis",
+			"cart_prices_changed":            "o carrinho tem preços que mudaram; aceite-os antes de finalizar a compra",
+			"email_taken":                    "outro usuário já tem esse e-mail",
+			"coupon_taken":                   "outro cupom já tem esse código",
+			"coupon_rejected":                "o cupom não pode ser aplicado",
+			"review_taken":                   "o produto já foi avaliado por este usuário",
+			"not_review_author":              "só o autor pode editar uma avaliação",
+			"payment_declined":               "o pagamento foi recusado",
+			"order_not_payable":              "só pedidos pendentes podem ser pagos",
+			"payment_in_progress":            "outro pagamento do pedido está em andamento",
+			"payment_not_refundable":         "o pedido não tem pagamento capturado para reembolsar",
+			"invalid_signature":              "a assinatura do webhook é inválida",
+			"invalid_payment_state":          "o pagamento não está em um estado que permite a operação",
+			"payment_not_found":              "o pagamento não existe",
+			"insufficient_stock":             "não há estoque suficiente",
+			"reservation_not_held":           "a reserva não está mais válida",
+			"tax_region_not_found":           "não há regras de imposto para a região",
+			"tax_currency_mismatch":          "todos os itens de uma cotação de imposto devem usar a mesma moeda",
+			"duplicate_tax_rate":             "uma região tem uma alíquota por categoria",
+			"idempotency_key_reused":         "a chave de idempotência já foi usada com outra requisição",
+			"request_in_progress":            "uma requisição com a mesma chave de idempotência ainda está em andamento",
+			"idempotent_request_too_large":   "a requisição é grande demais para ser feita com uma chave de idempotência",
+			"job_not_cancelable":             "o job já terminou",
+			"job_has_no_file":                "o job não tem arquivo para baixar",
+			"category_cycle":                 "uma categoria não pode ficar sob ela mesma ou suas descendentes",
+			"category_has_children":          "a categoria tem subcategorias",
+		},
+	},
+}
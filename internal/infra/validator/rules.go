@@ -0,0 +1,94 @@
+package validator
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+)
+
+// rules are the custom tags available to entities besides the validator's
+// built-in ones.
+var rules = map[string]validator.Func{
+	"uuid_id":          isUUIDID,
+	"trimmed":          isTrimmed,
+	"money_positive":   isMoneyPositive,
+	"decimals":         hasMaxDecimals,
+	"positive_decimal": isPositiveDecimal,
+}
+
+func registerRules(v *validator.Validate) {
+	for tag, fn := range rules {
+		v.RegisterValidation(tag, fn)
+	}
+}
+
+// isUUIDID accepts non-nil RFC 4122 UUIDs, either typed or as strings.
+func isUUIDID(fl validator.FieldLevel) bool {
+	var id uuid.UUID
+	switch value := fl.Field().Interface().(type) {
+	case uuid.UUID:
+		id = value
+	case string:
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return false
+		}
+		id = parsed
+	default:
+		return false
+	}
+	return id != uuid.Nil && id.Variant() == uuid.RFC4122
+}
+
+func isTrimmed(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return value == strings.TrimSpace(value)
+}
+
+// isMoneyPositive accepts positive numbers and values such as money.Money
+// that report whether they are positive and, when they can, whether they
+// are within money.MaxAmount.
+func isMoneyPositive(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if amount, ok := field.Interface().(interface{ InRange() bool }); ok && !amount.InRange() {
+		return false
+	}
+	if amount, ok := field.Interface().(interface{ IsPositive() bool }); ok {
+		return amount.IsPositive()
+	}
+	switch field.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return field.Float() > 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int() > 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint() > 0
+	}
+	return false
+}
+
+// hasMaxDecimals checks that a float has at most as many decimal places as
+// the tag parameter, e.g. decimals=2.
+func hasMaxDecimals(fl validator.FieldLevel) bool {
+	max, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+	field := fl.Field()
+	if field.Kind() != reflect.Float32 && field.Kind() != reflect.Float64 {
+		return false
+	}
+	_, decimals, _ := strings.Cut(strconv.FormatFloat(field.Float(), 'f', -1, 64), ".")
+	return len(decimals) <= max
+}
+
+// isPositiveDecimal accepts strings holding a plain positive decimal number,
+// such as exchange rates.
+func isPositiveDecimal(fl validator.FieldLevel) bool {
+	_, err := money.ParseRate(fl.Field().String())
+	return err == nil
+}
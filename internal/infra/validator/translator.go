@@ -0,0 +1,144 @@
+package validator
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/pt"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	pt_translations "github.com/go-playground/validator/v10/translations/pt"
+)
+
+const DefaultLocale = "en"
+
+var uni *ut.UniversalTranslator
+
+type Translator = ut.Translator
+
+// GetTranslator picks the translator that best matches an Accept-Language
+// header, falling back to English.
+func GetTranslator(acceptLanguage string) Translator {
+	GetValidatorInstance()
+	trans, _ := uni.FindTranslator(parseAcceptLanguage(acceptLanguage)...)
+	return trans
+}
+
+// Message renders the catalog message for a rule of a resource field, used
+// for errors that do not come from the validator itself.
+func Message(trans Translator, resource, field, tag, param string) string {
+	message, err := trans.T(tag, fieldLabel(trans, resource+"."+field), param)
+	if err != nil {
+		message, _ = trans.T("invalid", fieldLabel(trans, resource+"."+field), param)
+	}
+	return message
+}
+
+// Detail renders the catalog description of a problem, such as
+// "email_taken", falling back to the English one.
+func Detail(trans Translator, key string) string {
+	detail, err := trans.T("detail." + key)
+	if err != nil {
+		return catalogs[DefaultLocale].details[key]
+	}
+	return detail
+}
+
+func registerTranslations(v *validator.Validate) {
+	english := en.New()
+	uni = ut.New(english, english, pt.New())
+
+	enTrans, _ := uni.GetTranslator("en")
+	en_translations.RegisterDefaultTranslations(v, enTrans)
+	registerCatalog(v, enTrans, catalogs["en"])
+
+	ptTrans, _ := uni.GetTranslator("pt")
+	pt_translations.RegisterDefaultTranslations(v, ptTrans)
+	registerCatalog(v, ptTrans, catalogs["pt"])
+}
+
+func registerCatalog(v *validator.Validate, trans ut.Translator, c catalog) {
+	for key, label := range c.fields {
+		trans.Add("field."+key, label, true)
+	}
+	for key, detail := range c.details {
+		trans.Add("detail."+key, detail, true)
+	}
+
+	for tag, text := range c.messages {
+		text := text
+		v.RegisterTranslation(tag, trans, func(trans ut.Translator) error {
+			return trans.Add(tag, text, true)
+		}, translateFieldError)
+	}
+}
+
+func translateFieldError(trans ut.Translator, fe validator.FieldError) string {
+	message, err := trans.T(fe.Tag(), fieldLabel(trans, strings.ToLower(fe.Namespace())), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
+
+// fieldLabel looks up the localized label of a field such as "user.name",
+// defaulting to its JSON name.
+func fieldLabel(trans ut.Translator, key string) string {
+	label, err := trans.T("field." + key)
+	if err != nil {
+		return key[strings.LastIndex(key, ".")+1:]
+	}
+	return label
+}
+
+// parseAcceptLanguage orders the locales of an Accept-Language header by
+// quality, adding the base language after each regional variant.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale  string
+		quality float64
+	}
+
+	var candidates []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if quality <= 0 {
+			continue
+		}
+		candidates = append(candidates, weighted{locale: strings.ReplaceAll(tag, "-", "_"), quality: quality})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	locales := make([]string, 0, len(candidates)*2+1)
+	seen := map[string]bool{}
+	add := func(locale string) {
+		if !seen[locale] {
+			seen[locale] = true
+			locales = append(locales, locale)
+		}
+	}
+	for _, c := range candidates {
+		add(c.locale)
+		if base, _, regional := strings.Cut(c.locale, "_"); regional {
+			add(base)
+		}
+	}
+	add(DefaultLocale)
+	return locales
+}
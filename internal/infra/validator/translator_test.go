@@ -0,0 +1,55 @@
+package validator
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"en"}, parseAcceptLanguage(""))
+	assert.Equal(t, []string{"pt_BR", "pt", "en"}, parseAcceptLanguage("pt-BR,en;q=0.5"))
+	assert.Equal(t, []string{"pt", "en_US", "en"}, parseAcceptLanguage("en-US;q=0.4, pt;q=0.8, fr;q=0"))
+}
+
+// TestGetTranslatorConcurrently must be the first test to use the validator
+// so that the concurrent calls are the ones setting it up.
+func TestGetTranslatorConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	locales := make([]string, 8)
+	for i := range locales {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			locales[i] = GetTranslator("pt-BR").Locale()
+		}(i)
+	}
+	wg.Wait()
+	for _, locale := range locales {
+		assert.Equal(t, "pt", locale)
+	}
+}
+
+func TestGetTranslator(t *testing.T) {
+	assert.Equal(t, "en", GetTranslator("").Locale())
+	assert.Equal(t, "pt", GetTranslator("pt-BR").Locale())
+	assert.Equal(t, "en", GetTranslator("de-DE,de;q=0.9").Locale())
+}
+
+func TestMessage(t *testing.T) {
+	assert.Equal(t, "name is required", Message(GetTranslator("en"), "product", "name", "required", ""))
+	assert.Equal(t, "preço deve ser maior que 0", Message(GetTranslator("pt"), "product", "price", "gt", "0"))
+	assert.Equal(t, "sku é inválido", Message(GetTranslator("pt"), "variant", "sku", "unknown", ""))
+}
+
+func TestCatalogsHaveTheSameDetails(t *testing.T) {
+	for key := range catalogs["en"].details {
+		assert.Contains(t, catalogs["pt"].details, key)
+	}
+	assert.Len(t, catalogs["pt"].details, len(catalogs["en"].details))
+
+	assert.Equal(t, "o recurso pedido não existe", Detail(GetTranslator("pt"), "not_found"))
+	assert.Equal(t, "the requested resource does not exist", Detail(GetTranslator("en"), "not_found"))
+	assert.Empty(t, Detail(GetTranslator("pt"), "unknown"))
+}
@@ -1,14 +1,42 @@
 package validator
 
-import "github.com/go-playground/validator/v10"
+import (
+	"reflect"
+	"strings"
+	"sync"
 
-var validate *validator.Validate
+	"github.com/go-playground/validator/v10"
+)
+
+var (
+	validate *validator.Validate
+	once     sync.Once
+)
 
 type Validator = *validator.Validate
 
+type ValidationErrors = validator.ValidationErrors
+
+type FieldError = validator.FieldError
+
+// GetValidatorInstance returns the shared validator, set up along with its
+// translators on first use. It is safe to call from concurrent requests.
 func GetValidatorInstance() Validator {
-	if validate == nil {
+	once.Do(func() {
 		validate = validator.New(validator.WithRequiredStructEnabled())
-	}
+		validate.RegisterTagNameFunc(jsonFieldName)
+		registerRules(validate)
+		registerTranslations(validate)
+	})
 	return validate
 }
+
+// jsonFieldName reports fields by their JSON name so that validation errors
+// match the payload the client sent instead of the Go struct field.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" || name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+)
+
+const RoleAdmin = entity.RoleAdmin
+
+// RequireAdmin lets through only requests whose verified token carries the
+// admin role. It must run after jwtauth.Verifier and jwtauth.Authenticator.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !isAdmin(req) {
+			writeDetail(w, req, http.StatusForbidden, ProblemTypeForbidden, "admin_required")
+			return
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// subject returns the user ID carried by the verified token of the request.
+func subject(req *http.Request) string {
+	_, claims, err := jwtauth.FromContext(req.Context())
+	if err != nil {
+		return ""
+	}
+	sub, _ := claims["sub"].(string)
+	return sub
+}
+
+// isAdmin tells whether the verified token of the request carries the admin
+// role.
+func isAdmin(req *http.Request) bool {
+	_, claims, err := jwtauth.FromContext(req.Context())
+	return err == nil && claims["role"] == RoleAdmin
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/tax"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
+)
+
+// CartHandler serves the cart of the authenticated user, identified by the
+// subject of their token. With Taxes set, the cart is taxed in the region
+// requested or else in TaxRegion.
+type CartHandler struct {
+	CartDB    database.CartInterface
+	ProductDB database.ProductInterface
+	VariantDB database.VariantInterface
+	CouponDB  database.CouponInterface
+	Taxes     *tax.Calculator
+	TaxRegion string
+}
+
+func NewCartHandler(carts database.CartInterface, products database.ProductInterface, variants database.VariantInterface, coupons database.CouponInterface) *CartHandler {
+	return &CartHandler{
+		CartDB:    carts,
+		ProductDB: products,
+		VariantDB: variants,
+		CouponDB:  coupons,
+	}
+}
+
+// GetCart godoc
+// @Summary 		Get the cart
+// @Description 	Get the cart of the authenticated user priced at the current product prices. Items whose price changed since they were added are flagged, the applied coupon is evaluated against the cart and taxes are quoted for the region on the prices after the coupon discount.
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Param 			region	query		string		false	"ISO 3166 tax region, defaults to the configured one"
+// @Success 		200		{object}	dto.CartOutput
+// @Failure 		422		{object}	Problem
+// @Failure 		500		{object}	Problem
+// @Router 			/cart 	[get]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) GetCart(w http.ResponseWriter, req *http.Request) {
+	handler.writeCart(w, req, http.StatusOK)
+}
+
+// AddCartItem godoc
+// @Summary 		Add an item to the cart
+// @Description 	Add a product, or one of its variants, to the cart. Adding a product already in the cart increases its quantity.
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Param 			request			body		dto.AddCartItemInput	true 	"cart item request"
+// @Success 		201				{object}	dto.CartOutput
+// @Failure 		400				{object}	Problem
+// @Failure 		404				{object}	Problem
+// @Failure 		422				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/cart/items 	[post]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) AddCartItem(w http.ResponseWriter, req *http.Request) {
+	var input dto.AddCartItemInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	product, err := handler.ProductDB.FindByID(input.ProductID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	var variant *entity.Variant
+	if input.VariantID != nil {
+		variant, err = handler.VariantDB.FindByID(*input.VariantID)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+
+	item, err := entity.NewCartItem(subject(req), product, variant, input.Quantity)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	_, err = handler.CartDB.AddItem(item)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeCart(w, req, http.StatusCreated)
+}
+
+// UpdateCartItem godoc
+// @Summary 		Update a cart item
+// @Description 	Change the quantity of a cart item. The item takes the current product price.
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Param 			itemID					path		string						true 	"cart item ID"	Format(uuid)
+// @Param 			request					body		dto.UpdateCartItemInput		true 	"cart item request"
+// @Success 		200						{object}	dto.CartOutput
+// @Failure 		400						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/cart/items/{itemID} 	[put]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) UpdateCartItem(w http.ResponseWriter, req *http.Request) {
+	item, err := handler.CartDB.FindItem(subject(req), chi.URLParam(req, "itemID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.UpdateCartItemInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	line, err := handler.line(*item)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if !line.Available() {
+		writeError(w, req, gorm.ErrRecordNotFound)
+		return
+	}
+
+	item.Quantity = input.Quantity
+	item.UnitPrice = line.CurrentPrice()
+	item.UpdatedAt = time.Now()
+	err = item.Validate()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CartDB.UpdateItem(item)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeCart(w, req, http.StatusOK)
+}
+
+// DeleteCartItem godoc
+// @Summary 		Remove a cart item
+// @Description 	Remove an item from the cart
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Param 			itemID					path		string		true 	"cart item ID"	Format(uuid)
+// @Success 		200						{object}	dto.CartOutput
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/cart/items/{itemID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) DeleteCartItem(w http.ResponseWriter, req *http.Request) {
+	err := handler.CartDB.DeleteItem(subject(req), chi.URLParam(req, "itemID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeCart(w, req, http.StatusOK)
+}
+
+// ClearCart godoc
+// @Summary 		Empty the cart
+// @Description 	Remove every item from the cart
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Success 		200
+// @Failure 		500		{object}	Problem
+// @Router 			/cart 	[delete]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) ClearCart(w http.ResponseWriter, req *http.Request) {
+	err := handler.CartDB.Clear(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AcceptCartPrices godoc
+// @Summary 		Accept the current prices
+// @Description 	Take the current product price on every cart item whose price changed, clearing the stale price flags
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Success 		200						{object}	dto.CartOutput
+// @Failure 		500						{object}	Problem
+// @Router 			/cart/accept-prices 	[post]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) AcceptCartPrices(w http.ResponseWriter, req *http.Request) {
+	lines, err := handler.lines(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	for _, line := range lines {
+		if !line.PriceChanged() {
+			continue
+		}
+		item := line.Item
+		item.UnitPrice = line.CurrentPrice()
+		item.UpdatedAt = time.Now()
+		err = handler.CartDB.UpdateItem(&item)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+
+	handler.writeCart(w, req, http.StatusOK)
+}
+
+// ApplyCoupon godoc
+// @Summary 		Apply a coupon to the cart
+// @Description 	Evaluate a coupon against the cart and, when it applies, keep it for checkout, replacing the coupon applied before. A rejected coupon lists every reason it does not apply.
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Param 			request					body		dto.ApplyCouponInput		true 	"coupon request"
+// @Success 		200						{object}	entity.CouponEvaluation
+// @Failure 		400						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/cart/apply-coupon 		[post]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) ApplyCoupon(w http.ResponseWriter, req *http.Request) {
+	var input dto.ApplyCouponInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	if entity.NormalizeCouponCode(input.Code) == "" {
+		writeError(w, req, entity.ErrCouponCodeIsRequired)
+		return
+	}
+
+	userID := subject(req)
+	lines, err := handler.lines(userID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	evaluation, err := handler.CouponDB.Evaluate(input.Code, userID, lines)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	if evaluation.Valid {
+		err = handler.CouponDB.ApplyToCart(userID, evaluation.Code)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(evaluation)
+}
+
+// RemoveCoupon godoc
+// @Summary 		Remove the coupon from the cart
+// @Description 	Remove the coupon applied to the cart
+// @Tags 			cart
+// @Accept 			json
+// @Produce 		json
+// @Success 		200					{object}	dto.CartOutput
+// @Failure 		500					{object}	Problem
+// @Router 			/cart/coupon 		[delete]
+// @Security		ApiKeyAuth
+func (handler *CartHandler) RemoveCoupon(w http.ResponseWriter, req *http.Request) {
+	err := handler.CouponDB.RemoveFromCart(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeCart(w, req, http.StatusOK)
+}
+
+func (handler *CartHandler) writeCart(w http.ResponseWriter, req *http.Request, status int) {
+	lines, err := handler.lines(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	totals, err := entity.CartTotals(lines)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	output := dto.CartOutput{
+		Items:  make([]dto.CartItemOutput, 0, len(lines)),
+		Totals: totals,
+	}
+	for _, line := range lines {
+		item := dto.CartItemOutput{
+			CartItem:     line.Item,
+			PriceChanged: line.PriceChanged(),
+			Available:    line.Available(),
+		}
+		if item.Available {
+			price := line.CurrentPrice()
+			total, err := line.Total()
+			if err != nil {
+				writeError(w, req, err)
+				return
+			}
+			item.CurrentPrice = &price
+			item.Total = &total
+		}
+		output.HasStalePrices = output.HasStalePrices || item.PriceChanged
+		output.Items = append(output.Items, item)
+	}
+
+	code, err := handler.CouponDB.FindCartCoupon(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if code != "" {
+		evaluation, err := handler.CouponDB.Evaluate(code, subject(req), lines)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		output.Coupon = &evaluation
+	}
+
+	var discounts []money.Money
+	if output.Coupon != nil && output.Coupon.Valid {
+		discounts = output.Coupon.LineDiscounts
+	}
+	output.Tax, err = handler.quoteTax(req, lines, discounts)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
+
+// quoteTax quotes the tax of the cart lines, after the coupon discounts of
+// each line, leaving it out when no region is known or the cart has nothing
+// to tax in a single currency.
+func (handler *CartHandler) quoteTax(req *http.Request, lines []entity.CartLine, discounts []money.Money) (*entity.TaxQuote, error) {
+	region := req.URL.Query().Get("region")
+	if region == "" {
+		region = handler.TaxRegion
+	}
+	if handler.Taxes == nil || region == "" {
+		return nil, nil
+	}
+
+	quote, err := handler.Taxes.Quote(region, lines, discounts)
+	if errors.Is(err, entity.ErrTaxQuoteIsEmpty) || errors.Is(err, entity.ErrTaxCurrencyMismatch) {
+		return nil, nil
+	}
+	return quote, err
+}
+
+func (handler *CartHandler) lines(userID string) ([]entity.CartLine, error) {
+	items, err := handler.CartDB.FindItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]entity.CartLine, 0, len(items))
+	for _, item := range items {
+		line, err := handler.line(item)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// line loads the product and variant of a cart item, leaving them nil when
+// they were deleted since the item was added.
+func (handler *CartHandler) line(item entity.CartItem) (entity.CartLine, error) {
+	return loadCartLine(handler.ProductDB, handler.VariantDB, item)
+}
+
+func loadCartLine(products database.ProductInterface, variants database.VariantInterface, item entity.CartItem) (entity.CartLine, error) {
+	line := entity.CartLine{Item: item}
+
+	product, err := products.FindByID(item.ProductID.String())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return line, nil
+	}
+	if err != nil {
+		return line, err
+	}
+	line.Product = product
+
+	if item.VariantID != nil {
+		variant, err := variants.FindByID(item.VariantID.String())
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return line, err
+		}
+		line.Variant = variant
+	}
+	return line, nil
+}
+
+// loadInputLines prices the requested items as cart lines of the user,
+// failing when a product or variant does not exist.
+func loadInputLines(products database.ProductInterface, variants database.VariantInterface, userID string, inputs []dto.OrderItemInput) ([]entity.CartLine, error) {
+	lines := make([]entity.CartLine, 0, len(inputs))
+	for _, input := range inputs {
+		product, err := products.FindByID(input.ProductID)
+		if err != nil {
+			return nil, err
+		}
+		var variant *entity.Variant
+		if input.VariantID != nil {
+			variant, err = variants.FindByID(*input.VariantID)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		item, err := entity.NewCartItem(userID, product, variant, input.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, entity.CartLine{Item: *item, Product: product, Variant: variant})
+	}
+	return lines, nil
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+type CategoryHandler struct {
+	CategoryDB database.CategoryInterface
+}
+
+func NewCategoryHandler(db database.CategoryInterface) *CategoryHandler {
+	return &CategoryHandler{
+		CategoryDB: db,
+	}
+}
+
+// CreateCategory godoc
+// @Summary 		Create category
+// @Description 	Create a category, nested under parent_id when one is given. Requires the admin role.
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			request			body		dto.CategoryInput	true	"category request"
+// @Success 		201				{object}	entity.Category
+// @Failure 		400 			{object}	Problem
+// @Failure 		403 			{object}	Problem
+// @Failure 		404 			{object}	Problem
+// @Failure 		500 			{object}	Problem
+// @Router 			/categories 	[post]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) CreateCategory(w http.ResponseWriter, req *http.Request) {
+	var input dto.CategoryInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	category, err := entity.NewCategory(input.Name, input.ParentID)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CategoryDB.Create(category)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(category)
+}
+
+// GetCategories godoc
+// @Summary 		Get the category tree
+// @Description 	Get all categories nested under their parents
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Success 		200				{array}		entity.Category
+// @Failure 		500 			{object}	Problem
+// @Router 			/categories 	[get]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) GetCategories(w http.ResponseWriter, req *http.Request) {
+	categories, err := handler.CategoryDB.FindAll()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	tree := entity.BuildCategoryTree(categories)
+	if tree == nil {
+		tree = []entity.Category{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tree)
+}
+
+// GetCategory godoc
+// @Summary 		Get a category
+// @Description 	Get a category with its subcategories
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id					path		string		true 	"category ID"	Format(uuid)
+// @Success 		200					{object}	entity.Category
+// @Failure 		404					{object}	Problem
+// @Failure 		500 				{object}	Problem
+// @Router 			/categories/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) GetCategory(w http.ResponseWriter, req *http.Request) {
+	category, err := handler.CategoryDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	subtree, err := handler.CategoryDB.FindSubtree(category.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	for _, root := range entity.BuildCategoryTree(subtree) {
+		if root.ID == category.ID {
+			category = &root
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+// UpdateCategory godoc
+// @Summary 		Update a category
+// @Description 	Rename a category or move it under another parent. Requires the admin role.
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id					path		string				true 	"category ID"	Format(uuid)
+// @Param 			request				body		dto.CategoryInput	true 	"category request"
+// @Success 		200					{object}	entity.Category
+// @Failure 		400					{object}	Problem
+// @Failure 		403					{object}	Problem
+// @Failure 		404					{object}	Problem
+// @Failure 		422					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/categories/{id} 	[put]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) UpdateCategory(w http.ResponseWriter, req *http.Request) {
+	category, err := handler.CategoryDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.CategoryInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	category.Name = input.Name
+	category.ParentID = input.ParentID
+
+	err = category.Validate()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CategoryDB.Update(category)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(category)
+}
+
+// DeleteCategory godoc
+// @Summary 		Delete a category
+// @Description 	Delete a category without subcategories. Its products are kept. Requires the admin role.
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id					path		string		true 	"category ID"	Format(uuid)
+// @Success 		200
+// @Failure 		403					{object}	Problem
+// @Failure 		404					{object}	Problem
+// @Failure 		409					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/categories/{id} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) DeleteCategory(w http.ResponseWriter, req *http.Request) {
+	err := handler.CategoryDB.Delete(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AssignProducts godoc
+// @Summary 		Assign products to a category
+// @Description 	Assign products to a category. Products already in the category are ignored. Requires the admin role.
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string					true 	"category ID"	Format(uuid)
+// @Param 			request						body		dto.AssignProductsInput	true 	"product IDs"
+// @Success 		204
+// @Failure 		400							{object}	Problem
+// @Failure 		403							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/categories/{id}/products 	[post]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) AssignProducts(w http.ResponseWriter, req *http.Request) {
+	var input dto.AssignProductsInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	err = handler.CategoryDB.AssignProducts(chi.URLParam(req, "id"), input.ProductIDs)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnassignProduct godoc
+// @Summary 		Remove a product from a category
+// @Description 	Remove a product from a category. Requires the admin role.
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string		true 	"category ID"	Format(uuid)
+// @Param 			productID								path		string		true 	"product ID"	Format(uuid)
+// @Success 		204
+// @Failure 		403										{object}	Problem
+// @Failure 		404										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/categories/{id}/products/{productID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) UnassignProduct(w http.ResponseWriter, req *http.Request) {
+	err := handler.CategoryDB.UnassignProduct(chi.URLParam(req, "id"), chi.URLParam(req, "productID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCategoryProducts godoc
+// @Summary 		Get the products of a category
+// @Description 	Get the products of a category, including those of its subcategories when include_descendants is true
+// @Tags 			categories
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"category ID"	Format(uuid)
+// @Param 			include_descendants			query		bool		false	"include products of subcategories"
+// @Param 			page						query		string		false	"page number"
+// @Param 			limit						query		string		false	"limit"
+// @Success 		200							{array}		entity.Product
+// @Failure 		400							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/categories/{id}/products 	[get]
+// @Security		ApiKeyAuth
+func (handler *CategoryHandler) GetCategoryProducts(w http.ResponseWriter, req *http.Request) {
+	includeDescendants := false
+	if value := req.URL.Query().Get("include_descendants"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeInvalidParam(w, req, "include_descendants", "boolean")
+			return
+		}
+		includeDescendants = parsed
+	}
+
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+
+	products, err := handler.CategoryDB.FindProducts(chi.URLParam(req, "id"), includeDescendants, page, limit)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+// CouponHandler lets admins manage the discount codes.
+type CouponHandler struct {
+	CouponDB database.CouponInterface
+}
+
+func NewCouponHandler(coupons database.CouponInterface) *CouponHandler {
+	return &CouponHandler{CouponDB: coupons}
+}
+
+// CreateCoupon godoc
+// @Summary 		Create a coupon
+// @Description 	Create a percentage or fixed amount discount code, optionally limited in time, number of uses and products or categories. Requires the admin role.
+// @Tags 			coupons
+// @Accept 			json
+// @Produce 		json
+// @Param 			request				body		dto.CouponInput		true 	"coupon request"
+// @Success 		201					{object}	entity.Coupon
+// @Failure 		400					{object}	Problem
+// @Failure 		403					{object}	Problem
+// @Failure 		409					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/admin/coupons 		[post]
+// @Security		ApiKeyAuth
+func (handler *CouponHandler) CreateCoupon(w http.ResponseWriter, req *http.Request) {
+	var input dto.CouponInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	coupon, err := entity.NewCoupon(input.Code, entity.CouponType(input.Type), input.Percent, input.Amount)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	err = applyCouponInput(coupon, input)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CouponDB.Create(coupon)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// GetCoupons godoc
+// @Summary 		List coupons
+// @Description 	List every coupon by code. Requires the admin role.
+// @Tags 			coupons
+// @Accept 			json
+// @Produce 		json
+// @Param 			page				query		string		false	"page number"
+// @Param 			limit				query		string		false	"limit"
+// @Success 		200					{array}		entity.Coupon
+// @Failure 		403					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/admin/coupons 		[get]
+// @Security		ApiKeyAuth
+func (handler *CouponHandler) GetCoupons(w http.ResponseWriter, req *http.Request) {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	coupons, err := handler.CouponDB.FindAll(page, limit)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if coupons == nil {
+		coupons = []entity.Coupon{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupons)
+}
+
+// GetCoupon godoc
+// @Summary 		Get a coupon
+// @Description 	Get a coupon with its redemption count. Requires the admin role.
+// @Tags 			coupons
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"coupon ID"	Format(uuid)
+// @Success 		200						{object}	entity.Coupon
+// @Failure 		403						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/admin/coupons/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *CouponHandler) GetCoupon(w http.ResponseWriter, req *http.Request) {
+	coupon, err := handler.CouponDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// UpdateCoupon godoc
+// @Summary 		Update a coupon
+// @Description 	Replace the settings of a coupon. Its redemption count is kept. Requires the admin role.
+// @Tags 			coupons
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string				true 	"coupon ID"	Format(uuid)
+// @Param 			request					body		dto.CouponInput		true 	"coupon request"
+// @Success 		200						{object}	entity.Coupon
+// @Failure 		400						{object}	Problem
+// @Failure 		403						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		409						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/admin/coupons/{id} 	[put]
+// @Security		ApiKeyAuth
+func (handler *CouponHandler) UpdateCoupon(w http.ResponseWriter, req *http.Request) {
+	coupon, err := handler.CouponDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.CouponInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	coupon.Code = input.Code
+	coupon.Type = entity.CouponType(input.Type)
+	coupon.Percent, coupon.Amount = 0, nil
+	if coupon.Type == entity.CouponFixed {
+		coupon.Amount = input.Amount
+	} else {
+		coupon.Percent = input.Percent
+	}
+	err = applyCouponInput(coupon, input)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CouponDB.Update(coupon)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(coupon)
+}
+
+// DeleteCoupon godoc
+// @Summary 		Delete a coupon
+// @Description 	Delete a coupon and take it off the carts it was applied to. Orders keep their discount. Requires the admin role.
+// @Tags 			coupons
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"coupon ID"	Format(uuid)
+// @Success 		200
+// @Failure 		403						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/admin/coupons/{id} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *CouponHandler) DeleteCoupon(w http.ResponseWriter, req *http.Request) {
+	coupon, err := handler.CouponDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.CouponDB.Delete(coupon)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// applyCouponInput sets the limits and restrictions of the input on the
+// coupon and validates it.
+func applyCouponInput(coupon *entity.Coupon, input dto.CouponInput) error {
+	coupon.MinOrder = input.MinOrder
+	coupon.MaxRedemptions = input.MaxRedemptions
+	coupon.MaxPerUser = input.MaxPerUser
+	coupon.StartsAt = input.StartsAt
+	coupon.EndsAt = input.EndsAt
+	coupon.ProductIDs = input.ProductIDs
+	coupon.CategoryIDs = input.CategoryIDs
+	coupon.Active = input.Active == nil || *input.Active
+	coupon.UpdatedAt = time.Now()
+	return coupon.Validate()
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+type ExchangeRateHandler struct {
+	ExchangeRateDB database.ExchangeRateInterface
+}
+
+func NewExchangeRateHandler(db database.ExchangeRateInterface) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		ExchangeRateDB: db,
+	}
+}
+
+// CreateExchangeRates godoc
+// @Summary 		Upload exchange rates
+// @Description 	Upload exchange rates as a JSON list or as a CSV file with the header base_currency,quote_currency,rate,effective_at. Rates for an existing pair and effective date are replaced.
+// @Tags 			exchange-rates
+// @Accept 			json,text/csv
+// @Produce 		json
+// @Param 			request				body		[]dto.CreateExchangeRateInput	true	"exchange rates"
+// @Success 		201					{array}		entity.ExchangeRate
+// @Failure 		400 				{object}	Problem
+// @Failure 		403 				{object}	Problem
+// @Failure 		500 				{object}	Problem
+// @Router 			/exchange-rates 	[post]
+// @Security		ApiKeyAuth
+func (handler *ExchangeRateHandler) CreateExchangeRates(w http.ResponseWriter, req *http.Request) {
+	var rates []entity.ExchangeRate
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "text/csv" {
+		imported, err := handler.ExchangeRateDB.ImportCSV(req.Body, "upload")
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		rates = imported
+	} else {
+		var input []dto.CreateExchangeRateInput
+		err := json.NewDecoder(req.Body).Decode(&input)
+		if err != nil {
+			writeMalformed(w, req, err)
+			return
+		}
+
+		for _, r := range input {
+			source := r.Source
+			if source == "" {
+				source = "upload"
+			}
+			rate, err := entity.NewExchangeRate(r.BaseCurrency, r.QuoteCurrency, r.Rate, r.EffectiveAt, source)
+			if err != nil {
+				writeError(w, req, err)
+				return
+			}
+			rates = append(rates, *rate)
+		}
+
+		err = handler.ExchangeRateDB.Save(rates)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(rates)
+}
+
+// GetExchangeRates godoc
+// @Summary 		List exchange rates
+// @Description 	List exchange rates, newest first for each currency pair
+// @Tags 			exchange-rates
+// @Accept 			json
+// @Produce 		json
+// @Param 			base				query		string	false	"base currency"
+// @Param 			quote				query		string	false	"quote currency"
+// @Success 		200					{array}		entity.ExchangeRate
+// @Failure 		500 				{object}	Problem
+// @Router 			/exchange-rates 	[get]
+// @Security		ApiKeyAuth
+func (handler *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, req *http.Request) {
+	rates, err := handler.ExchangeRateDB.FindAll(req.URL.Query().Get("base"), req.URL.Query().Get("quote"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(rates)
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long responses are kept for their
+	// idempotency key unless configured otherwise.
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// DefaultIdempotencyMaxBytes is the largest body of a request made with
+	// an idempotency key unless configured otherwise.
+	DefaultIdempotencyMaxBytes = 1 << 20
+)
+
+// IdempotentReplayedHeader marks a response replayed from an earlier
+// request made with the same idempotency key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// Idempotency makes retrying a request with the same Idempotency-Key return
+// the response of the first one instead of running it again. Keys belong to
+// the user of the request and are kept for TTL; reusing one for a different
+// request is rejected with 422. Anonymous requests are only replayed for
+// the same key and body. Their bodies are read up front, up to MaxBytes.
+// Requests without the header run as usual.
+type Idempotency struct {
+	IdempotencyDB database.IdempotencyInterface
+	TTL           time.Duration
+	MaxBytes      int64
+	Clock         clock.Clock
+}
+
+func NewIdempotency(db database.IdempotencyInterface) *Idempotency {
+	return &Idempotency{
+		IdempotencyDB: db,
+		TTL:           DefaultIdempotencyTTL,
+		MaxBytes:      DefaultIdempotencyMaxBytes,
+		Clock:         clock.Real{},
+	}
+}
+
+// Handler is the middleware. It must run after jwtauth.Verifier on
+// authenticated routes, so that keys are kept per user.
+func (m *Idempotency) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := req.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, req)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, m.MaxBytes))
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, req, entity.ErrIdempotentRequestTooLarge)
+			return
+		}
+		if err != nil {
+			writeMalformed(w, req, err)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+
+		request, err := entity.NewIdempotentRequest(subject(req), key, req.Method, req.URL.Path, body, m.Clock.Now().Add(m.TTL))
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		kept, err := m.IdempotencyDB.Begin(request)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		if kept != nil {
+			replay(w, req, request, kept)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		answered := false
+		defer func() {
+			if !answered {
+				m.release(request)
+			}
+		}()
+		next.ServeHTTP(recorder, req)
+
+		// server errors are not kept, so that the request can be retried
+		if recorder.status >= http.StatusInternalServerError {
+			return
+		}
+		err = request.Answer(recorder.status, w.Header(), recorder.body.Bytes())
+		if err == nil {
+			err = m.IdempotencyDB.Answer(request)
+		}
+		if err != nil {
+			log.Printf("keeping the response of idempotency key %s: %v", key, err)
+			return
+		}
+		answered = true
+	})
+}
+
+func (m *Idempotency) release(request *entity.IdempotentRequest) {
+	err := m.IdempotencyDB.Release(request)
+	if err != nil {
+		log.Printf("releasing idempotency key %s: %v", request.Key, err)
+	}
+}
+
+// replay answers a retried request with the response kept for its key.
+func replay(w http.ResponseWriter, req *http.Request, request, kept *entity.IdempotentRequest) {
+	switch {
+	case !kept.Matches(request):
+		writeError(w, req, entity.ErrIdempotencyKeyReused)
+	case !kept.IsAnswered():
+		writeError(w, req, entity.ErrRequestInProgress)
+	default:
+		for name, values := range kept.ResponseHeader() {
+			w.Header()[name] = values
+		}
+		w.Header().Set(IdempotentReplayedHeader, "true")
+		w.WriteHeader(kept.Status)
+		w.Write(kept.Body)
+	}
+}
+
+// responseRecorder writes the response through while keeping a copy of its
+// status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var idempotencyTokenAuth = jwtauth.New("HS256", []byte("secret"), nil)
+
+// serveIdempotent runs next behind the middleware the way the router does,
+// after the JWT of the request is verified.
+func serveIdempotent(t *testing.T, m *Idempotency, next http.HandlerFunc) func(user, key, body string) *httptest.ResponseRecorder {
+	handler := jwtauth.Verifier(idempotencyTokenAuth)(m.Handler(next))
+	return func(user, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/products", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, key)
+		if user != "" {
+			_, token, err := idempotencyTokenAuth.Encode(map[string]interface{}{"sub": user})
+			assert.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+}
+
+func createIdempotency(t *testing.T) *Idempotency {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.IdempotentRequest{}))
+	return NewIdempotency(database.NewIdempotencyDB(db))
+}
+
+// counter answers with how many times it ran.
+func counter(calls *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		*calls++
+		w.Header().Set("Location", "/products/1")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"calls":%d}`, *calls)
+	}
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	calls := 0
+	serve := serveIdempotent(t, createIdempotency(t), counter(&calls))
+
+	first := serve("user-1", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+
+	retry := serve("user-1", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, "/products/1", retry.Header().Get("Location"))
+	assert.Equal(t, `{"calls":1}`, retry.Body.String())
+
+	// keys belong to their user
+	other := serve("user-2", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, `{"calls":2}`, other.Body.String())
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyKeyReusedWithAnotherBody(t *testing.T) {
+	calls := 0
+	serve := serveIdempotent(t, createIdempotency(t), counter(&calls))
+
+	serve("user-1", "key-1", `{"name":"Pen"}`)
+	rec := serve("user-1", "key-1", `{"name":"Pencil"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+	assert.Equal(t, 1, calls)
+}
+
+func TestIdempotencyRequestInProgress(t *testing.T) {
+	var serve func(user, key, body string) *httptest.ResponseRecorder
+	var retry *httptest.ResponseRecorder
+	serve = serveIdempotent(t, createIdempotency(t), func(w http.ResponseWriter, req *http.Request) {
+		if retry == nil {
+			retry = serve("user-1", "key-1", `{"name":"Pen"}`)
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	first := serve("user-1", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Equal(t, http.StatusConflict, retry.Code)
+}
+
+func TestIdempotencyDoesNotKeepServerErrors(t *testing.T) {
+	calls := 0
+	serve := serveIdempotent(t, createIdempotency(t), func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	assert.Equal(t, http.StatusServiceUnavailable, serve("user-1", "key-1", `{"name":"Pen"}`).Code)
+	retry := serve("user-1", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Empty(t, retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyLimitsBody(t *testing.T) {
+	calls := 0
+	m := createIdempotency(t)
+	m.MaxBytes = 8
+	serve := serveIdempotent(t, m, counter(&calls))
+
+	rec := serve("user-1", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, 0, calls)
+}
+
+func TestIdempotencyKeepsAnonymousRequestsApart(t *testing.T) {
+	calls := 0
+	serve := serveIdempotent(t, createIdempotency(t), counter(&calls))
+
+	serve("", "key-1", `{"name":"Pen"}`)
+	retry := serve("", "key-1", `{"name":"Pen"}`)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, `{"calls":1}`, retry.Body.String())
+
+	// others using the same key neither see the response nor are refused
+	other := serve("", "key-1", `{"name":"Pencil"}`)
+	assert.Equal(t, http.StatusCreated, other.Code)
+	assert.Empty(t, other.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, `{"calls":2}`, other.Body.String())
+	assert.Equal(t, `{"calls":3}`, serve("user-1", "key-1", `{"name":"Pen"}`).Body.String())
+}
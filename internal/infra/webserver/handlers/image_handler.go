@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/blobstore"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/imaging"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultImageMaxBytes = 5 << 20
+	DefaultThumbnailSize = 256
+	// multipartOverhead leaves room for the multipart headers around the file.
+	multipartOverhead = 1 << 20
+)
+
+type ImageHandler struct {
+	ProductDB     database.ProductInterface
+	ImageDB       database.ImageInterface
+	Store         blobstore.BlobStore
+	MaxBytes      int64
+	ThumbnailSize int
+}
+
+func NewImageHandler(products database.ProductInterface, images database.ImageInterface, store blobstore.BlobStore) *ImageHandler {
+	return &ImageHandler{
+		ProductDB:     products,
+		ImageDB:       images,
+		Store:         store,
+		MaxBytes:      DefaultImageMaxBytes,
+		ThumbnailSize: DefaultThumbnailSize,
+	}
+}
+
+// UploadImage godoc
+// @Summary 		Upload a product image
+// @Description 	Upload a JPEG, PNG or GIF image as the "image" field of a multipart form. The type is detected from the content, a thumbnail is generated and the image is placed after the existing ones.
+// @Tags 			images
+// @Accept 			mpfd
+// @Produce 		json
+// @Param 			id						path		string		true 	"product ID"	Format(uuid)
+// @Param 			image					formData	file		true 	"image file"
+// @Success 		201						{object}	dto.ProductImageOutput
+// @Failure 		400						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		413						{object}	Problem
+// @Failure 		415						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/products/{id}/images 	[post]
+// @Security		ApiKeyAuth
+func (handler *ImageHandler) UploadImage(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	data, err := handler.readImage(w, req)
+	if errors.Is(err, entity.ErrImageTooLarge) {
+		writeError(w, req, err)
+		return
+	}
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	contentType := mimetype.Detect(data).String()
+	if _, ok := entity.ImageTypes[contentType]; !ok {
+		writeError(w, req, entity.ErrUnsupportedImageType)
+		return
+	}
+	img, err := imaging.Decode(data)
+	if err != nil {
+		writeError(w, req, entity.ErrUnsupportedImageType)
+		return
+	}
+	thumbnail, thumbnailType, err := imaging.Encode(imaging.Thumbnail(img, handler.ThumbnailSize), contentType)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	bounds := img.Bounds()
+	image, err := entity.NewProductImage(product.ID, contentType, thumbnailType, int64(len(data)), bounds.Dx(), bounds.Dy())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.Store.Put(req.Context(), image.Key, data, contentType)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	err = handler.Store.Put(req.Context(), image.ThumbnailKey, thumbnail, thumbnailType)
+	if err != nil {
+		handler.deleteBlobs(req, image)
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ImageDB.Create(image)
+	if err != nil {
+		handler.deleteBlobs(req, image)
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(handler.imageOutput(*image))
+}
+
+// GetImages godoc
+// @Summary 		List a product images
+// @Description 	List the images of a product in display order
+// @Tags 			images
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"product ID"	Format(uuid)
+// @Success 		200						{array}		dto.ProductImageOutput
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/products/{id}/images 	[get]
+// @Security		ApiKeyAuth
+func (handler *ImageHandler) GetImages(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	images, err := handler.ImageDB.FindByProduct(product.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(handler.imageOutputs(images))
+}
+
+// ReorderImages godoc
+// @Summary 		Reorder a product images
+// @Description 	Set the display order of the images of a product. image_ids must list every image of the product.
+// @Tags 			images
+// @Accept 			json
+// @Produce 		json
+// @Param 			id								path		string				true 	"product ID"	Format(uuid)
+// @Param 			request							body		dto.ImageOrderInput	true 	"image IDs in display order"
+// @Success 		200								{array}		dto.ProductImageOutput
+// @Failure 		400								{object}	Problem
+// @Failure 		404								{object}	Problem
+// @Failure 		422								{object}	Problem
+// @Failure 		500								{object}	Problem
+// @Router 			/products/{id}/images/order 	[put]
+// @Security		ApiKeyAuth
+func (handler *ImageHandler) ReorderImages(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.ImageOrderInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	images, err := handler.ImageDB.Reorder(product.ID.String(), input.ImageIDs)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(handler.imageOutputs(images))
+}
+
+// DeleteImage godoc
+// @Summary 		Delete a product image
+// @Description 	Delete a product image and its thumbnail
+// @Tags 			images
+// @Accept 			json
+// @Produce 		json
+// @Param 			id									path		string		true 	"product ID"	Format(uuid)
+// @Param 			imageID								path		string		true 	"image ID"		Format(uuid)
+// @Success 		204
+// @Failure 		404									{object}	Problem
+// @Failure 		500									{object}	Problem
+// @Router 			/products/{id}/images/{imageID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *ImageHandler) DeleteImage(w http.ResponseWriter, req *http.Request) {
+	image, err := handler.ImageDB.FindByID(chi.URLParam(req, "imageID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if image.ProductID.String() != chi.URLParam(req, "id") {
+		writeError(w, req, gorm.ErrRecordNotFound)
+		return
+	}
+
+	err = handler.ImageDB.Delete(image.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	handler.deleteBlobs(req, image)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readImage reads the uploaded file, failing with ErrImageTooLarge when it
+// is over MaxBytes.
+func (handler *ImageHandler) readImage(w http.ResponseWriter, req *http.Request) ([]byte, error) {
+	req.Body = http.MaxBytesReader(w, req.Body, handler.MaxBytes+multipartOverhead)
+
+	file, _, err := req.FormFile("image")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return nil, entity.ErrImageTooLarge
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, handler.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > handler.MaxBytes {
+		return nil, entity.ErrImageTooLarge
+	}
+	return data, nil
+}
+
+// deleteBlobs removes the blobs of an image, logging failures since the
+// image record is already gone or was never saved.
+func (handler *ImageHandler) deleteBlobs(req *http.Request, image *entity.ProductImage) {
+	for _, key := range []string{image.Key, image.ThumbnailKey} {
+		if err := handler.Store.Delete(req.Context(), key); err != nil {
+			log.Printf("deleting blob %s: %v", key, err)
+		}
+	}
+}
+
+func (handler *ImageHandler) imageOutput(image entity.ProductImage) dto.ProductImageOutput {
+	return dto.ProductImageOutput{
+		ProductImage: image,
+		URL:          handler.Store.URL(image.Key),
+		ThumbnailURL: handler.Store.URL(image.ThumbnailKey),
+	}
+}
+
+func (handler *ImageHandler) imageOutputs(images []entity.ProductImage) []dto.ProductImageOutput {
+	output := make([]dto.ProductImageOutput, len(images))
+	for i, image := range images {
+		output[i] = handler.imageOutput(image)
+	}
+	return output
+}
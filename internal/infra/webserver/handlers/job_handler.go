@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/blobstore"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// JobHandler lets users follow and cancel their background jobs and
+// download the files they produced. Admins can reach every job.
+type JobHandler struct {
+	JobDB database.JobInterface
+	Files blobstore.BlobStore
+}
+
+func NewJobHandler(db database.JobInterface, files blobstore.BlobStore) *JobHandler {
+	return &JobHandler{
+		JobDB: db,
+		Files: files,
+	}
+}
+
+// GetJob godoc
+// @Summary 		Get a job
+// @Description 	Get the status, progress and, once it succeeded, the result of a background job
+// @Tags 			jobs
+// @Produce 		json
+// @Param 			id			path		string		true 	"job ID"	Format(uuid)
+// @Success 		200			{object}	dto.JobOutput
+// @Failure 		404			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/jobs/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *JobHandler) GetJob(w http.ResponseWriter, req *http.Request) {
+	job, ok := handler.findJob(w, req)
+	if !ok {
+		return
+	}
+	writeJob(w, req, http.StatusOK, job)
+}
+
+// CancelJob godoc
+// @Summary 		Cancel a job
+// @Description 	Cancel a queued job, or ask a running one to stop, which it does at its next progress report. Work done until then is kept.
+// @Tags 			jobs
+// @Produce 		json
+// @Param 			id			path		string		true 	"job ID"	Format(uuid)
+// @Success 		200			{object}	dto.JobOutput	"the job was cancelled"
+// @Success 		202			{object}	dto.JobOutput	"the job is stopping"
+// @Failure 		404			{object}	Problem
+// @Failure 		409			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/jobs/{id} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *JobHandler) CancelJob(w http.ResponseWriter, req *http.Request) {
+	job, ok := handler.findJob(w, req)
+	if !ok {
+		return
+	}
+	job, err := handler.JobDB.Cancel(job.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !job.IsFinished() {
+		status = http.StatusAccepted
+	}
+	writeJob(w, req, status, job)
+}
+
+// GetJobFile godoc
+// @Summary 		Download the file of a job
+// @Description 	Download the file produced by a job that succeeded, such as an export
+// @Tags 			jobs
+// @Produce 		text/csv,application/x-ndjson,json
+// @Param 			id				path		string		true 	"job ID"	Format(uuid)
+// @Success 		200				{file}		file
+// @Failure 		404				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/jobs/{id}/file [get]
+// @Security		ApiKeyAuth
+func (handler *JobHandler) GetJobFile(w http.ResponseWriter, req *http.Request) {
+	job, ok := handler.findJob(w, req)
+	if !ok {
+		return
+	}
+	if job.Status != entity.JobSucceeded || job.File == "" {
+		writeError(w, req, entity.ErrJobHasNoFile)
+		return
+	}
+
+	file, err := handler.Files.Get(req.Context(), job.File)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	defer file.Close()
+
+	filename := path.Base(job.File)
+	contentType := "application/octet-stream"
+	if exporter, ok := newProductExporter(strings.TrimPrefix(path.Ext(filename), ".")); ok {
+		contentType = exporter.ContentType()
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, file)
+}
+
+// findJob loads the job of the path, answering 404 when it belongs to
+// another user.
+func (handler *JobHandler) findJob(w http.ResponseWriter, req *http.Request) (*entity.Job, bool) {
+	job, err := handler.JobDB.FindByID(chi.URLParam(req, "id"))
+	if err == nil && job.UserID != subject(req) && !isAdmin(req) {
+		err = gorm.ErrRecordNotFound
+	}
+	if err != nil {
+		writeError(w, req, err)
+		return nil, false
+	}
+	return job, true
+}
+
+// writeJob answers with the job. Accepted responses point to it with a
+// Location header.
+func writeJob(w http.ResponseWriter, req *http.Request, status int, job *entity.Job) {
+	output := dto.JobOutput{Job: *job}
+	if job.Status == entity.JobSucceeded {
+		output.Result = json.RawMessage(job.Result)
+		if job.File != "" {
+			output.FileURL = "/jobs/" + job.ID.String() + "/file"
+		}
+	}
+
+	if status == http.StatusAccepted {
+		w.Header().Set("Location", "/jobs/"+job.ID.String())
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
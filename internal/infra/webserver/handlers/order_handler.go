@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+)
+
+// OrderHandler places the orders of the authenticated user and lets admins
+// manage every order. Pending orders hold their stock for ReservationTTL.
+type OrderHandler struct {
+	OrderDB        database.OrderInterface
+	CartDB         database.CartInterface
+	ProductDB      database.ProductInterface
+	VariantDB      database.VariantInterface
+	CouponDB       database.CouponInterface
+	ReservationTTL time.Duration
+	Clock          clock.Clock
+}
+
+func NewOrderHandler(orders database.OrderInterface, carts database.CartInterface, products database.ProductInterface, variants database.VariantInterface, coupons database.CouponInterface, reservationTTL time.Duration) *OrderHandler {
+	return &OrderHandler{
+		OrderDB:        orders,
+		CartDB:         carts,
+		ProductDB:      products,
+		VariantDB:      variants,
+		CouponDB:       coupons,
+		ReservationTTL: reservationTTL,
+		Clock:          clock.Real{},
+	}
+}
+
+// CreateOrder godoc
+// @Summary 		Place an order
+// @Description 	Place an order for the listed items or, without items, for the whole cart, which is emptied. Prices are taken at the time of the order and the stock is reserved until the order is paid; a cart with changed prices must accept them first. The coupon applied to the cart is redeemed and its discount taken off the total; a coupon that no longer applies must be removed first.
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			request		body		dto.CreateOrderInput	true 	"order request"
+// @Success 		201			{object}	entity.Order
+// @Failure 		400			{object}	Problem
+// @Failure 		404			{object}	Problem
+// @Failure 		409			{object}	Problem
+// @Failure 		422			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/orders 	[post]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) CreateOrder(w http.ResponseWriter, req *http.Request) {
+	var input dto.CreateOrderInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	userID := subject(req)
+	fromCart := len(input.Items) == 0
+	var lines []entity.CartLine
+	if fromCart {
+		lines, err = handler.cartLines(userID)
+	} else {
+		lines, err = loadInputLines(handler.ProductDB, handler.VariantDB, userID, input.Items)
+	}
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	order, err := entity.NewOrder(userID, lines)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if fromCart {
+		err = handler.applyCartCoupon(order, lines)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+
+	expiresAt := handler.Clock.Now().Add(handler.ReservationTTL)
+	if fromCart {
+		err = handler.OrderDB.Checkout(order, expiresAt)
+	} else {
+		err = handler.OrderDB.Create(order, expiresAt)
+	}
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetOrders godoc
+// @Summary 		List my orders
+// @Description 	List the orders of the authenticated user, newest first
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			status		query		string		false	"order status"	Enums(pending, paid, shipped, delivered, cancelled)
+// @Param 			page		query		string		false	"page number"
+// @Param 			limit		query		string		false	"limit"
+// @Success 		200			{array}		entity.Order
+// @Failure 		400			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/orders 	[get]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) GetOrders(w http.ResponseWriter, req *http.Request) {
+	query, ok := orderQuery(w, req)
+	if !ok {
+		return
+	}
+	query.UserID = subject(req)
+	handler.writeOrders(w, req, query)
+}
+
+// GetOrder godoc
+// @Summary 		Get one of my orders
+// @Description 	Get an order of the authenticated user
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			id				path		string		true 	"order ID"	Format(uuid)
+// @Success 		200				{object}	entity.Order
+// @Failure 		404				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/orders/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) GetOrder(w http.ResponseWriter, req *http.Request) {
+	order, err := handler.OrderDB.FindUserOrder(subject(req), chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// CancelOrder godoc
+// @Summary 		Cancel one of my orders
+// @Description 	Cancel a pending order of the authenticated user, releasing its stock. Orders being paid cannot be cancelled and paid orders can only be cancelled by an admin.
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"order ID"	Format(uuid)
+// @Success 		200						{object}	entity.Order
+// @Failure 		404						{object}	Problem
+// @Failure 		409						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/orders/{id}/cancel 	[post]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) CancelOrder(w http.ResponseWriter, req *http.Request) {
+	order, err := handler.OrderDB.FindUserOrder(subject(req), chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if order.Status != entity.OrderPending {
+		writeError(w, req, entity.ErrInvalidOrderTransition)
+		return
+	}
+
+	order, err = handler.OrderDB.Transition(order.ID.String(), entity.OrderCancelled, subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetAllOrders godoc
+// @Summary 		List all orders
+// @Description 	List the orders of every user, newest first. Requires the admin role.
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			status			query		string		false	"order status"	Enums(pending, paid, shipped, delivered, cancelled)
+// @Param 			user_id			query		string		false	"user ID"
+// @Param 			page			query		string		false	"page number"
+// @Param 			limit			query		string		false	"limit"
+// @Success 		200				{array}		entity.Order
+// @Failure 		400				{object}	Problem
+// @Failure 		403				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/admin/orders 	[get]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) GetAllOrders(w http.ResponseWriter, req *http.Request) {
+	query, ok := orderQuery(w, req)
+	if !ok {
+		return
+	}
+	query.UserID = req.URL.Query().Get("user_id")
+	handler.writeOrders(w, req, query)
+}
+
+// GetAnyOrder godoc
+// @Summary 		Get an order
+// @Description 	Get the order of any user. Requires the admin role.
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			id					path		string		true 	"order ID"	Format(uuid)
+// @Success 		200					{object}	entity.Order
+// @Failure 		403					{object}	Problem
+// @Failure 		404					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/admin/orders/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) GetAnyOrder(w http.ResponseWriter, req *http.Request) {
+	order, err := handler.OrderDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+// UpdateOrderStatus godoc
+// @Summary 		Change an order status
+// @Description 	Move an order along pending → paid → shipped → delivered, or cancel it while pending or paid. Paying commits the reserved stock and cancelling gives it back. Requires the admin role.
+// @Tags 			orders
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string						true 	"order ID"	Format(uuid)
+// @Param 			request						body		dto.OrderStatusInput		true 	"status request"
+// @Success 		200							{object}	entity.Order
+// @Failure 		400							{object}	Problem
+// @Failure 		403							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		409							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/admin/orders/{id}/status 	[put]
+// @Security		ApiKeyAuth
+func (handler *OrderHandler) UpdateOrderStatus(w http.ResponseWriter, req *http.Request) {
+	var input dto.OrderStatusInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	status, err := entity.ParseOrderStatus(input.Status)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	order, err := handler.OrderDB.Transition(chi.URLParam(req, "id"), status, subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (handler *OrderHandler) writeOrders(w http.ResponseWriter, req *http.Request, query database.OrderQuery) {
+	orders, err := handler.OrderDB.Find(query)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if orders == nil {
+		orders = []entity.Order{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+// orderQuery reads the status and paging parameters shared by the order
+// lists, reporting an unknown status to the client.
+func orderQuery(w http.ResponseWriter, req *http.Request) (database.OrderQuery, bool) {
+	var query database.OrderQuery
+	if status := req.URL.Query().Get("status"); status != "" {
+		parsed, err := entity.ParseOrderStatus(status)
+		if err != nil {
+			writeInvalidParam(w, req, "status", "invalid")
+			return query, false
+		}
+		query.Status = parsed
+	}
+	query.Page, _ = strconv.Atoi(req.URL.Query().Get("page"))
+	query.Limit, _ = strconv.Atoi(req.URL.Query().Get("limit"))
+	return query, true
+}
+
+// cartLines loads the cart of the user for checkout, which needs every price
+// to be the one the user agreed to.
+func (handler *OrderHandler) cartLines(userID string) ([]entity.CartLine, error) {
+	items, err := handler.CartDB.FindItems(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]entity.CartLine, 0, len(items))
+	for _, item := range items {
+		line, err := loadCartLine(handler.ProductDB, handler.VariantDB, item)
+		if err != nil {
+			return nil, err
+		}
+		if line.PriceChanged() {
+			return nil, entity.ErrCartPricesChanged
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// applyCartCoupon takes the discount of the coupon applied to the cart of
+// the order user off the order, failing with ErrCouponRejected when the
+// coupon no longer applies.
+func (handler *OrderHandler) applyCartCoupon(order *entity.Order, lines []entity.CartLine) error {
+	code, err := handler.CouponDB.FindCartCoupon(order.UserID)
+	if err != nil || code == "" {
+		return err
+	}
+	evaluation, err := handler.CouponDB.Evaluate(code, order.UserID, lines)
+	if err != nil {
+		return err
+	}
+	return order.ApplyCoupon(evaluation)
+}
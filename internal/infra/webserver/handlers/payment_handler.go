@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/payment"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyHeader identifies a payment attempt; retrying with the same
+// key returns the first attempt instead of charging again.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+const maxWebhookBytes = 1 << 20
+
+// DefaultPaymentHold is how long a payment attempt may take before the
+// order can be paid by another attempt or cancelled.
+const DefaultPaymentHold = 5 * time.Minute
+
+// eventStatuses maps the provider webhook events to the payment status they
+// settle.
+var eventStatuses = map[payment.EventType]entity.PaymentStatus{
+	payment.EventCaptured: entity.PaymentCaptured,
+	payment.EventFailed:   entity.PaymentFailed,
+	payment.EventRefunded: entity.PaymentRefunded,
+}
+
+type PaymentHandler struct {
+	OrderDB   database.OrderInterface
+	PaymentDB database.PaymentInterface
+	Provider  payment.PaymentProvider
+	// PaymentHold is how long the order and its stock are held for a payment
+	// attempt.
+	PaymentHold time.Duration
+}
+
+func NewPaymentHandler(orders database.OrderInterface, payments database.PaymentInterface, provider payment.PaymentProvider) *PaymentHandler {
+	return &PaymentHandler{
+		OrderDB:     orders,
+		PaymentDB:   payments,
+		Provider:    provider,
+		PaymentHold: DefaultPaymentHold,
+	}
+}
+
+// PayOrder godoc
+// @Summary 		Pay one of my orders
+// @Description 	Charge a pending order of the authenticated user, which becomes paid once the payment is captured. Retrying with the same Idempotency-Key returns the first attempt; other attempts are refused while one is running. A payment captured for an order whose stock can no longer be sold is refunded. The fake provider declines the method fake_card_declined.
+// @Tags 			payments
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string					true 	"order ID"	Format(uuid)
+// @Param 			Idempotency-Key			header		string					true 	"key of the payment attempt"
+// @Param 			request					body		dto.PayOrderInput		true 	"payment request"
+// @Success 		200						{object}	entity.Payment
+// @Success 		201						{object}	entity.Payment
+// @Failure 		400						{object}	Problem
+// @Failure 		402						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		409						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/orders/{id}/payments 	[post]
+// @Security		ApiKeyAuth
+func (handler *PaymentHandler) PayOrder(w http.ResponseWriter, req *http.Request) {
+	var input dto.PayOrderInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	order, err := handler.OrderDB.FindUserOrder(subject(req), chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	key := req.Header.Get(IdempotencyKeyHeader)
+	attempt, err := handler.PaymentDB.FindByKey(order.ID.String(), key)
+	if err == nil {
+		writePayment(w, req, attempt, http.StatusOK)
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeError(w, req, err)
+		return
+	}
+
+	if order.Status != entity.OrderPending {
+		writeError(w, req, entity.ErrOrderNotPayable)
+		return
+	}
+
+	attempt, err = entity.NewPayment(order, key, handler.Provider.Name(), input.Method)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	// one attempt at a time, with the stock held until it is done
+	order, err = handler.OrderDB.StartPayment(order.ID.String(), handler.PaymentHold)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	defer handler.finishPayment(order)
+
+	result, err := handler.Provider.Authorize(req.Context(), payment.AuthorizeRequest{
+		Amount:         order.Total,
+		Method:         input.Method,
+		Reference:      order.ID.String(),
+		IdempotencyKey: order.ID.String() + ":" + key,
+	})
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	attempt.ProviderRef = result.ProviderRef
+	attempt.Status = entity.PaymentStatus(result.Status)
+
+	err = handler.PaymentDB.Create(attempt)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if attempt.Status != entity.PaymentAuthorized {
+		writePayment(w, req, attempt, http.StatusCreated)
+		return
+	}
+
+	result, err = handler.Provider.Capture(req.Context(), attempt.ProviderRef)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	settled, err := handler.PaymentDB.Settle(attempt.ID.String(), entity.PaymentStatus(result.Status), subject(req))
+	if err != nil {
+		// the money was taken but the order could not be paid
+		handler.refund(req, attempt)
+		writeError(w, req, err)
+		return
+	}
+
+	writePayment(w, req, settled, http.StatusCreated)
+}
+
+// refund gives back a captured payment whose order could not be paid and
+// records the attempt as failed.
+func (handler *PaymentHandler) refund(req *http.Request, attempt *entity.Payment) {
+	_, err := handler.Provider.Refund(req.Context(), attempt.ProviderRef)
+	if err != nil {
+		log.Printf("refunding payment %s of order %s: %v", attempt.ID, attempt.OrderID, err)
+		return
+	}
+	_, err = handler.PaymentDB.Settle(attempt.ID.String(), entity.PaymentFailed, subject(req))
+	if err != nil {
+		log.Printf("recording the refund of payment %s: %v", attempt.ID, err)
+	}
+}
+
+// finishPayment lets other attempts pay the order. Should it fail, they
+// are let in once the payment hold passes.
+func (handler *PaymentHandler) finishPayment(order *entity.Order) {
+	err := handler.OrderDB.FinishPayment(order.ID.String())
+	if err != nil {
+		log.Printf("finishing the payment of order %s: %v", order.ID, err)
+	}
+}
+
+// GetOrderPayments godoc
+// @Summary 		List the payments of one of my orders
+// @Description 	List the payment attempts of an order of the authenticated user
+// @Tags 			payments
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"order ID"	Format(uuid)
+// @Success 		200						{array}		entity.Payment
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/orders/{id}/payments 	[get]
+// @Security		ApiKeyAuth
+func (handler *PaymentHandler) GetOrderPayments(w http.ResponseWriter, req *http.Request) {
+	order, err := handler.OrderDB.FindUserOrder(subject(req), chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	payments, err := handler.PaymentDB.FindByOrder(order.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if payments == nil {
+		payments = []entity.Payment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(payments)
+}
+
+// RefundOrder godoc
+// @Summary 		Refund an order
+// @Description 	Refund the captured payment of a paid order, which is cancelled and its stock returned. Requires the admin role.
+// @Tags 			payments
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"order ID"	Format(uuid)
+// @Success 		200							{object}	entity.Payment
+// @Failure 		403							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		409							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/admin/orders/{id}/refund 	[post]
+// @Security		ApiKeyAuth
+func (handler *PaymentHandler) RefundOrder(w http.ResponseWriter, req *http.Request) {
+	order, err := handler.OrderDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if !order.CanTransition(entity.OrderCancelled) {
+		writeError(w, req, entity.ErrInvalidOrderTransition)
+		return
+	}
+
+	payments, err := handler.PaymentDB.FindByOrder(order.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	var captured *entity.Payment
+	for i := range payments {
+		if payments[i].Status == entity.PaymentCaptured {
+			captured = &payments[i]
+		}
+	}
+	if captured == nil {
+		writeError(w, req, entity.ErrPaymentNotRefundable)
+		return
+	}
+
+	result, err := handler.Provider.Refund(req.Context(), captured.ProviderRef)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	captured, err = handler.PaymentDB.Settle(captured.ID.String(), entity.PaymentStatus(result.Status), subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writePayment(w, req, captured, http.StatusOK)
+}
+
+// PaymentWebhook godoc
+// @Summary 		Receive a payment provider webhook
+// @Description 	Apply a signed payment event of the provider, moving the payment and its order along. Events are applied once; redeliveries are acknowledged and ignored.
+// @Tags 			payments
+// @Accept 			json
+// @Produce 		json
+// @Param 			request					body		payment.Event	true 	"payment event"
+// @Success 		200
+// @Failure 		400						{object}	Problem
+// @Failure 		401						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/payments/webhook 		[post]
+func (handler *PaymentHandler) PaymentWebhook(w http.ResponseWriter, req *http.Request) {
+	payload, err := io.ReadAll(io.LimitReader(req.Body, maxWebhookBytes))
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	event, err := handler.Provider.VerifyWebhook(payload, req.Header)
+	if errors.Is(err, payment.ErrInvalidSignature) {
+		writeError(w, req, err)
+		return
+	}
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	if event.ID == "" {
+		writeMalformed(w, req, errors.New("the event has no id"))
+		return
+	}
+
+	status, ok := eventStatuses[event.Type]
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = handler.PaymentDB.ApplyEvent(event.ID, string(event.Type), handler.Provider.Name(), event.ProviderRef, status)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writePayment returns a payment attempt, as a problem when it was declined.
+func writePayment(w http.ResponseWriter, req *http.Request, attempt *entity.Payment, status int) {
+	if attempt.Status == entity.PaymentDeclined {
+		writeError(w, req, entity.ErrPaymentDeclined)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(attempt)
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/payment"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// createPaymentHandler returns a handler over the fake provider and a
+// pending order of user-1 holding stock.
+func createPaymentHandler(t *testing.T) (*PaymentHandler, *entity.Order) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.Product{}, &entity.StockLevel{}, &entity.StockMovement{}, &entity.StockReservation{}, &entity.Order{}, &entity.OrderItem{}, &entity.Payment{}, &entity.PaymentEvent{}))
+
+	product, err := entity.NewProduct("Mug", money.MustParse("10.00", "USD"))
+	assert.NoError(t, err)
+	assert.NoError(t, database.NewProductDB(db).Create(product))
+	movement, err := entity.NewStockAdjustment(product.ID, 5, entity.StockRestock, "", "tester")
+	assert.NoError(t, err)
+	_, err = database.NewStockDB(db).Adjust(movement)
+	assert.NoError(t, err)
+
+	item, err := entity.NewCartItem("user-1", product, nil, 2)
+	assert.NoError(t, err)
+	order, err := entity.NewOrder("user-1", []entity.CartLine{{Item: *item, Product: product}})
+	assert.NoError(t, err)
+	orderDB := database.NewOrderDB(db)
+	assert.NoError(t, orderDB.Create(order, time.Now().Add(time.Minute)))
+
+	return NewPaymentHandler(orderDB, database.NewPaymentDB(db), payment.NewFakeProvider("secret", "")), order
+}
+
+func payOrder(t *testing.T, handler *PaymentHandler, order *entity.Order, key string) *httptest.ResponseRecorder {
+	router := chi.NewRouter()
+	router.Use(jwtauth.Verifier(idempotencyTokenAuth))
+	router.Post("/orders/{id}/payments", handler.PayOrder)
+
+	_, token, err := idempotencyTokenAuth.Encode(map[string]interface{}{"sub": order.UserID})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+order.ID.String()+"/payments", strings.NewReader(`{"method":"fake_card"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(IdempotencyKeyHeader, key)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// failingSettle fails to settle captured payments, like when the order can
+// no longer be paid.
+type failingSettle struct {
+	database.PaymentInterface
+}
+
+func (f failingSettle) Settle(id string, status entity.PaymentStatus, changedBy string) (*entity.Payment, error) {
+	if status == entity.PaymentCaptured {
+		return nil, entity.ErrReservationNotHeld
+	}
+	return f.PaymentInterface.Settle(id, status, changedBy)
+}
+
+func TestPayOrder(t *testing.T) {
+	handler, order := createPaymentHandler(t)
+
+	rec := payOrder(t, handler, order, "key-1")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	found, err := handler.OrderDB.FindByID(order.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderPaid, found.Status)
+	assert.Nil(t, found.PayingUntil)
+
+	// retries replay the attempt and new ones find the order paid
+	assert.Equal(t, http.StatusOK, payOrder(t, handler, order, "key-1").Code)
+	assert.Equal(t, http.StatusConflict, payOrder(t, handler, order, "key-2").Code)
+}
+
+func TestPayOrderWhileAnotherAttemptRuns(t *testing.T) {
+	handler, order := createPaymentHandler(t)
+	_, err := handler.OrderDB.StartPayment(order.ID.String(), time.Minute)
+	assert.NoError(t, err)
+
+	rec := payOrder(t, handler, order, "key-1")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	payments, err := handler.PaymentDB.FindByOrder(order.ID.String())
+	assert.NoError(t, err)
+	assert.Empty(t, payments)
+}
+
+func TestPayOrderRefundsWhenTheOrderCannotBePaid(t *testing.T) {
+	handler, order := createPaymentHandler(t)
+	handler.PaymentDB = failingSettle{handler.PaymentDB}
+
+	rec := payOrder(t, handler, order, "key-1")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	payments, err := handler.PaymentDB.FindByOrder(order.ID.String())
+	assert.NoError(t, err)
+	assert.Len(t, payments, 1)
+	assert.Equal(t, entity.PaymentFailed, payments[0].Status)
+	result, err := handler.Provider.Refund(context.Background(), payments[0].ProviderRef)
+	assert.NoError(t, err)
+	assert.Equal(t, payment.StatusRefunded, result.Status)
+
+	// the order can be paid again
+	found, err := handler.OrderDB.FindByID(order.ID.String())
+	assert.NoError(t, err)
+	assert.Equal(t, entity.OrderPending, found.Status)
+	assert.Nil(t, found.PayingUntil)
+}
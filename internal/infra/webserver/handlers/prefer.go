@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ReturnMinimal asks for a response without the resource it changed.
+	ReturnMinimal = "minimal"
+	// ReturnRepresentation asks for the resource in the response, which is
+	// also what is answered when no preference is given.
+	ReturnRepresentation = "representation"
+)
+
+// prefers tells whether the Prefer header of the request (RFC 7240) holds
+// the preference, such as respond-async. Parameters of a preference are
+// ignored.
+func prefers(req *http.Request, preference string) bool {
+	_, ok := preferenceValue(req, preference)
+	return ok
+}
+
+// preferenceValue returns the value of a preference of the Prefer header,
+// such as minimal for return=minimal, unquoted. ok tells whether the
+// preference was given at all.
+func preferenceValue(req *http.Request, preference string) (value string, ok bool) {
+	for _, header := range req.Header.Values("Prefer") {
+		for _, token := range strings.Split(header, ",") {
+			token, _, _ = strings.Cut(token, ";")
+			name, value, _ := strings.Cut(token, "=")
+			if strings.EqualFold(strings.TrimSpace(name), preference) {
+				return strings.Trim(strings.TrimSpace(value), `"`), true
+			}
+		}
+	}
+	return "", false
+}
+
+// writeRepresentation answers with the resource a request created or
+// changed, pointing to it with a Location header when location is set.
+// With Prefer: return=minimal the body is left out, and a 200 becomes a 204.
+func writeRepresentation(w http.ResponseWriter, req *http.Request, status int, location string, representation any) {
+	if location != "" {
+		w.Header().Set("Location", location)
+	}
+	w.Header().Add("Vary", "Prefer")
+
+	value, _ := preferenceValue(req, "return")
+	switch strings.ToLower(value) {
+	case ReturnMinimal:
+		w.Header().Set("Preference-Applied", "return="+ReturnMinimal)
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return
+	case ReturnRepresentation:
+		w.Header().Set("Preference-Applied", "return="+ReturnRepresentation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(representation)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func preferRequest(prefer ...string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/products", nil)
+	for _, value := range prefer {
+		req.Header.Add("Prefer", value)
+	}
+	return req
+}
+
+func TestPreferenceValue(t *testing.T) {
+	value, ok := preferenceValue(preferRequest(`respond-async, return="minimal"; foo=bar`), "return")
+	assert.True(t, ok)
+	assert.Equal(t, ReturnMinimal, value)
+
+	value, ok = preferenceValue(preferRequest("wait=10", "Respond-Async"), "respond-async")
+	assert.True(t, ok)
+	assert.Empty(t, value)
+
+	_, ok = preferenceValue(preferRequest(), "return")
+	assert.False(t, ok)
+}
+
+func TestWriteRepresentation(t *testing.T) {
+	product := map[string]string{"name": "Pen"}
+
+	rec := httptest.NewRecorder()
+	writeRepresentation(rec, preferRequest(), http.StatusCreated, "/products/1", product)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/products/1", rec.Header().Get("Location"))
+	assert.Equal(t, "Prefer", rec.Header().Get("Vary"))
+	assert.Empty(t, rec.Header().Get("Preference-Applied"))
+	assert.JSONEq(t, `{"name":"Pen"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	writeRepresentation(rec, preferRequest("return=minimal"), http.StatusOK, "", product)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "Prefer", rec.Header().Get("Vary"))
+	assert.Equal(t, "return=minimal", rec.Header().Get("Preference-Applied"))
+	assert.Empty(t, rec.Header().Get("Location"))
+	assert.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	writeRepresentation(rec, preferRequest("return=minimal"), http.StatusCreated, "/products/1", product)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "/products/1", rec.Header().Get("Location"))
+	assert.Empty(t, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	writeRepresentation(rec, preferRequest("return=representation"), http.StatusOK, "", product)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "return=representation", rec.Header().Get("Preference-Applied"))
+	assert.JSONEq(t, `{"name":"Pen"}`, rec.Body.String())
+
+	// an unknown value is ignored
+	rec = httptest.NewRecorder()
+	writeRepresentation(rec, preferRequest("return=everything"), http.StatusOK, "", product)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Preference-Applied"))
+	assert.JSONEq(t, `{"name":"Pen"}`, rec.Body.String())
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/payment"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
+)
+
+const (
+	ProblemContentType = "application/problem+json"
+
+	ProblemTypeValidation     = "/problems/validation-error"
+	ProblemTypeMalformed      = "/problems/malformed-request"
+	ProblemTypeNotFound       = "/problems/not-found"
+	ProblemTypeConflict       = "/problems/conflict"
+	ProblemTypeUnauthorized   = "/problems/unauthorized"
+	ProblemTypeForbidden      = "/problems/forbidden"
+	ProblemTypeUnprocessable  = "/problems/unprocessable-entity"
+	ProblemTypeTooLarge       = "/problems/payload-too-large"
+	ProblemTypePayment        = "/problems/payment-declined"
+	ProblemTypeUnsupported    = "/problems/unsupported-media-type"
+	ProblemTypeDependency     = "/problems/failed-dependency"
+	ProblemTypeInternalServer = "/problems/internal-server-error"
+)
+
+// Problem is an RFC 7807 problem details document.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a single invalid field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type entityError struct {
+	resource string
+	field    string
+	code     string
+	param    string
+}
+
+// entityErrors maps the entity sentinels to the field and rule they refer to.
+var entityErrors = map[error]entityError{
+	entity.ErrIDIsRequired:    {resource: "product", field: "id", code: "required"},
+	entity.ErrInvalidID:       {resource: "product", field: "id", code: "uuid"},
+	entity.ErrNameIsRequired:  {resource: "product", field: "name", code: "required"},
+	entity.ErrInvalidName:     {resource: "product", field: "name", code: "invalid"},
+	entity.ErrPriceIsRequired: {resource: "product", field: "price", code: "required"},
+	entity.ErrInvalidPrice:    {resource: "product", field: "price", code: "money_positive"},
+	entity.ErrInvalidCurrency: {resource: "product", field: "price.currency", code: "iso4217"},
+
+	entity.ErrInvalidBaseCurrency:   {resource: "exchangerate", field: "base_currency", code: "iso4217"},
+	entity.ErrInvalidQuoteCurrency:  {resource: "exchangerate", field: "quote_currency", code: "iso4217"},
+	entity.ErrInvalidRate:           {resource: "exchangerate", field: "rate", code: "positive_decimal"},
+	entity.ErrEffectiveAtIsRequired: {resource: "exchangerate", field: "effective_at", code: "required"},
+
+	entity.ErrInvalidTag: {resource: "product", field: "tags", code: "invalid"},
+
+	entity.ErrSKUIsRequired:        {resource: "variant", field: "sku", code: "required"},
+	entity.ErrInvalidSKU:           {resource: "variant", field: "sku", code: "invalid"},
+	entity.ErrInvalidVariantOption: {resource: "variant", field: "options", code: "invalid"},
+	entity.ErrInvalidStock:         {resource: "variant", field: "stock", code: "gte", param: "0"},
+	entity.ErrVariantCurrency:      {resource: "variant", field: "price.currency", code: "eqfield", param: "product currency"},
+
+	entity.ErrInvalidQuantity:    {resource: "stock", field: "quantity", code: "invalid"},
+	entity.ErrInvalidStockReason: {resource: "stock", field: "reason", code: "oneof", param: "restock return damaged lost correction"},
+	entity.ErrInvalidStockNote:   {resource: "stock", field: "note", code: "max", param: "255"},
+
+	entity.ErrInvalidCartQuantity: {resource: "cart", field: "quantity", code: "invalid"},
+
+	entity.ErrOrderIsEmpty:       {resource: "order", field: "items", code: "required"},
+	entity.ErrInvalidOrderTotal:  {resource: "order", field: "total", code: "money_positive"},
+	entity.ErrInvalidOrderStatus: {resource: "order", field: "status", code: "oneof", param: "pending paid shipped delivered cancelled"},
+
+	entity.ErrInvalidRating:       {resource: "review", field: "rating", code: "oneof", param: "1 2 3 4 5"},
+	entity.ErrInvalidReviewText:   {resource: "review", field: "text", code: "max", param: "2000"},
+	entity.ErrInvalidReviewStatus: {resource: "review", field: "status", code: "oneof", param: "published hidden"},
+
+	entity.ErrWishlistNameIsRequired: {resource: "wishlist", field: "name", code: "required"},
+	entity.ErrInvalidWishlistName:    {resource: "wishlist", field: "name", code: "invalid"},
+
+	entity.ErrInvalidIdempotencyKey: {resource: "payment", field: "idempotency_key", code: "invalid"},
+
+	entity.ErrCouponCodeIsRequired:  {resource: "coupon", field: "code", code: "required"},
+	entity.ErrInvalidCouponCode:     {resource: "coupon", field: "code", code: "invalid"},
+	entity.ErrInvalidCouponType:     {resource: "coupon", field: "type", code: "oneof", param: "percentage fixed"},
+	entity.ErrInvalidCouponPercent:  {resource: "coupon", field: "percent", code: "invalid"},
+	entity.ErrInvalidCouponAmount:   {resource: "coupon", field: "amount", code: "money_positive"},
+	entity.ErrInvalidCouponMinOrder: {resource: "coupon", field: "min_order", code: "money_positive"},
+	entity.ErrInvalidCouponLimit:    {resource: "coupon", field: "max_redemptions", code: "gte", param: "0"},
+	entity.ErrInvalidCouponWindow:   {resource: "coupon", field: "ends_at", code: "gtfield", param: "starts_at"},
+	entity.ErrInvalidCouponProduct:  {resource: "coupon", field: "product_ids", code: "uuid"},
+	entity.ErrInvalidCouponCategory: {resource: "coupon", field: "category_ids", code: "uuid"},
+
+	entity.ErrInvalidTaxRegion:     {resource: "tax", field: "region", code: "invalid"},
+	entity.ErrInvalidTaxRegionName: {resource: "tax", field: "name", code: "max", param: "120"},
+	entity.ErrInvalidTaxRounding:   {resource: "tax", field: "rounding", code: "oneof", param: "line order"},
+	entity.ErrInvalidTaxRate:       {resource: "tax", field: "rate", code: "invalid"},
+	entity.ErrTaxQuoteIsEmpty:      {resource: "tax", field: "items", code: "required"},
+
+	entity.ErrCategoryNameIsRequired: {resource: "category", field: "name", code: "required"},
+	entity.ErrInvalidCategoryName:    {resource: "category", field: "name", code: "invalid"},
+
+	entity.ErrStartsAtIsRequired:     {resource: "scheduledprice", field: "starts_at", code: "required"},
+	entity.ErrInvalidEndsAt:          {resource: "scheduledprice", field: "ends_at", code: "gtfield", param: "starts_at"},
+	entity.ErrScheduledPriceCurrency: {resource: "scheduledprice", field: "price.currency", code: "eqfield", param: "product currency"},
+
+	database.ErrInvalidBatchOperation: {resource: "batch", field: "op", code: "oneof", param: "create update delete"},
+	database.ErrBatchProductRequired:  {resource: "batch", field: "product", code: "required"},
+}
+
+func NewProblem(status int, problemType, detail string) Problem {
+	return Problem{
+		Type:   problemType,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+type statusError struct {
+	status      int
+	problemType string
+	// detail is the catalog key of the description of the problem.
+	detail string
+}
+
+// statusErrors maps domain errors that are not about a single field.
+var statusErrors = map[error]statusError{
+	entity.ErrExchangeRateNotFound: {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "exchange_rate_not_found"},
+	money.ErrAmountOverflow:        {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "amount_overflow"},
+	database.ErrMalformedCSV:       {status: http.StatusBadRequest, problemType: ProblemTypeMalformed, detail: "malformed_csv"},
+	database.ErrMalformedNDJSON:    {status: http.StatusBadRequest, problemType: ProblemTypeMalformed, detail: "malformed_ndjson"},
+	database.ErrUnsupportedImport:  {status: http.StatusUnsupportedMediaType, problemType: ProblemTypeUnsupported, detail: "unsupported_import"},
+	database.ErrImportTooLarge:     {status: http.StatusRequestEntityTooLarge, problemType: ProblemTypeTooLarge, detail: "import_too_large"},
+	database.ErrBatchAborted:       {status: http.StatusFailedDependency, problemType: ProblemTypeDependency, detail: "batch_aborted"},
+	entity.ErrPriceNotFound:        {status: http.StatusNotFound, problemType: ProblemTypeNotFound, detail: "price_not_found"},
+	entity.ErrCurrencyLocked:       {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "currency_locked"},
+
+	entity.ErrScheduledPriceNotCancelable: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "scheduled_price_not_cancelable"},
+
+	entity.ErrSKUAlreadyExists: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "sku_taken"},
+
+	entity.ErrImageTooLarge:        {status: http.StatusRequestEntityTooLarge, problemType: ProblemTypeTooLarge, detail: "image_too_large"},
+	entity.ErrUnsupportedImageType: {status: http.StatusUnsupportedMediaType, problemType: ProblemTypeUnsupported, detail: "unsupported_image"},
+	entity.ErrInvalidImageOrder:    {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "invalid_image_order"},
+
+	entity.ErrVariantNotInProduct: {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "variant_not_in_product"},
+
+	entity.ErrOrderCurrencyMismatch:  {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "order_currency_mismatch"},
+	entity.ErrInvalidOrderTransition: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "invalid_order_transition"},
+	entity.ErrCartItemUnavailable:    {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "cart_item_unavailable"},
+	entity.ErrCartPricesChanged:      {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "cart_prices_changed"},
+
+	entity.ErrEmailAlreadyExists: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "email_taken"},
+
+	entity.ErrCouponAlreadyExists: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "coupon_taken"},
+	entity.ErrCouponRejected:      {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "coupon_rejected"},
+
+	entity.ErrReviewAlreadyExists: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "review_taken"},
+	entity.ErrNotReviewAuthor:     {status: http.StatusForbidden, problemType: ProblemTypeForbidden, detail: "not_review_author"},
+
+	entity.ErrPaymentDeclined:      {status: http.StatusPaymentRequired, problemType: ProblemTypePayment, detail: "payment_declined"},
+	entity.ErrOrderNotPayable:      {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "order_not_payable"},
+	entity.ErrPaymentInProgress:    {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "payment_in_progress"},
+	entity.ErrPaymentNotRefundable: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "payment_not_refundable"},
+	payment.ErrInvalidSignature:    {status: http.StatusUnauthorized, problemType: ProblemTypeUnauthorized, detail: "invalid_signature"},
+	payment.ErrInvalidState:        {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "invalid_payment_state"},
+	payment.ErrNotFound:            {status: http.StatusNotFound, problemType: ProblemTypeNotFound, detail: "payment_not_found"},
+
+	entity.ErrInsufficientStock:  {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "insufficient_stock"},
+	entity.ErrReservationNotHeld: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "reservation_not_held"},
+
+	entity.ErrTaxRegionNotFound:   {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "tax_region_not_found"},
+	entity.ErrTaxCurrencyMismatch: {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "tax_currency_mismatch"},
+	entity.ErrDuplicateTaxRate:    {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "duplicate_tax_rate"},
+
+	entity.ErrIdempotencyKeyReused:      {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "idempotency_key_reused"},
+	entity.ErrRequestInProgress:         {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "request_in_progress"},
+	entity.ErrIdempotentRequestTooLarge: {status: http.StatusRequestEntityTooLarge, problemType: ProblemTypeTooLarge, detail: "idempotent_request_too_large"},
+
+	entity.ErrJobNotCancelable: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "job_not_cancelable"},
+	entity.ErrJobHasNoFile:     {status: http.StatusNotFound, problemType: ProblemTypeNotFound, detail: "job_has_no_file"},
+
+	entity.ErrCategoryCycle:       {status: http.StatusUnprocessableEntity, problemType: ProblemTypeUnprocessable, detail: "category_cycle"},
+	entity.ErrCategoryHasChildren: {status: http.StatusConflict, problemType: ProblemTypeConflict, detail: "category_has_children"},
+}
+
+// ProblemFromError maps validation, entity and repository errors to the
+// problem returned to the client, with its detail and field messages in the
+// translator's locale. The text of the errors is never sent, so that any
+// context they were wrapped with does not leak.
+func ProblemFromError(err error, trans validator.Translator) Problem {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		problem := NewProblem(http.StatusBadRequest, ProblemTypeValidation, validator.Detail(trans, "invalid_fields"))
+		for _, fe := range validationErrors {
+			problem.Errors = append(problem.Errors, FieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fe.Translate(trans),
+			})
+		}
+		return problem
+	}
+
+	for sentinel, e := range entityErrors {
+		if errors.Is(err, sentinel) {
+			problem := NewProblem(http.StatusBadRequest, ProblemTypeValidation, validator.Detail(trans, "invalid_fields"))
+			problem.Errors = []FieldError{{
+				Field:   e.field,
+				Code:    e.code,
+				Message: validator.Message(trans, e.resource, e.field, e.code, e.param),
+			}}
+			return problem
+		}
+	}
+
+	for sentinel, e := range statusErrors {
+		if errors.Is(err, sentinel) {
+			return NewProblem(e.status, e.problemType, validator.Detail(trans, e.detail))
+		}
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return NewProblem(http.StatusNotFound, ProblemTypeNotFound, validator.Detail(trans, "not_found"))
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return NewProblem(http.StatusConflict, ProblemTypeConflict, validator.Detail(trans, "already_exists"))
+	}
+
+	return NewProblem(http.StatusInternalServerError, ProblemTypeInternalServer, validator.Detail(trans, "unexpected"))
+}
+
+func writeProblem(w http.ResponseWriter, req *http.Request, problem Problem) {
+	problem.Instance = req.URL.Path
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
+
+func writeError(w http.ResponseWriter, req *http.Request, err error) {
+	trans := requestTranslator(req)
+	w.Header().Set("Content-Language", trans.Locale())
+	writeProblem(w, req, ProblemFromError(err, trans))
+}
+
+func requestTranslator(req *http.Request) validator.Translator {
+	return validator.GetTranslator(req.Header.Get("Accept-Language"))
+}
+
+// writeDetail reports a problem described by the catalog detail key.
+func writeDetail(w http.ResponseWriter, req *http.Request, status int, problemType, detail string) {
+	trans := requestTranslator(req)
+	w.Header().Set("Content-Language", trans.Locale())
+	writeProblem(w, req, NewProblem(status, problemType, validator.Detail(trans, detail)))
+}
+
+// writeInvalidParam reports a query or path parameter that failed the rule
+// identified by code.
+func writeInvalidParam(w http.ResponseWriter, req *http.Request, param, code string) {
+	trans := requestTranslator(req)
+	w.Header().Set("Content-Language", trans.Locale())
+	problem := NewProblem(http.StatusBadRequest, ProblemTypeValidation, validator.Detail(trans, "invalid_params"))
+	problem.Errors = []FieldError{{
+		Field:   param,
+		Code:    code,
+		Message: validator.Message(trans, "param", param, code, ""),
+	}}
+	writeProblem(w, req, problem)
+}
+
+// writeMalformed reports a request body that could not be decoded.
+func writeMalformed(w http.ResponseWriter, req *http.Request, err error) {
+	writeProblem(w, req, NewProblem(http.StatusBadRequest, ProblemTypeMalformed, err.Error()))
+}
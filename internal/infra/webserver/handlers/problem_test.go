@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestProblemFromValidationErrors(t *testing.T) {
+	_, err := entity.NewUser("", "chandelier.pipo@gmail.com", "")
+	assert.Error(t, err)
+
+	problem := ProblemFromError(err, validator.GetTranslator(""))
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, ProblemTypeValidation, problem.Type)
+	assert.Len(t, problem.Errors, 2)
+	assert.Equal(t, "name", problem.Errors[0].Field)
+	assert.Equal(t, "required", problem.Errors[0].Code)
+	assert.Equal(t, "name is required", problem.Errors[0].Message)
+	assert.Equal(t, "password", problem.Errors[1].Field)
+}
+
+func TestProblemMessagesInPortuguese(t *testing.T) {
+	_, err := entity.NewUser("", "chandelier.pipo@gmail.com", "123321")
+	assert.Error(t, err)
+
+	problem := ProblemFromError(err, validator.GetTranslator("pt-BR,pt;q=0.9,en;q=0.8"))
+	assert.Equal(t, "nome é obrigatório", problem.Errors[0].Message)
+
+	problem = ProblemFromError(entity.ErrPriceIsRequired, validator.GetTranslator("pt"))
+	assert.Equal(t, "preço é obrigatório", problem.Errors[0].Message)
+}
+
+func TestProblemFromEntityError(t *testing.T) {
+	problem := ProblemFromError(fmt.Errorf("creating product: %w", entity.ErrInvalidPrice), validator.GetTranslator("en-US"))
+	assert.Equal(t, http.StatusBadRequest, problem.Status)
+	assert.Equal(t, []FieldError{{Field: "price", Code: "money_positive", Message: "price must be a positive amount"}}, problem.Errors)
+}
+
+func TestProblemFromRepositoryErrors(t *testing.T) {
+	trans := validator.GetTranslator("")
+	assert.Equal(t, http.StatusNotFound, ProblemFromError(gorm.ErrRecordNotFound, trans).Status)
+	assert.Equal(t, http.StatusConflict, ProblemFromError(gorm.ErrDuplicatedKey, trans).Status)
+
+	problem := ProblemFromError(fmt.Errorf("no such table: products"), trans)
+	assert.Equal(t, http.StatusInternalServerError, problem.Status)
+	assert.NotContains(t, problem.Detail, "products")
+}
+
+func TestProblemFromOverflow(t *testing.T) {
+	problem := ProblemFromError(fmt.Errorf("converting price: %w", money.ErrAmountOverflow), validator.GetTranslator(""))
+	assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+	assert.Equal(t, ProblemTypeUnprocessable, problem.Type)
+}
+
+func TestProblemDetailIsTranslated(t *testing.T) {
+	err := fmt.Errorf("creating user mrs.pipo@gmail.com: %w", entity.ErrEmailAlreadyExists)
+
+	problem := ProblemFromError(err, validator.GetTranslator("pt-BR"))
+	assert.Equal(t, http.StatusConflict, problem.Status)
+	assert.Equal(t, "outro usuário já tem esse e-mail", problem.Detail)
+
+	problem = ProblemFromError(err, validator.GetTranslator("en"))
+	assert.Equal(t, "another user already has this email", problem.Detail)
+	assert.NotContains(t, problem.Detail, "mrs.pipo")
+}
+
+func TestStatusErrorsHaveDetails(t *testing.T) {
+	for sentinel, e := range statusErrors {
+		assert.NotEmpty(t, validator.Detail(validator.GetTranslator("en"), e.detail), sentinel.Error())
+		assert.NotEmpty(t, validator.Detail(validator.GetTranslator("pt"), e.detail), sentinel.Error())
+	}
+}
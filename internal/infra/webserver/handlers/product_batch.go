@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+// DefaultBatchLimit is the most operations a product batch may have unless
+// configured otherwise.
+const DefaultBatchLimit = 100
+
+// BatchOutput reports the outcome of each operation of a batch, in order.
+type BatchOutput struct {
+	Atomic  bool          `json:"atomic"`
+	Results []BatchResult `json:"results"`
+}
+
+// BatchResult is the outcome of one operation: the status it would have had
+// as a request of its own and, when it failed, the reason why.
+type BatchResult struct {
+	Index   int      `json:"index"`
+	Op      string   `json:"op"`
+	Status  int      `json:"status"`
+	ID      string   `json:"id,omitempty" format:"uuid"`
+	Problem *Problem `json:"problem,omitempty"`
+}
+
+// BatchProducts godoc
+// @Summary 		Create, update and delete products in a batch
+// @Description 	Apply up to the configured limit of operations in order, validated like the single product endpoints. Each one gets the status it would have had as a request of its own. Atomic batches are applied all or nothing: when an operation fails none is, the response has the status of the failing one and the others fail with 424.
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			request				body		dto.ProductBatchInput	true	"batch request"
+// @Success 		200					{object}	BatchOutput
+// @Failure 		400					{object}	BatchOutput	"an operation of an atomic batch is invalid; a malformed, empty or too long batch gets a Problem instead"
+// @Failure 		404,409,422,424		{object}	BatchOutput	"an operation of an atomic batch failed"
+// @Failure 		500					{object}	BatchOutput	"an operation of an atomic batch could not be saved"
+// @Router 			/products/batch 	[post]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) BatchProducts(w http.ResponseWriter, req *http.Request) {
+	var input dto.ProductBatchInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	if len(input.Operations) == 0 {
+		writeInvalidBatch(w, req, "required", "")
+		return
+	}
+	if len(input.Operations) > handler.BatchLimit {
+		writeInvalidBatch(w, req, "max_items", strconv.Itoa(handler.BatchLimit))
+		return
+	}
+
+	output := BatchOutput{Atomic: input.Atomic, Results: make([]BatchResult, len(input.Operations))}
+	errs := make([]error, len(input.Operations))
+	var ops []database.ProductOperation
+	var indexes []int
+	for i, operation := range input.Operations {
+		op, err := batchOperation(operation)
+		output.Results[i] = BatchResult{Index: i, Op: operation.Op, ID: op.ID}
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		ops = append(ops, op)
+		indexes = append(indexes, i)
+	}
+
+	switch {
+	case input.Atomic && len(ops) < len(input.Operations):
+		for _, i := range indexes {
+			errs[i] = database.ErrBatchAborted
+		}
+	case len(ops) > 0:
+		for j, err := range handler.ProductDB.Batch(ops, input.Atomic, subject(req)) {
+			errs[indexes[j]] = err
+		}
+	}
+
+	trans := requestTranslator(req)
+	status := http.StatusOK
+	for i, err := range errs {
+		result := &output.Results[i]
+		if err == nil {
+			result.Status = http.StatusOK
+			if result.Op == database.OpCreate {
+				result.Status = http.StatusCreated
+			}
+			continue
+		}
+		if result.Op == database.OpCreate {
+			result.ID = ""
+		}
+		problem := ProblemFromError(err, trans)
+		problem.Instance = req.URL.Path
+		result.Status, result.Problem = problem.Status, &problem
+		if input.Atomic && status == http.StatusOK && !errors.Is(err, database.ErrBatchAborted) {
+			status = problem.Status
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", trans.Locale())
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(output)
+}
+
+// batchOperation validates an operation of a batch the way the single
+// product endpoints validate their requests.
+func batchOperation(input dto.ProductOperationInput) (database.ProductOperation, error) {
+	op := database.ProductOperation{Op: input.Op, ID: input.ID}
+	switch input.Op {
+	case database.OpCreate:
+		if input.Product == nil {
+			return op, database.ErrBatchProductRequired
+		}
+		product, err := entity.NewProduct(input.Product.Name, input.Product.Price)
+		if err != nil {
+			return op, err
+		}
+		op.ID, op.Product = product.ID.String(), product
+	case database.OpUpdate:
+		if input.Product == nil {
+			return op, database.ErrBatchProductRequired
+		}
+		product, err := updatedProduct(input.ID, entity.Product{Name: input.Product.Name, Price: input.Product.Price})
+		if err != nil {
+			return op, err
+		}
+		op.Product = product
+	case database.OpDelete:
+		if input.ID == "" {
+			return op, entity.ErrIDIsRequired
+		}
+		_, err := entityPkg.ParseID(input.ID)
+		if err != nil {
+			return op, entity.ErrInvalidID
+		}
+	default:
+		return op, database.ErrInvalidBatchOperation
+	}
+	return op, nil
+}
+
+// writeInvalidBatch reports a list of operations that failed the rule
+// identified by code.
+func writeInvalidBatch(w http.ResponseWriter, req *http.Request, code, param string) {
+	trans := requestTranslator(req)
+	w.Header().Set("Content-Language", trans.Locale())
+	problem := NewProblem(http.StatusBadRequest, ProblemTypeValidation, validator.Detail(trans, "invalid_fields"))
+	problem.Errors = []FieldError{{
+		Field:   "operations",
+		Code:    code,
+		Message: validator.Message(trans, "batch", "operations", code, param),
+	}}
+	writeProblem(w, req, problem)
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+)
+
+// exportBatchSize is how many products an export reads from the cursor
+// before resolving their tags and prices.
+const exportBatchSize = 500
+
+// productExporter writes the products of an export in one of its formats.
+type productExporter interface {
+	ContentType() string
+	Extension() string
+	Begin(w io.Writer) error
+	Write(product dto.ProductOutput) error
+	// Flush writes out what the exporter buffered, at the end of a batch.
+	Flush() error
+	End() error
+}
+
+func newProductExporter(format string) (productExporter, bool) {
+	switch format {
+	case "csv":
+		return &csvExporter{}, true
+	case "ndjson":
+		return &ndjsonExporter{}, true
+	case "json":
+		return &jsonExporter{}, true
+	}
+	return nil, false
+}
+
+// csvExporter writes a header row and then a row per product, with the tags
+// comma separated in a single column.
+type csvExporter struct {
+	writer *csv.Writer
+}
+
+var csvExportHeader = []string{
+	"id", "name", "price", "currency", "effective_price", "converted_price", "converted_currency",
+	"tags", "rating_average", "rating_count", "created_at",
+}
+
+func (e *csvExporter) ContentType() string { return "text/csv" }
+
+func (e *csvExporter) Extension() string { return "csv" }
+
+func (e *csvExporter) Begin(w io.Writer) error {
+	e.writer = csv.NewWriter(w)
+	return e.writer.Write(csvExportHeader)
+}
+
+func (e *csvExporter) Write(product dto.ProductOutput) error {
+	var convertedPrice, convertedCurrency string
+	if product.ConvertedPrice != nil {
+		convertedPrice = product.ConvertedPrice.Price.Decimal()
+		convertedCurrency = product.ConvertedPrice.Price.Currency
+	}
+	return e.writer.Write([]string{
+		product.ID.String(),
+		product.Name,
+		product.Price.Decimal(),
+		product.Price.Currency,
+		product.EffectivePrice.Decimal(),
+		convertedPrice,
+		convertedCurrency,
+		strings.Join(product.Tags, ","),
+		strconv.FormatFloat(product.RatingAverage, 'f', -1, 64),
+		strconv.FormatInt(product.RatingCount, 10),
+		product.CreatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (e *csvExporter) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+func (e *csvExporter) End() error {
+	return e.Flush()
+}
+
+// ndjsonExporter writes each product as a JSON object on its own line.
+type ndjsonExporter struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExporter) ContentType() string { return "application/x-ndjson" }
+
+func (e *ndjsonExporter) Extension() string { return "ndjson" }
+
+func (e *ndjsonExporter) Begin(w io.Writer) error {
+	e.encoder = json.NewEncoder(w)
+	return nil
+}
+
+func (e *ndjsonExporter) Write(product dto.ProductOutput) error {
+	return e.encoder.Encode(product)
+}
+
+func (e *ndjsonExporter) Flush() error { return nil }
+
+func (e *ndjsonExporter) End() error { return nil }
+
+// jsonExporter writes a single JSON array, one element at a time.
+type jsonExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonExporter) ContentType() string { return "application/json" }
+
+func (e *jsonExporter) Extension() string { return "json" }
+
+func (e *jsonExporter) Begin(w io.Writer) error {
+	e.w = w
+	_, err := io.WriteString(w, "[")
+	return err
+}
+
+func (e *jsonExporter) Write(product dto.ProductOutput) error {
+	if e.count > 0 {
+		_, err := io.WriteString(e.w, ",")
+		if err != nil {
+			return err
+		}
+	}
+	e.count++
+	data, err := json.Marshal(product)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonExporter) Flush() error { return nil }
+
+func (e *jsonExporter) End() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
@@ -2,61 +2,158 @@ package handlers
 
 import (
 	"encoding/json"
+	"io"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/blobstore"
 	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
 	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/money"
+	"gorm.io/gorm"
 )
 
 type ProductHandler struct {
-	ProductDB database.ProductInterface
+	ProductDB        database.ProductInterface
+	ExchangeRateDB   database.ExchangeRateInterface
+	ScheduledPriceDB database.ScheduledPriceInterface
+	VariantDB        database.VariantInterface
+	Rounding         money.RoundingMode
+	// ImportBatchSize is how many imported products are saved per
+	// transaction, database.DefaultImportBatchSize when zero.
+	ImportBatchSize int
+	// ImportMaxBytes is the largest file accepted for a background import.
+	ImportMaxBytes int64
+	// JobDB queues the imports, exports and purges run in the background,
+	// which are tried up to JobMaxAttempts times. Files keeps the files
+	// they read and write and must not be served publicly.
+	JobDB          database.JobInterface
+	JobMaxAttempts int
+	Files          blobstore.BlobStore
+	// BatchLimit is the most operations a batch may have.
+	BatchLimit int
+	Clock      clock.Clock
 }
 
-func NewProductHandler(db database.ProductInterface) *ProductHandler {
+func NewProductHandler(db database.ProductInterface, rates database.ExchangeRateInterface, scheduledPrices database.ScheduledPriceInterface, variants database.VariantInterface) *ProductHandler {
 	return &ProductHandler{
-		ProductDB: db,
+		ProductDB:        db,
+		ExchangeRateDB:   rates,
+		ScheduledPriceDB: scheduledPrices,
+		VariantDB:        variants,
+		Rounding:         money.RoundHalfEven,
+		ImportMaxBytes:   DefaultImportMaxBytes,
+		BatchLimit:       DefaultBatchLimit,
+		Clock:            clock.Real{},
 	}
 }
 
 // CreateProduct godoc
 // @Summary 		Create product
-// @Description 	Create product
+// @Description 	Create product and answer with it, pointed to by the Location header. With Prefer: return=minimal the body is left out. Retrying with the same Idempotency-Key replays the first response.
 // @Tags 			products
 // @Accept 			json
 // @Produce 		json
-// @Param 			request		body	dto.CreateProductInput	true	"product request"
-// @Success 		201
-// @Failure 		400 		{object}	Error
-// @Failure 		500 		{object}	Error
+// @Param 			request				body	dto.CreateProductInput	true	"product request"
+// @Param 			Idempotency-Key		header	string					false	"key making retries of the request safe"
+// @Param 			Prefer				header	string					false	"return=minimal to leave the product out of the response"	Enums(return=minimal, return=representation)
+// @Success 		201			{object}	dto.ProductOutput
+// @Header 			201			{string}	Location	"URL of the product"
+// @Failure 		400 		{object}	Problem
+// @Failure 		409 		{object}	Problem
+// @Failure 		413 		{object}	Problem
+// @Failure 		422 		{object}	Problem
+// @Failure 		500 		{object}	Problem
 // @Router 			/products 	[post]
 // @Security		ApiKeyAuth
 func (handler *ProductHandler) CreateProduct(w http.ResponseWriter, req *http.Request) {
 	var product dto.CreateProductInput
 	err := json.NewDecoder(req.Body).Decode(&product)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Error{Message: err.Error()})
+		writeMalformed(w, req, err)
 		return
 	}
 
 	p, err := entity.NewProduct(product.Name, product.Price)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Error{Message: err.Error()})
+		writeError(w, req, err)
 		return
 	}
 
 	err = handler.ProductDB.Create(p)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	output, err := handler.productRepresentation(p)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	writeRepresentation(w, req, http.StatusCreated, "/products/"+p.ID.String(), output)
+}
+
+// ImportProducts godoc
+// @Summary 		Import products
+// @Description 	Create products from a CSV file with the header name,price,currency or from NDJSON with one product per line, shaped like the body of POST /products. Valid rows are saved in batches and every row gets its outcome in the report; on a dry run the rows are only validated. With the header Prefer: respond-async the file is imported by a background job, whose result is the report.
+// @Tags 			products
+// @Accept 			text/csv,application/x-ndjson
+// @Produce 		json
+// @Param 			dry_run				query		bool				false	"validate the rows without saving them"
+// @Param 			Prefer				header		string				false	"respond-async to import in the background"
+// @Param 			request				body		string				true	"products file"
+// @Success 		200					{object}	dto.ImportReport
+// @Success 		202					{object}	dto.JobOutput
+// @Failure 		400					{object}	Problem
+// @Failure 		413					{object}	Problem	"the file of a background import is too large"
+// @Failure 		415					{object}	Problem
+// @Failure 		500					{object}	Problem
+// @Router 			/products/import 	[post]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) ImportProducts(w http.ResponseWriter, req *http.Request) {
+	dryRun := false
+	if value := req.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeInvalidParam(w, req, "dry_run", "boolean")
+			return
+		}
+		dryRun = parsed
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if prefers(req, "respond-async") {
+		handler.enqueueImport(w, req, mediaType, dryRun)
+		return
+	}
+
+	rows, err := database.NewProductRows(mediaType, req.Body)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	trans := requestTranslator(req)
+	report, err := handler.importProducts(rows, dryRun, trans, nil)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", trans.Locale())
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
 }
 
 // GetProducts godoc
@@ -67,112 +164,516 @@ func (handler *ProductHandler) CreateProduct(w http.ResponseWriter, req *http.Re
 // @Produce 		json
 // @Param 			page		query	string	false	"page number"
 // @Param 			page		query	string	false	"limit"
-// @Success 		200			{array}	entity.Product
-// @Failure 		404 		{object}	Error
-// @Failure 		500 		{object}	Error
+// @Param 			currency	query	string	false	"ISO 4217 currency to convert prices to"
+// @Param 			tags		query	string	false	"comma separated tags to filter by"
+// @Param 			tag_mode	query	string	false	"whether products need all the tags or any of them"	Enums(any, all)	default(any)
+// @Param 			expand		query	string	false	"related data to include with each product"	Enums(variants)
+// @Param 			sort		query	string	false	"order by creation date or rating average"	Enums(asc, desc, rating, -rating)
+// @Param 			min_rating	query	number	false	"lowest rating average, leaving out products without reviews"
+// @Success 		200			{array}	dto.ProductOutput
+// @Failure 		404 		{object}	Problem
+// @Failure 		500 		{object}	Problem
 // @Router 			/products 	[get]
 // @Security		ApiKeyAuth
 func (handler *ProductHandler) GetProducts(w http.ResponseWriter, req *http.Request) {
-	page := req.URL.Query().Get("page")
-	limit := req.URL.Query().Get("limit")
-	sort := req.URL.Query().Get("sort")
-
-	pageInt, err := strconv.Atoi(page)
-	if err != nil {
-		pageInt = 0
+	query, ok := productQuery(w, req)
+	if !ok {
+		return
+	}
+	currency, ok := currencyParam(req)
+	if !ok {
+		writeInvalidParam(w, req, "currency", "iso4217")
+		return
+	}
+	expand := req.URL.Query().Get("expand")
+	if expand != "" && expand != "variants" {
+		writeInvalidParam(w, req, "expand", "invalid")
+		return
 	}
 
-	limitInt, err := strconv.Atoi(limit)
+	products, err := handler.ProductDB.Find(query)
 	if err != nil {
-		limitInt = 0
+		writeError(w, req, err)
+		return
 	}
 
-	products, err := handler.ProductDB.FindAll(pageInt, limitInt, sort)
+	output, err := handler.productOutputs(products, currency)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
+	if expand == "variants" {
+		err = handler.withVariants(output)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(products)
+	json.NewEncoder(w).Encode(output)
+}
+
+// ExportProducts godoc
+// @Summary 		Export products
+// @Description 	Stream every product matching the filters of the product list as CSV, NDJSON or a JSON array, read from the database in batches. Errors found once the download started cut it short and are only logged.
+// @Tags 			products
+// @Produce 		text/csv,application/x-ndjson,json
+// @Param 			format		query		string	false	"file format"	Enums(csv, ndjson, json)	default(csv)
+// @Param 			currency	query		string	false	"ISO 4217 currency to convert prices to"
+// @Param 			tags		query		string	false	"comma separated tags to filter by"
+// @Param 			tag_mode	query		string	false	"whether products need all the tags or any of them"	Enums(any, all)	default(any)
+// @Param 			sort		query		string	false	"order by creation date or rating average"	Enums(asc, desc, rating, -rating)
+// @Param 			min_rating	query		number	false	"lowest rating average, leaving out products without reviews"
+// @Success 		200			{array}		dto.ProductOutput
+// @Failure 		400			{object}	Problem
+// @Failure 		422			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/products/export 	[get]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) ExportProducts(w http.ResponseWriter, req *http.Request) {
+	params, ok := exportParams(w, req)
+	if !ok {
+		return
+	}
+	exporter, _ := newProductExporter(params.Format)
+
+	begin := func() (io.Writer, error) {
+		filename := "products-" + handler.Clock.Now().UTC().Format("20060102-150405") + "." + exporter.Extension()
+		w.Header().Set("Content-Type", exporter.ContentType())
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+		w.WriteHeader(http.StatusOK)
+		return w, nil
+	}
+	flush := func(int64) error {
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	started, err := handler.exportProducts(exporter, params, begin, flush)
+	if err != nil && !started {
+		writeError(w, req, err)
+		return
+	}
+	if err != nil {
+		log.Printf("exporting products: %v", err)
+	}
+}
+
+// CreateProductExport godoc
+// @Summary 		Export products in the background
+// @Description 	Queue a job exporting the products like GET /products/export. Once the job succeeded the file is downloaded from GET /jobs/{id}/file.
+// @Tags 			products
+// @Produce 		json
+// @Param 			format		query		string	false	"file format"	Enums(csv, ndjson, json)	default(csv)
+// @Param 			currency	query		string	false	"ISO 4217 currency to convert prices to"
+// @Param 			tags		query		string	false	"comma separated tags to filter by"
+// @Param 			tag_mode	query		string	false	"whether products need all the tags or any of them"	Enums(any, all)	default(any)
+// @Param 			sort		query		string	false	"order by creation date or rating average"	Enums(asc, desc, rating, -rating)
+// @Param 			min_rating	query		number	false	"lowest rating average, leaving out products without reviews"
+// @Success 		202			{object}	dto.JobOutput
+// @Header 			202			{string}	Location	"the job"
+// @Failure 		400			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/products/export 	[post]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) CreateProductExport(w http.ResponseWriter, req *http.Request) {
+	params, ok := exportParams(w, req)
+	if !ok {
+		return
+	}
+	handler.enqueue(w, req, entity.JobProductExport, params)
+}
+
+// PurgeProducts godoc
+// @Summary 		Purge products
+// @Description 	Queue a job deleting every product carrying the tags or rated at least min_rating, along with their variants, images and tags. At least one of the filters is required.
+// @Tags 			products
+// @Produce 		json
+// @Param 			tags		query		string	false	"comma separated tags to filter by"
+// @Param 			tag_mode	query		string	false	"whether products need all the tags or any of them"	Enums(any, all)	default(any)
+// @Param 			min_rating	query		number	false	"lowest rating average, leaving out products without reviews"
+// @Success 		202			{object}	dto.JobOutput
+// @Header 			202			{string}	Location	"the job"
+// @Failure 		400			{object}	Problem
+// @Failure 		403			{object}	Problem
+// @Failure 		500			{object}	Problem
+// @Router 			/products/purge 	[post]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) PurgeProducts(w http.ResponseWriter, req *http.Request) {
+	query, ok := productQuery(w, req)
+	if !ok {
+		return
+	}
+	if len(query.Tags) == 0 && query.MinRating == 0 {
+		writeInvalidParam(w, req, "tags", "required")
+		return
+	}
+	query.Page, query.Limit, query.Sort = 0, 0, ""
+	handler.enqueue(w, req, entity.JobProductPurge, query)
 }
 
 // GetProduct godoc
 // @Summary 		Get a product
-// @Description 	Get a product
+// @Description 	Get a product with its variants
 // @Tags 			products
 // @Accept 			json
 // @Produce 		json
 // @Param 			id				path		string		true 	"product ID"	Format(uuid)
-// @Success 		200				{object}	entity.Product
-// @Failure 		404
-// @Failure 		500 			{object}	Error
+// @Param 			currency		query		string		false	"ISO 4217 currency to convert the price to"
+// @Success 		200				{object}	dto.ProductOutput
+// @Failure 		400				{object}	Problem
+// @Failure 		404				{object}	Problem
+// @Failure 		500 			{object}	Problem
 // @Router 			/products/{id} 	[get]
 // @Security		ApiKeyAuth
 func (handler *ProductHandler) GetProduct(w http.ResponseWriter, req *http.Request) {
 	id := chi.URLParam(req, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, req, entity.ErrIDIsRequired)
+		return
+	}
+	currency, ok := currencyParam(req)
+	if !ok {
+		writeInvalidParam(w, req, "currency", "iso4217")
 		return
 	}
 
 	product, err := handler.ProductDB.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, req, err)
+		return
+	}
+
+	output, err := handler.productOutputs([]entity.Product{*product}, currency)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	err = handler.withVariants(output)
+	if err != nil {
+		writeError(w, req, err)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(output[0])
+}
+
+// productRepresentation is the product as GetProduct returns it, answered
+// by the handlers that create or change it.
+func (handler *ProductHandler) productRepresentation(product *entity.Product) (dto.ProductOutput, error) {
+	output, err := handler.productOutputs([]entity.Product{*product}, "")
+	if err != nil {
+		return dto.ProductOutput{}, err
+	}
+	err = handler.withVariants(output)
+	if err != nil {
+		return dto.ProductOutput{}, err
+	}
+	return output[0], nil
 }
 
 // UpdateProduct godoc
 // @Summary 		Update a product
-// @Description 	Update a product
+// @Description 	Update a product and answer with it. The currency cannot change while variants have prices of their own or prices are scheduled. With Prefer: return=minimal the body is left out and the status is 204.
 // @Tags 			products
 // @Accept 			json
 // @Produce 		json
 // @Param 			id				path		string		true 	"product ID"	Format(uuid)
 // @Param 			request			body		dto.CreateProductInput		true 	"product request"
-// @Success 		200
-// @Failure 		404
-// @Failure 		500
+// @Param 			Prefer			header		string		false	"return=minimal to leave the product out of the response"	Enums(return=minimal, return=representation)
+// @Success 		200				{object}	dto.ProductOutput
+// @Success 		204				"the product was updated and Prefer: return=minimal was given"
+// @Failure 		400				{object}	Problem
+// @Failure 		404				{object}	Problem
+// @Failure 		409				{object}	Problem	"the currency changed while variants or scheduled prices use the old one"
+// @Failure 		500				{object}	Problem
 // @Router 			/products/{id} 	[put]
 // @Security		ApiKeyAuth
 func (handler *ProductHandler) UpdateProduct(w http.ResponseWriter, req *http.Request) {
 	id := chi.URLParam(req, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, req, entity.ErrIDIsRequired)
 		return
 	}
 
-	var product entity.Product
-	err := json.NewDecoder(req.Body).Decode(&product)
+	var input entity.Product
+	err := json.NewDecoder(req.Body).Decode(&input)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeMalformed(w, req, err)
 		return
 	}
 
-	product.ID, err = entityPkg.ParseID(id)
+	product, err := updatedProduct(id, input)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, req, err)
 		return
 	}
 
 	_, err = handler.ProductDB.FindByID(product.ID.String())
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ProductDB.Update(product, subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	// answer with the product as saved, not as sent
+	product, err = handler.ProductDB.FindByID(product.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	output, err := handler.productRepresentation(product)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	writeRepresentation(w, req, http.StatusOK, "", output)
+}
+
+// GetPriceHistory godoc
+// @Summary 		Get a product price history
+// @Description 	List the price changes of a product, newest first. With as_of, return the price it had at that time as a dto.PriceAsOfOutput instead.
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			id								path		string		true 	"product ID"	Format(uuid)
+// @Param 			as_of							query		string		false	"RFC 3339 timestamp"	Format(date-time)
+// @Success 		200								{array}		entity.ProductPriceHistory
+// @Failure 		400								{object}	Problem
+// @Failure 		404								{object}	Problem
+// @Failure 		500								{object}	Problem
+// @Router 			/products/{id}/price-history 	[get]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) GetPriceHistory(w http.ResponseWriter, req *http.Request) {
+	id := chi.URLParam(req, "id")
+	if id == "" {
+		writeError(w, req, entity.ErrIDIsRequired)
+		return
+	}
+
+	product, err := handler.ProductDB.FindByID(id)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var output interface{}
+	if asOf := req.URL.Query().Get("as_of"); asOf != "" {
+		at, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			writeInvalidParam(w, req, "as_of", "datetime")
+			return
+		}
+		price, err := handler.ProductDB.FindPriceAt(product.ID.String(), at)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		output = dto.PriceAsOfOutput{ProductID: product.ID, AsOf: at, Price: price}
+	} else {
+		history, err := handler.ProductDB.FindPriceHistory(product.ID.String())
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		output = history
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+// CreateScheduledPrice godoc
+// @Summary 		Schedule a product price
+// @Description 	Schedule a price change. Without ends_at the price replaces the list price at starts_at; with ends_at it is a promotion that only changes the effective price during its window.
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			id									path		string							true 	"product ID"	Format(uuid)
+// @Param 			request								body		dto.CreateScheduledPriceInput	true 	"scheduled price"
+// @Success 		201									{object}	entity.ScheduledPrice
+// @Failure 		400									{object}	Problem
+// @Failure 		404									{object}	Problem
+// @Failure 		500									{object}	Problem
+// @Router 			/products/{id}/scheduled-prices 	[post]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) CreateScheduledPrice(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.CreateScheduledPriceInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	scheduledPrice, err := entity.NewScheduledPrice(product, input.Price, input.StartsAt, input.EndsAt, subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ScheduledPriceDB.Create(scheduledPrice)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
+
+// GetScheduledPrices godoc
+// @Summary 		List a product scheduled prices
+// @Description 	List the scheduled prices of a product ordered by start date
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			id									path		string		true 	"product ID"	Format(uuid)
+// @Success 		200									{array}		entity.ScheduledPrice
+// @Failure 		404									{object}	Problem
+// @Failure 		500									{object}	Problem
+// @Router 			/products/{id}/scheduled-prices 	[get]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) GetScheduledPrices(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	scheduledPrices, err := handler.ScheduledPriceDB.FindByProduct(product.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduledPrices)
+}
+
+// CancelScheduledPrice godoc
+// @Summary 		Cancel a scheduled price
+// @Description 	Cancel a scheduled price that has not started yet
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			id														path		string		true 	"product ID"			Format(uuid)
+// @Param 			scheduledPriceID										path		string		true 	"scheduled price ID"	Format(uuid)
+// @Success 		200														{object}	entity.ScheduledPrice
+// @Failure 		404														{object}	Problem
+// @Failure 		409														{object}	Problem
+// @Failure 		500														{object}	Problem
+// @Router 			/products/{id}/scheduled-prices/{scheduledPriceID} 		[delete]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) CancelScheduledPrice(w http.ResponseWriter, req *http.Request) {
+	scheduledPrice, err := handler.ScheduledPriceDB.FindByID(chi.URLParam(req, "scheduledPriceID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if scheduledPrice.ProductID.String() != chi.URLParam(req, "id") {
+		writeError(w, req, gorm.ErrRecordNotFound)
+		return
+	}
+
+	err = scheduledPrice.Cancel()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ScheduledPriceDB.Update(scheduledPrice)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scheduledPrice)
+}
+
+// SetProductTags godoc
+// @Summary 		Set a product tags
+// @Description 	Replace the tags of a product. Tags are stored as lowercase slugs, so "Summer Sale" becomes "summer-sale".
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string					true 	"product ID"	Format(uuid)
+// @Param 			request					body		dto.ProductTagsInput	true 	"tags"
+// @Success 		200						{object}	dto.ProductTagsInput
+// @Failure 		400						{object}	Problem
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/products/{id}/tags 	[put]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) SetProductTags(w http.ResponseWriter, req *http.Request) {
+	var input dto.ProductTagsInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	tags, err := entity.NormalizeTags(input.Tags)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ProductDB.SetTags(chi.URLParam(req, "id"), tags)
+	if err != nil {
+		writeError(w, req, err)
 		return
 	}
 
-	err = handler.ProductDB.Update(&product)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dto.ProductTagsInput{Tags: tags})
+}
+
+// GetTags godoc
+// @Summary 		List tags
+// @Description 	List the tags in use with the number of products carrying each, most used first
+// @Tags 			products
+// @Accept 			json
+// @Produce 		json
+// @Success 		200		{array}		entity.TagCount
+// @Failure 		500		{object}	Problem
+// @Router 			/tags 	[get]
+// @Security		ApiKeyAuth
+func (handler *ProductHandler) GetTags(w http.ResponseWriter, req *http.Request) {
+	counts, err := handler.ProductDB.CountTags()
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
+	if counts == nil {
+		counts = []entity.TagCount{}
+	}
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(counts)
 }
 
 // DeleteProduct godoc
@@ -183,28 +684,191 @@ func (handler *ProductHandler) UpdateProduct(w http.ResponseWriter, req *http.Re
 // @Produce 		json
 // @Param 			id				path		string		true 	"product ID"	Format(uuid)
 // @Success 		200
-// @Failure 		404
-// @Failure 		500
+// @Failure 		400				{object}	Problem
+// @Failure 		404				{object}	Problem
+// @Failure 		500				{object}	Problem
 // @Router 			/products/{id} 	[delete]
 // @Security		ApiKeyAuth
 func (handler *ProductHandler) DeleteProduct(w http.ResponseWriter, req *http.Request) {
 	id := chi.URLParam(req, "id")
 	if id == "" {
-		w.WriteHeader(http.StatusBadRequest)
+		writeError(w, req, entity.ErrIDIsRequired)
 		return
 	}
 
 	product, err := handler.ProductDB.FindByID(id)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
+		writeError(w, req, err)
 		return
 	}
 
 	err = handler.ProductDB.Delete(product.ID.String())
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, req, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
+
+// updatedProduct validates the new state of the product with the given id.
+func updatedProduct(id string, product entity.Product) (*entity.Product, error) {
+	if id == "" {
+		return nil, entity.ErrIDIsRequired
+	}
+	parsed, err := entityPkg.ParseID(id)
+	if err != nil {
+		return nil, entity.ErrInvalidID
+	}
+	product.ID = parsed
+
+	err = product.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+// productQuery reads the filters of the product list from the query string,
+// reporting the first invalid one to the client.
+func productQuery(w http.ResponseWriter, req *http.Request) (database.ProductQuery, bool) {
+	tagMode := strings.ToLower(req.URL.Query().Get("tag_mode"))
+	if tagMode == "" {
+		tagMode = database.TagModeAny
+	}
+	if tagMode != database.TagModeAny && tagMode != database.TagModeAll {
+		writeInvalidParam(w, req, "tag_mode", "invalid")
+		return database.ProductQuery{}, false
+	}
+	var minRating float64
+	if value := req.URL.Query().Get("min_rating"); value != "" {
+		var err error
+		minRating, err = strconv.ParseFloat(value, 64)
+		if err != nil || minRating < 0 || minRating > 5 {
+			writeInvalidParam(w, req, "min_rating", "invalid")
+			return database.ProductQuery{}, false
+		}
+	}
+	var tags []string
+	if value := req.URL.Query().Get("tags"); value != "" {
+		var err error
+		tags, err = entity.NormalizeTags(strings.Split(value, ","))
+		if err != nil {
+			writeInvalidParam(w, req, "tags", "invalid")
+			return database.ProductQuery{}, false
+		}
+	}
+
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil {
+		page = 0
+	}
+
+	limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+	if err != nil {
+		limit = 0
+	}
+
+	return database.ProductQuery{
+		Page:      page,
+		Limit:     limit,
+		Sort:      req.URL.Query().Get("sort"),
+		Tags:      tags,
+		TagMode:   tagMode,
+		MinRating: minRating,
+	}, true
+}
+
+// productOutputs prepares products for the response, resolving the price
+// in effect now and converting list prices to currency when one is given.
+func (handler *ProductHandler) productOutputs(products []entity.Product, currency string) ([]dto.ProductOutput, error) {
+	now := handler.Clock.Now()
+	output := make([]dto.ProductOutput, len(products))
+	rates := map[string]*entity.ExchangeRate{}
+
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID.String()
+	}
+	promotions, err := handler.ScheduledPriceDB.FindActivePromotions(ids, now)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := handler.ProductDB.FindTags(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, product := range products {
+		output[i].Product = product
+		output[i].Tags = tags[product.ID.String()]
+		if output[i].Tags == nil {
+			output[i].Tags = []string{}
+		}
+		output[i].EffectivePrice = product.Price
+		if promotion, ok := promotions[product.ID.String()]; ok {
+			output[i].EffectivePrice = promotion.Price
+			output[i].ActivePromotion = &promotion
+		}
+		if currency == "" {
+			continue
+		}
+
+		rate, ok := rates[product.Price.Currency]
+		if !ok {
+			var err error
+			rate, err = handler.exchangeRate(product.Price.Currency, currency)
+			if err != nil {
+				return nil, err
+			}
+			rates[product.Price.Currency] = rate
+		}
+
+		price, err := rate.Convert(product.Price, handler.Rounding)
+		if err != nil {
+			return nil, err
+		}
+		output[i].ConvertedPrice = &dto.ConvertedPrice{
+			Price:           price,
+			Rate:            rate.Rate,
+			RateSource:      rate.Source,
+			RateEffectiveAt: rate.EffectiveAt,
+			Rounding:        string(handler.Rounding),
+		}
+	}
+
+	return output, nil
+}
+
+// withVariants adds the variants of each product to the output.
+func (handler *ProductHandler) withVariants(output []dto.ProductOutput) error {
+	ids := make([]string, len(output))
+	for i := range output {
+		ids[i] = output[i].ID.String()
+	}
+	variants, err := handler.VariantDB.FindByProducts(ids)
+	if err != nil {
+		return err
+	}
+	for i := range output {
+		output[i].Variants = variants[ids[i]]
+	}
+	return nil
+}
+
+func (handler *ProductHandler) exchangeRate(base, quote string) (*entity.ExchangeRate, error) {
+	if base == quote {
+		return &entity.ExchangeRate{BaseCurrency: base, QuoteCurrency: quote, Rate: "1", Source: "identity"}, nil
+	}
+	return handler.ExchangeRateDB.FindEffective(base, quote, handler.Clock.Now())
+}
+
+// currencyParam reads the optional currency query parameter, reporting false
+// when it is not an ISO 4217 code.
+func currencyParam(req *http.Request) (string, bool) {
+	currency := strings.ToUpper(req.URL.Query().Get("currency"))
+	if currency == "" {
+		return "", true
+	}
+	return currency, validator.GetValidatorInstance().Var(currency, "iso4217") == nil
+}
@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/blobstore"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/jobs"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/validator"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+	"gorm.io/gorm"
+)
+
+// DefaultImportMaxBytes is the largest file accepted for a background
+// import unless configured otherwise.
+const DefaultImportMaxBytes = 32 << 20
+
+const (
+	// importProgressRows is how often an import job reports its progress.
+	importProgressRows = 100
+	// purgeBatchSize is how many products a purge job deletes between
+	// progress reports.
+	purgeBatchSize = 100
+)
+
+// productImport is the payload of import jobs. File is the blob key of the
+// uploaded file and Locale the language of the messages in the report.
+type productImport struct {
+	File      string `json:"file"`
+	MediaType string `json:"media_type"`
+	DryRun    bool   `json:"dry_run"`
+	Locale    string `json:"locale"`
+}
+
+// productExport is the payload of export jobs.
+type productExport struct {
+	Format   string                `json:"format"`
+	Currency string                `json:"currency,omitempty"`
+	Query    database.ProductQuery `json:"query"`
+}
+
+// exportParams reads the format and filters of an export from the query
+// string, reporting the first invalid one to the client.
+func exportParams(w http.ResponseWriter, req *http.Request) (productExport, bool) {
+	params := productExport{Format: req.URL.Query().Get("format")}
+	if params.Format == "" {
+		params.Format = "csv"
+	}
+	if _, ok := newProductExporter(params.Format); !ok {
+		writeInvalidParam(w, req, "format", "invalid")
+		return params, false
+	}
+	query, ok := productQuery(w, req)
+	if !ok {
+		return params, false
+	}
+	params.Query = query
+	params.Currency, ok = currencyParam(req)
+	if !ok {
+		writeInvalidParam(w, req, "currency", "iso4217")
+		return params, false
+	}
+	return params, true
+}
+
+// importProducts runs an import and builds the report of its rows, with the
+// field errors in the translator's locale. When progress is set it is told
+// the number of rows read every importProgressRows rows.
+func (handler *ProductHandler) importProducts(rows database.ProductRows, dryRun bool, trans validator.Translator, progress jobs.Progress) (*dto.ImportReport, error) {
+	report := &dto.ImportReport{DryRun: dryRun, Rows: []dto.ImportRowOutput{}}
+	err := handler.ProductDB.Import(rows, dryRun, handler.ImportBatchSize, func(result database.ImportResult) error {
+		report.Total++
+		row := dto.ImportRowOutput{Line: result.Line}
+		switch {
+		case result.Err != nil:
+			problem := ProblemFromError(result.Err, trans)
+			row.Status, row.Detail = "invalid", problem.Detail
+			if problem.Status >= http.StatusInternalServerError {
+				row.Status = "failed"
+			}
+			for _, fe := range problem.Errors {
+				row.Errors = append(row.Errors, dto.ImportFieldError(fe))
+			}
+			report.Failed++
+		case dryRun:
+			row.Status = "valid"
+			report.Valid++
+		default:
+			row.Status, row.ID = "created", result.Product.ID.String()
+			report.Created++
+		}
+		report.Rows = append(report.Rows, row)
+
+		if progress != nil && report.Total%importProgressRows == 0 {
+			return progress(int64(report.Total))
+		}
+		return nil
+	})
+	if err == nil && progress != nil {
+		err = progress(int64(report.Total))
+	}
+	return report, err
+}
+
+// exportProducts writes the products of the export with the exporter. The
+// output is only started, by calling begin, once the first batch is ready,
+// so that errors found before it can still be reported; started tells
+// whether it was. progress is told the number of products written after
+// each batch.
+func (handler *ProductHandler) exportProducts(exporter productExporter, params productExport, begin func() (io.Writer, error), progress jobs.Progress) (started bool, err error) {
+	start := func() error {
+		started = true
+		w, err := begin()
+		if err != nil {
+			return err
+		}
+		return exporter.Begin(w)
+	}
+
+	var written int64
+	err = handler.ProductDB.Stream(params.Query, exportBatchSize, func(products []entity.Product) error {
+		output, err := handler.productOutputs(products, params.Currency)
+		if err != nil {
+			return err
+		}
+		if !started {
+			err = start()
+			if err != nil {
+				return err
+			}
+		}
+		for _, product := range output {
+			err = exporter.Write(product)
+			if err != nil {
+				return err
+			}
+		}
+		err = exporter.Flush()
+		if err != nil {
+			return err
+		}
+		written += int64(len(output))
+		return progress(written)
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = exporter.End()
+	}
+	return started, err
+}
+
+// enqueue queues a job of the user making the request and answers with it.
+func (handler *ProductHandler) enqueue(w http.ResponseWriter, req *http.Request, jobType string, payload any) {
+	job, err := entity.NewJob(jobType, subject(req), payload, handler.JobMaxAttempts)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	err = handler.JobDB.Create(job)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	writeJob(w, req, http.StatusAccepted, job)
+}
+
+// enqueueImport keeps the uploaded file, of up to ImportMaxBytes, in the
+// job file store for the import job. The header of CSV files is checked
+// before the job is queued.
+func (handler *ProductHandler) enqueueImport(w http.ResponseWriter, req *http.Request, mediaType string, dryRun bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, req.Body, handler.ImportMaxBytes))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeError(w, req, database.ErrImportTooLarge)
+		return
+	}
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	_, err = database.NewProductRows(mediaType, bytes.NewReader(data))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	key := "imports/" + entityPkg.NewID().String()
+	err = handler.Files.Put(req.Context(), key, data, mediaType)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	handler.enqueue(w, req, entity.JobProductImport, productImport{
+		File:      key,
+		MediaType: mediaType,
+		DryRun:    dryRun,
+		Locale:    requestTranslator(req).Locale(),
+	})
+}
+
+// RunImportJob imports the uploaded file of the job and returns the report.
+// Imports are not retried once they started, since the rows already saved
+// would be saved again.
+func (handler *ProductHandler) RunImportJob(ctx context.Context, job *entity.Job, progress jobs.Progress) (any, error) {
+	var params productImport
+	err := job.DecodePayload(&params)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	file, err := handler.Files.Get(ctx, params.File)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		return nil, jobs.Permanent(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := database.NewProductRows(params.MediaType, file)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	report, err := handler.importProducts(rows, params.DryRun, validator.GetTranslator(params.Locale), progress)
+	if errors.Is(err, entity.ErrJobCancelled) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+
+	err = handler.Files.Delete(ctx, params.File)
+	if err != nil {
+		log.Printf("deleting blob %s: %v", params.File, err)
+	}
+	return report, nil
+}
+
+// RunExportJob exports the products to a file kept in the blob store, which
+// becomes the file of the job.
+func (handler *ProductHandler) RunExportJob(ctx context.Context, job *entity.Job, progress jobs.Progress) (any, error) {
+	var params productExport
+	err := job.DecodePayload(&params)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	exporter, ok := newProductExporter(params.Format)
+	if !ok {
+		return nil, jobs.Permanent(fmt.Errorf("unknown export format %q", params.Format))
+	}
+
+	var buf bytes.Buffer
+	var written int64
+	_, err = handler.exportProducts(exporter, params, func() (io.Writer, error) {
+		return &buf, nil
+	}, func(done int64) error {
+		written = done
+		return progress(done)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := "jobs/" + job.ID.String() + "/products." + exporter.Extension()
+	err = handler.Files.Put(ctx, key, buf.Bytes(), exporter.ContentType())
+	if err != nil {
+		return nil, err
+	}
+	job.File = key
+	return dto.ExportResult{Format: params.Format, Products: written}, nil
+}
+
+// RunPurgeJob deletes the products matching the query of the job, a batch
+// at a time. Deleting is idempotent, so a failed purge is simply retried.
+func (handler *ProductHandler) RunPurgeJob(ctx context.Context, job *entity.Job, progress jobs.Progress) (any, error) {
+	var query database.ProductQuery
+	err := job.DecodePayload(&query)
+	if err != nil {
+		return nil, jobs.Permanent(err)
+	}
+	query.Page, query.Limit = 1, purgeBatchSize
+
+	var deleted int64
+	for {
+		products, err := handler.ProductDB.Find(query)
+		if err != nil {
+			return nil, err
+		}
+		if len(products) == 0 {
+			return dto.PurgeResult{Deleted: deleted}, nil
+		}
+		for _, product := range products {
+			err = handler.ProductDB.Delete(product.ID.String())
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			deleted++
+		}
+		err = progress(deleted)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportProductsInBackgroundTooLarge(t *testing.T) {
+	handler := NewProductHandler(nil, nil, nil, nil)
+	handler.ImportMaxBytes = 16
+
+	req := httptest.NewRequest(http.MethodPost, "/products/import", strings.NewReader("name,price,currency\nPen,1.50,USD\n"))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Prefer", "respond-async")
+	rec := httptest.NewRecorder()
+	handler.ImportProducts(rec, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, ProblemContentType, rec.Header().Get("Content-Type"))
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+)
+
+type ReviewHandler struct {
+	ProductDB database.ProductInterface
+	ReviewDB  database.ReviewInterface
+}
+
+func NewReviewHandler(products database.ProductInterface, reviews database.ReviewInterface) *ReviewHandler {
+	return &ReviewHandler{
+		ProductDB: products,
+		ReviewDB:  reviews,
+	}
+}
+
+// CreateReview godoc
+// @Summary 		Review a product
+// @Description 	Rate a product from 1 to 5 stars with an optional text. Each user reviews a product once.
+// @Tags 			reviews
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string				true 	"product ID"	Format(uuid)
+// @Param 			request						body		dto.ReviewInput		true 	"review request"
+// @Success 		201							{object}	entity.Review
+// @Failure 		400							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		409							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/products/{id}/reviews 		[post]
+// @Security		ApiKeyAuth
+func (handler *ReviewHandler) CreateReview(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.ReviewInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	review, err := entity.NewReview(product.ID, subject(req), input.Rating, input.Text)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ReviewDB.Create(review)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(review)
+}
+
+// GetReviews godoc
+// @Summary 		List a product reviews
+// @Description 	List the published reviews of a product, newest first. Admins also get the hidden ones.
+// @Tags 			reviews
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"product ID"	Format(uuid)
+// @Param 			page						query		string		false	"page number"
+// @Param 			limit						query		string		false	"limit"
+// @Success 		200							{array}		entity.Review
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/products/{id}/reviews 		[get]
+// @Security		ApiKeyAuth
+func (handler *ReviewHandler) GetReviews(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+	limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+	reviews, err := handler.ReviewDB.FindByProduct(product.ID.String(), isAdmin(req), page, limit)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if reviews == nil {
+		reviews = []entity.Review{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reviews)
+}
+
+// UpdateReview godoc
+// @Summary 		Edit a review
+// @Description 	Change the rating and text of a review. Only its author can edit it.
+// @Tags 			reviews
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string				true 	"product ID"	Format(uuid)
+// @Param 			reviewID								path		string				true 	"review ID"		Format(uuid)
+// @Param 			request									body		dto.ReviewInput		true 	"review request"
+// @Success 		200										{object}	entity.Review
+// @Failure 		400										{object}	Problem
+// @Failure 		403										{object}	Problem
+// @Failure 		404										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/products/{id}/reviews/{reviewID} 		[put]
+// @Security		ApiKeyAuth
+func (handler *ReviewHandler) UpdateReview(w http.ResponseWriter, req *http.Request) {
+	review, err := handler.ReviewDB.FindByID(chi.URLParam(req, "id"), chi.URLParam(req, "reviewID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.ReviewInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	err = review.Edit(subject(req), input.Rating, input.Text)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ReviewDB.Update(review)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
+
+// DeleteReview godoc
+// @Summary 		Delete a review
+// @Description 	Delete a review. Authors can delete their reviews and admins any review.
+// @Tags 			reviews
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string		true 	"product ID"	Format(uuid)
+// @Param 			reviewID								path		string		true 	"review ID"		Format(uuid)
+// @Success 		200
+// @Failure 		403										{object}	Problem
+// @Failure 		404										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/products/{id}/reviews/{reviewID} 		[delete]
+// @Security		ApiKeyAuth
+func (handler *ReviewHandler) DeleteReview(w http.ResponseWriter, req *http.Request) {
+	review, err := handler.ReviewDB.FindByID(chi.URLParam(req, "id"), chi.URLParam(req, "reviewID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if review.UserID != subject(req) && !isAdmin(req) {
+		writeError(w, req, entity.ErrNotReviewAuthor)
+		return
+	}
+
+	err = handler.ReviewDB.Delete(review)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ModerateReview godoc
+// @Summary 		Moderate a review
+// @Description 	Hide a review, leaving it out of the product rating, or publish it again. Requires the admin role.
+// @Tags 			reviews
+// @Accept 			json
+// @Produce 		json
+// @Param 			id												path		string							true 	"product ID"	Format(uuid)
+// @Param 			reviewID										path		string							true 	"review ID"		Format(uuid)
+// @Param 			request											body		dto.ReviewModerationInput		true 	"moderation request"
+// @Success 		200												{object}	entity.Review
+// @Failure 		400												{object}	Problem
+// @Failure 		403												{object}	Problem
+// @Failure 		404												{object}	Problem
+// @Failure 		500												{object}	Problem
+// @Router 			/products/{id}/reviews/{reviewID}/moderation 	[put]
+// @Security		ApiKeyAuth
+func (handler *ReviewHandler) ModerateReview(w http.ResponseWriter, req *http.Request) {
+	review, err := handler.ReviewDB.FindByID(chi.URLParam(req, "id"), chi.URLParam(req, "reviewID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.ReviewModerationInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	review.Status, err = entity.ParseReviewStatus(input.Status)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.ReviewDB.Update(review)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(review)
+}
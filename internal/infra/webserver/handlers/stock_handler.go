@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/pkg/clock"
+	"gorm.io/gorm"
+)
+
+type StockHandler struct {
+	ProductDB      database.ProductInterface
+	StockDB        database.StockInterface
+	ReservationTTL time.Duration
+	Clock          clock.Clock
+}
+
+func NewStockHandler(products database.ProductInterface, stock database.StockInterface, reservationTTL time.Duration) *StockHandler {
+	return &StockHandler{
+		ProductDB:      products,
+		StockDB:        stock,
+		ReservationTTL: reservationTTL,
+		Clock:          clock.Real{},
+	}
+}
+
+// GetStock godoc
+// @Summary 		Get a product stock
+// @Description 	Get the quantity on hand, reserved and available of a product
+// @Tags 			stock
+// @Accept 			json
+// @Produce 		json
+// @Param 			id						path		string		true 	"product ID"	Format(uuid)
+// @Success 		200						{object}	dto.StockLevelOutput
+// @Failure 		404						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/products/{id}/stock 	[get]
+// @Security		ApiKeyAuth
+func (handler *StockHandler) GetStock(w http.ResponseWriter, req *http.Request) {
+	level, err := handler.StockDB.FindLevel(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stockLevelOutput(level))
+}
+
+// CreateStockAdjustment godoc
+// @Summary 		Adjust a product stock
+// @Description 	Add (positive quantity) or remove (negative quantity) stock with a reason code. Removing more than is available fails with 409. Requires the admin role.
+// @Tags 			stock
+// @Accept 			json
+// @Produce 		json
+// @Param 			id									path		string					true 	"product ID"	Format(uuid)
+// @Param 			request								body		dto.StockAdjustmentInput	true 	"adjustment"
+// @Success 		201									{object}	dto.StockLevelOutput
+// @Failure 		400									{object}	Problem
+// @Failure 		403									{object}	Problem
+// @Failure 		404									{object}	Problem
+// @Failure 		409									{object}	Problem
+// @Failure 		500									{object}	Problem
+// @Router 			/products/{id}/stock-adjustments 	[post]
+// @Security		ApiKeyAuth
+func (handler *StockHandler) CreateStockAdjustment(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.StockAdjustmentInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	movement, err := entity.NewStockAdjustment(product.ID, input.Quantity, entity.StockReason(input.Reason), input.Note, subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	level, err := handler.StockDB.Adjust(movement)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(stockLevelOutput(level))
+}
+
+// GetStockLedger godoc
+// @Summary 		Get a product stock ledger
+// @Description 	List every stock movement of a product, newest first
+// @Tags 			stock
+// @Accept 			json
+// @Produce 		json
+// @Param 			id								path		string		true 	"product ID"	Format(uuid)
+// @Success 		200								{array}		entity.StockMovement
+// @Failure 		404								{object}	Problem
+// @Failure 		500								{object}	Problem
+// @Router 			/products/{id}/stock-ledger 	[get]
+// @Security		ApiKeyAuth
+func (handler *StockHandler) GetStockLedger(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	ledger, err := handler.StockDB.FindLedger(product.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ledger)
+}
+
+// CreateStockReservation godoc
+// @Summary 		Reserve stock
+// @Description 	Hold stock of a product for a pending order. The reservation expires after the configured time unless it is committed. Requires the admin role.
+// @Tags 			stock
+// @Accept 			json
+// @Produce 		json
+// @Param 			id									path		string							true 	"product ID"	Format(uuid)
+// @Param 			request								body		dto.CreateStockReservationInput	true 	"reservation"
+// @Success 		201									{object}	entity.StockReservation
+// @Failure 		400									{object}	Problem
+// @Failure 		403									{object}	Problem
+// @Failure 		404									{object}	Problem
+// @Failure 		409									{object}	Problem
+// @Failure 		500									{object}	Problem
+// @Router 			/products/{id}/stock-reservations 	[post]
+// @Security		ApiKeyAuth
+func (handler *StockHandler) CreateStockReservation(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.CreateStockReservationInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	expiresAt := handler.Clock.Now().Add(handler.ReservationTTL)
+	reservation, err := entity.NewStockReservation(product.ID, input.Quantity, expiresAt, input.Reference)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.StockDB.Reserve(reservation)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reservation)
+}
+
+// ReleaseStockReservation godoc
+// @Summary 		Release a stock reservation
+// @Description 	Give the stock held by a reservation back. Requires the admin role.
+// @Tags 			stock
+// @Accept 			json
+// @Produce 		json
+// @Param 			id													path		string		true 	"product ID"		Format(uuid)
+// @Param 			reservationID										path		string		true 	"reservation ID"	Format(uuid)
+// @Success 		204
+// @Failure 		403													{object}	Problem
+// @Failure 		404													{object}	Problem
+// @Failure 		409													{object}	Problem
+// @Failure 		500													{object}	Problem
+// @Router 			/products/{id}/stock-reservations/{reservationID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *StockHandler) ReleaseStockReservation(w http.ResponseWriter, req *http.Request) {
+	reservation, err := handler.StockDB.FindReservation(chi.URLParam(req, "reservationID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if reservation.ProductID.String() != chi.URLParam(req, "id") {
+		writeError(w, req, gorm.ErrRecordNotFound)
+		return
+	}
+
+	err = handler.StockDB.Release(reservation.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func stockLevelOutput(level *entity.StockLevel) dto.StockLevelOutput {
+	return dto.StockLevelOutput{StockLevel: *level, Available: level.Available()}
+}
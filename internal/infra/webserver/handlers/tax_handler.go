@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/tax"
+	entityPkg "github.com/pedro-chandelier/go-expert-apis/pkg/entity"
+)
+
+// TaxHandler quotes taxes and, when the rules are kept in the database, lets
+// admins manage them.
+type TaxHandler struct {
+	Taxes     *tax.Calculator
+	TaxDB     database.TaxInterface
+	ProductDB database.ProductInterface
+	VariantDB database.VariantInterface
+}
+
+func NewTaxHandler(taxes *tax.Calculator, taxDB database.TaxInterface, products database.ProductInterface, variants database.VariantInterface) *TaxHandler {
+	return &TaxHandler{
+		Taxes:     taxes,
+		TaxDB:     taxDB,
+		ProductDB: products,
+		VariantDB: variants,
+	}
+}
+
+// QuoteTax godoc
+// @Summary 		Quote taxes
+// @Description 	Compute the tax due on the listed items in a region, at the current prices. The rate of each item is the one of its nearest category with a rate, or the default rate of the region.
+// @Tags 			tax
+// @Accept 			json
+// @Produce 		json
+// @Param 			request			body		dto.TaxQuoteInput	true 	"tax quote request"
+// @Success 		200				{object}	entity.TaxQuote
+// @Failure 		400				{object}	Problem
+// @Failure 		404				{object}	Problem
+// @Failure 		422				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/tax/quote 		[post]
+// @Security		ApiKeyAuth
+func (handler *TaxHandler) QuoteTax(w http.ResponseWriter, req *http.Request) {
+	var input dto.TaxQuoteInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	lines, err := loadInputLines(handler.ProductDB, handler.VariantDB, subject(req), input.Items)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	quote, err := handler.Taxes.Quote(input.Region, lines, nil)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(quote)
+}
+
+// GetTaxRegions godoc
+// @Summary 		List tax regions
+// @Description 	List the regions with tax rules along with their rates
+// @Tags 			tax
+// @Accept 			json
+// @Produce 		json
+// @Success 		200				{array}		entity.TaxRegion
+// @Failure 		500				{object}	Problem
+// @Router 			/tax/regions 	[get]
+// @Security		ApiKeyAuth
+func (handler *TaxHandler) GetTaxRegions(w http.ResponseWriter, req *http.Request) {
+	regions, err := handler.Taxes.Rules.FindRegions()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if regions == nil {
+		regions = []entity.TaxRegion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(regions)
+}
+
+// SaveTaxRegion godoc
+// @Summary 		Create or replace a tax region
+// @Description 	Set the pricing, rounding and rates of a region, replacing its previous rates. Only available when the tax rules are kept in the database. Requires the admin role.
+// @Tags 			tax
+// @Accept 			json
+// @Produce 		json
+// @Param 			code						path		string					true 	"ISO 3166 region code"
+// @Param 			request						body		dto.TaxRegionInput		true 	"tax region request"
+// @Success 		200							{object}	entity.TaxRegion
+// @Failure 		400							{object}	Problem
+// @Failure 		403							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/admin/tax/regions/{code} 	[put]
+// @Security		ApiKeyAuth
+func (handler *TaxHandler) SaveTaxRegion(w http.ResponseWriter, req *http.Request) {
+	var input dto.TaxRegionInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	rounding := entity.TaxRounding(input.Rounding)
+	if rounding == "" {
+		rounding = entity.TaxRoundLine
+	}
+	region, err := entity.NewTaxRegion(chi.URLParam(req, "code"), input.Name, input.PricesIncludeTax, rounding)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	for _, rate := range input.Rates {
+		var categoryID *entityPkg.ID
+		if rate.CategoryID != nil {
+			id, err := entityPkg.ParseID(*rate.CategoryID)
+			if err != nil {
+				writeError(w, req, entity.ErrInvalidID)
+				return
+			}
+			categoryID = &id
+		}
+		err = region.AddRate(categoryID, rate.Rate)
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+	}
+	region.UpdatedAt = time.Now()
+
+	err = handler.TaxDB.Save(region)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(region)
+}
+
+// DeleteTaxRegion godoc
+// @Summary 		Delete a tax region
+// @Description 	Delete a region along with its rates. Only available when the tax rules are kept in the database. Requires the admin role.
+// @Tags 			tax
+// @Accept 			json
+// @Produce 		json
+// @Param 			code						path		string		true 	"ISO 3166 region code"
+// @Success 		200
+// @Failure 		403							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/admin/tax/regions/{code} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *TaxHandler) DeleteTaxRegion(w http.ResponseWriter, req *http.Request) {
+	err := handler.TaxDB.Delete(chi.URLParam(req, "code"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
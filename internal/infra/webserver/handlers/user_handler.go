@@ -5,10 +5,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/go-chi/chi"
 	"github.com/go-chi/jwtauth"
 	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
 	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
 	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"gorm.io/gorm"
 )
 
 type UserHandler struct {
@@ -17,10 +19,6 @@ type UserHandler struct {
 	JwtExpiresIn int
 }
 
-type Error struct {
-	Message string `json:"message"`
-}
-
 func NewUserHandler(userDB database.UserInterface) *UserHandler {
 	return &UserHandler{UserDB: userDB}
 }
@@ -33,9 +31,9 @@ func NewUserHandler(userDB database.UserInterface) *UserHandler {
 // @Produce 		json
 // @Param 			request	body			dto.GetJwtInput	true	"user credentials"
 // @Success 		200		{object}		dto.GetJwtOutput
-// @Failure 		400
-// @Failure 		401
-// @Failure 		404 	{object}		Error
+// @Failure 		400 	{object}		Problem
+// @Failure 		401 	{object}		Problem
+// @Failure 		404 	{object}		Problem
 // @Router 			/users/generate-token 	[post]
 func (handler *UserHandler) GetJwt(w http.ResponseWriter, req *http.Request) {
 	jwt := req.Context().Value("jwt").(*jwtauth.JWTAuth)
@@ -44,26 +42,29 @@ func (handler *UserHandler) GetJwt(w http.ResponseWriter, req *http.Request) {
 
 	err := json.NewDecoder(req.Body).Decode(&jwtInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeMalformed(w, req, err)
 		return
 	}
 
 	user, err := handler.UserDB.FindByEmail(jwtInput.Email)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(Error{Message: err.Error()})
+		writeError(w, req, err)
 		return
 	}
 
 	if !user.ValidatePassword(jwtInput.Password) {
-		w.WriteHeader(http.StatusUnauthorized)
+		writeDetail(w, req, http.StatusUnauthorized, ProblemTypeUnauthorized, "invalid_credentials")
 		return
 	}
 
-	_, tokenString, _ := jwt.Encode(map[string]interface{}{
+	claims := map[string]interface{}{
 		"sub": user.ID.String(),
 		"exp": time.Now().Add(time.Second * time.Duration(jwtExpiresIn)).Unix(),
-	})
+	}
+	if user.Role != "" {
+		claims["role"] = user.Role
+	}
+	_, tokenString, _ := jwt.Encode(claims)
 
 	accessToken := dto.GetJwtOutput{AccessToken: tokenString}
 
@@ -74,39 +75,71 @@ func (handler *UserHandler) GetJwt(w http.ResponseWriter, req *http.Request) {
 
 // Create user godoc
 // @Summary 		Create user
-// @Description 	Create user
+// @Description 	Create user and answer with it, without the password. With Prefer: return=minimal the body is left out. Retrying with the same Idempotency-Key and body replays the first response.
 // @Tags 			users
 // @Accept 			json
 // @Produce 		json
-// @Param 			request	body	dto.CreateUserInput	true	"user request"
-// @Success 		201
-// @Failure 		500 	{object}	Error
-// @Failure 		400 	{object}	Error
-// @Failure 		404 	{object}	Error
+// @Param 			request				body	dto.CreateUserInput	true	"user request"
+// @Param 			Idempotency-Key		header	string				false	"key making retries of the request safe"
+// @Param 			Prefer				header	string				false	"return=minimal to leave the user out of the response"	Enums(return=minimal, return=representation)
+// @Success 		201		{object}	entity.User
+// @Header 			201		{string}	Location	"URL of the user"
+// @Failure 		500 	{object}	Problem
+// @Failure 		400 	{object}	Problem
+// @Failure 		404 	{object}	Problem
+// @Failure 		409 	{object}	Problem
+// @Failure 		413 	{object}	Problem
 // @Router 			/users 	[post]
 func (handler *UserHandler) CreateUser(w http.ResponseWriter, req *http.Request) {
 	var userInput dto.CreateUserInput
 	err := json.NewDecoder(req.Body).Decode(&userInput)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Error{Message: err.Error()})
+		writeMalformed(w, req, err)
 		return
 	}
 
 	u, err := entity.NewUser(userInput.Name, userInput.Email, userInput.Password)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		apiError := Error{Message: err.Error()}
-		json.NewEncoder(w).Encode(apiError)
+		writeError(w, req, err)
 		return
 	}
 
 	err = handler.UserDB.Create(u)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(Error{Message: err.Error()})
+		writeError(w, req, err)
+		return
+	}
+
+	writeRepresentation(w, req, http.StatusCreated, "/users/"+u.ID.String(), u)
+}
+
+// GetUser godoc
+// @Summary 		Get a user
+// @Description 	Get a user, without the password. Users only get themselves unless they have the admin role.
+// @Tags 			users
+// @Accept 			json
+// @Produce 		json
+// @Param 			id				path		string		true 	"user ID"	Format(uuid)
+// @Success 		200				{object}	entity.User
+// @Failure 		404				{object}	Problem
+// @Failure 		500				{object}	Problem
+// @Router 			/users/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *UserHandler) GetUser(w http.ResponseWriter, req *http.Request) {
+	id := chi.URLParam(req, "id")
+	// other users are not found rather than forbidden, like their orders
+	if id != subject(req) && !isAdmin(req) {
+		writeError(w, req, gorm.ErrRecordNotFound)
+		return
+	}
+
+	user, err := handler.UserDB.FindByID(id)
+	if err != nil {
+		writeError(w, req, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(user)
 }
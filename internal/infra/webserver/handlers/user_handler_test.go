@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/jwtauth"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func createUserHandler(t *testing.T) (*UserHandler, *database.UserDB) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{TranslateError: true})
+	assert.NoError(t, err)
+	assert.NoError(t, db.AutoMigrate(&entity.User{}))
+	userDB := database.NewUserDB(db)
+	return NewUserHandler(userDB), userDB
+}
+
+// tokenRole signs in and returns the role claim of the token issued.
+func tokenRole(t *testing.T, handler *UserHandler, email, password string) any {
+	tokenAuth := jwtauth.New("HS256", []byte("secret"), nil)
+	body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+	req := httptest.NewRequest(http.MethodPost, "/users/generate-token", strings.NewReader(body))
+	ctx := context.WithValue(req.Context(), "jwt", tokenAuth)
+	ctx = context.WithValue(ctx, "jwtExpiresIn", 300)
+	rec := httptest.NewRecorder()
+	handler.GetJwt(rec, req.WithContext(ctx))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var output dto.GetJwtOutput
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&output))
+	token, err := tokenAuth.Decode(output.AccessToken)
+	assert.NoError(t, err)
+	role, _ := token.Get("role")
+	return role
+}
+
+func TestGetJwtCarriesTheRoleOfTheUser(t *testing.T) {
+	handler, userDB := createUserHandler(t)
+	user, _ := entity.NewUser("Mr. Pipo", "chandelier.pipo@gmail.com", "goexpert")
+	assert.NoError(t, userDB.Create(user))
+
+	// registering an address does not make its owner admin
+	assert.Nil(t, tokenRole(t, handler, "chandelier.pipo@gmail.com", "goexpert"))
+
+	assert.NoError(t, userDB.SetRole(user.Email, entity.RoleAdmin))
+	assert.Equal(t, RoleAdmin, tokenRole(t, handler, "chandelier.pipo@gmail.com", "goexpert"))
+}
+
+func TestCreateUserIsIdempotent(t *testing.T) {
+	handler, userDB := createUserHandler(t)
+	assert.NoError(t, userDB.DB.AutoMigrate(&entity.IdempotentRequest{}))
+	createUser := NewIdempotency(database.NewIdempotencyDB(userDB.DB)).Handler(http.HandlerFunc(handler.CreateUser))
+
+	signUp := func() *httptest.ResponseRecorder {
+		body := `{"name":"Mrs. Pipo","email":"mrs.pipo@gmail.com","password":"goexpert"}`
+		req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		req.Header.Set(IdempotencyKeyHeader, "create-user")
+		rec := httptest.NewRecorder()
+		createUser.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := signUp()
+	assert.Equal(t, http.StatusCreated, first.Code)
+	user, err := userDB.FindByEmail("mrs.pipo@gmail.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/"+user.ID.String(), first.Header().Get("Location"))
+
+	// a retry gets the same user instead of a conflict on the email
+	retry := signUp()
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+}
+
+func TestGetUser(t *testing.T) {
+	handler, userDB := createUserHandler(t)
+	user, _ := entity.NewUser("Mr. Pipo", "chandelier.pipo@gmail.com", "goexpert")
+	assert.NoError(t, userDB.Create(user))
+
+	router := chi.NewRouter()
+	router.Use(jwtauth.Verifier(idempotencyTokenAuth))
+	router.Get("/users/{id}", handler.GetUser)
+	getUser := func(claims map[string]interface{}) *httptest.ResponseRecorder {
+		_, token, err := idempotencyTokenAuth.Encode(claims)
+		assert.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/users/"+user.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := getUser(map[string]interface{}{"sub": user.ID.String()})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "password")
+	var found entity.User
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&found))
+	assert.Equal(t, user.Email, found.Email)
+
+	assert.Equal(t, http.StatusNotFound, getUser(map[string]interface{}{"sub": "someone-else"}).Code)
+	assert.Equal(t, http.StatusOK, getUser(map[string]interface{}{"sub": "someone-else", "role": RoleAdmin}).Code)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+type VariantHandler struct {
+	ProductDB database.ProductInterface
+	VariantDB database.VariantInterface
+}
+
+func NewVariantHandler(products database.ProductInterface, variants database.VariantInterface) *VariantHandler {
+	return &VariantHandler{
+		ProductDB: products,
+		VariantDB: variants,
+	}
+}
+
+// CreateVariant godoc
+// @Summary 		Create a product variant
+// @Description 	Create a variant of a product. Without a price the variant sells at the product price.
+// @Tags 			variants
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string				true 	"product ID"	Format(uuid)
+// @Param 			request						body		dto.VariantInput	true 	"variant request"
+// @Success 		201							{object}	entity.Variant
+// @Failure 		400							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		409							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/products/{id}/variants 	[post]
+// @Security		ApiKeyAuth
+func (handler *VariantHandler) CreateVariant(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.VariantInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	variant, err := entity.NewVariant(product, input.SKU, input.Options, input.Price, input.Stock)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.VariantDB.Create(variant)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// GetVariants godoc
+// @Summary 		List a product variants
+// @Description 	List the variants of a product ordered by SKU
+// @Tags 			variants
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"product ID"	Format(uuid)
+// @Success 		200							{array}		entity.Variant
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/products/{id}/variants 	[get]
+// @Security		ApiKeyAuth
+func (handler *VariantHandler) GetVariants(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	variants, err := handler.VariantDB.FindByProduct(product.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if variants == nil {
+		variants = []entity.Variant{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variants)
+}
+
+// GetVariant godoc
+// @Summary 		Get a product variant
+// @Description 	Get a product variant
+// @Tags 			variants
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string		true 	"product ID"	Format(uuid)
+// @Param 			variantID								path		string		true 	"variant ID"	Format(uuid)
+// @Success 		200										{object}	entity.Variant
+// @Failure 		404										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/products/{id}/variants/{variantID} 	[get]
+// @Security		ApiKeyAuth
+func (handler *VariantHandler) GetVariant(w http.ResponseWriter, req *http.Request) {
+	variant, err := handler.findVariant(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// UpdateVariant godoc
+// @Summary 		Update a product variant
+// @Description 	Update a product variant
+// @Tags 			variants
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string				true 	"product ID"	Format(uuid)
+// @Param 			variantID								path		string				true 	"variant ID"	Format(uuid)
+// @Param 			request									body		dto.VariantInput	true 	"variant request"
+// @Success 		200										{object}	entity.Variant
+// @Failure 		400										{object}	Problem
+// @Failure 		404										{object}	Problem
+// @Failure 		409										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/products/{id}/variants/{variantID} 	[put]
+// @Security		ApiKeyAuth
+func (handler *VariantHandler) UpdateVariant(w http.ResponseWriter, req *http.Request) {
+	product, err := handler.ProductDB.FindByID(chi.URLParam(req, "id"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	variant, err := handler.findVariant(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.VariantInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+	variant.SKU = entity.NormalizeSKU(input.SKU)
+	variant.Options = input.Options
+	variant.Price = input.Price
+	variant.Stock = input.Stock
+
+	err = variant.Validate(product)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.VariantDB.Update(variant)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(variant)
+}
+
+// DeleteVariant godoc
+// @Summary 		Delete a product variant
+// @Description 	Delete a product variant
+// @Tags 			variants
+// @Accept 			json
+// @Produce 		json
+// @Param 			id										path		string		true 	"product ID"	Format(uuid)
+// @Param 			variantID								path		string		true 	"variant ID"	Format(uuid)
+// @Success 		200
+// @Failure 		404										{object}	Problem
+// @Failure 		500										{object}	Problem
+// @Router 			/products/{id}/variants/{variantID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *VariantHandler) DeleteVariant(w http.ResponseWriter, req *http.Request) {
+	variant, err := handler.findVariant(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.VariantDB.Delete(variant.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// findVariant loads the variant of the URL, which must belong to the
+// product of the URL.
+func (handler *VariantHandler) findVariant(req *http.Request) (*entity.Variant, error) {
+	variant, err := handler.VariantDB.FindByID(chi.URLParam(req, "variantID"))
+	if err != nil {
+		return nil, err
+	}
+	if variant.ProductID.String() != chi.URLParam(req, "id") {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return variant, nil
+}
@@ -0,0 +1,363 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/pedro-chandelier/go-expert-apis/internal/dto"
+	"github.com/pedro-chandelier/go-expert-apis/internal/entity"
+	"github.com/pedro-chandelier/go-expert-apis/internal/infra/database"
+	"gorm.io/gorm"
+)
+
+// WishlistHandler serves the wishlists of the authenticated user and the
+// read-only view of shared ones.
+type WishlistHandler struct {
+	WishlistDB database.WishlistInterface
+	ProductDB  database.ProductInterface
+}
+
+func NewWishlistHandler(wishlists database.WishlistInterface, products database.ProductInterface) *WishlistHandler {
+	return &WishlistHandler{
+		WishlistDB: wishlists,
+		ProductDB:  products,
+	}
+}
+
+// CreateWishlist godoc
+// @Summary 		Create a wishlist
+// @Description 	Create a named wishlist for the authenticated user
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			request					body		dto.WishlistInput	true 	"wishlist request"
+// @Success 		201						{object}	entity.Wishlist
+// @Failure 		400						{object}	Problem
+// @Failure 		500						{object}	Problem
+// @Router 			/users/me/wishlists 	[post]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) CreateWishlist(w http.ResponseWriter, req *http.Request) {
+	var input dto.WishlistInput
+	err := json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	wishlist, err := entity.NewWishlist(subject(req), input.Name)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.Create(wishlist)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusCreated)
+}
+
+// GetWishlists godoc
+// @Summary 		List my wishlists
+// @Description 	List the wishlists of the authenticated user with their products
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Success 		200						{array}		entity.Wishlist
+// @Failure 		500						{object}	Problem
+// @Router 			/users/me/wishlists 	[get]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) GetWishlists(w http.ResponseWriter, req *http.Request) {
+	wishlists, err := handler.WishlistDB.FindByUser(subject(req))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+	if wishlists == nil {
+		wishlists = []entity.Wishlist{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(wishlists)
+}
+
+// GetWishlist godoc
+// @Summary 		Get one of my wishlists
+// @Description 	Get a wishlist of the authenticated user
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"wishlist ID"	Format(uuid)
+// @Success 		200							{object}	entity.Wishlist
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/users/me/wishlists/{id} 	[get]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) GetWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusOK)
+}
+
+// RenameWishlist godoc
+// @Summary 		Rename one of my wishlists
+// @Description 	Rename a wishlist of the authenticated user
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string				true 	"wishlist ID"	Format(uuid)
+// @Param 			request						body		dto.WishlistInput	true 	"wishlist request"
+// @Success 		200							{object}	entity.Wishlist
+// @Failure 		400							{object}	Problem
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/users/me/wishlists/{id} 	[put]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) RenameWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	var input dto.WishlistInput
+	err = json.NewDecoder(req.Body).Decode(&input)
+	if err != nil {
+		writeMalformed(w, req, err)
+		return
+	}
+
+	wishlist.Name = input.Name
+	err = wishlist.Validate()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.Update(wishlist)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusOK)
+}
+
+// DeleteWishlist godoc
+// @Summary 		Delete one of my wishlists
+// @Description 	Delete a wishlist of the authenticated user
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id							path		string		true 	"wishlist ID"	Format(uuid)
+// @Success 		200
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/users/me/wishlists/{id} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) DeleteWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.Delete(wishlist)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AddWishlistItem godoc
+// @Summary 		Add a product to one of my wishlists
+// @Description 	Add a product to a wishlist of the authenticated user. Adding it again changes nothing.
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id											path		string		true 	"wishlist ID"	Format(uuid)
+// @Param 			productID									path		string		true 	"product ID"	Format(uuid)
+// @Success 		200											{object}	entity.Wishlist
+// @Failure 		404											{object}	Problem
+// @Failure 		500											{object}	Problem
+// @Router 			/users/me/wishlists/{id}/items/{productID} 	[put]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) AddWishlistItem(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.AddItem(wishlist, chi.URLParam(req, "productID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeFreshWishlist(w, req, wishlist)
+}
+
+// RemoveWishlistItem godoc
+// @Summary 		Remove a product from one of my wishlists
+// @Description 	Remove a product from a wishlist of the authenticated user
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id											path		string		true 	"wishlist ID"	Format(uuid)
+// @Param 			productID									path		string		true 	"product ID"	Format(uuid)
+// @Success 		200											{object}	entity.Wishlist
+// @Failure 		404											{object}	Problem
+// @Failure 		500											{object}	Problem
+// @Router 			/users/me/wishlists/{id}/items/{productID} 	[delete]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) RemoveWishlistItem(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.RemoveItem(wishlist, chi.URLParam(req, "productID"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	handler.writeFreshWishlist(w, req, wishlist)
+}
+
+// ShareWishlist godoc
+// @Summary 		Share one of my wishlists
+// @Description 	Give a wishlist a new share token, readable by anyone at /wishlists/shared/{token}. Sharing again revokes the previous link.
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id								path		string		true 	"wishlist ID"	Format(uuid)
+// @Success 		200								{object}	entity.Wishlist
+// @Failure 		404								{object}	Problem
+// @Failure 		500								{object}	Problem
+// @Router 			/users/me/wishlists/{id}/share 	[post]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) ShareWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = wishlist.Share()
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	err = handler.WishlistDB.Update(wishlist)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusOK)
+}
+
+// UnshareWishlist godoc
+// @Summary 		Stop sharing one of my wishlists
+// @Description 	Revoke the share link of a wishlist
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			id								path		string		true 	"wishlist ID"	Format(uuid)
+// @Success 		200								{object}	entity.Wishlist
+// @Failure 		404								{object}	Problem
+// @Failure 		500								{object}	Problem
+// @Router 			/users/me/wishlists/{id}/share 	[delete]
+// @Security		ApiKeyAuth
+func (handler *WishlistHandler) UnshareWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.findWishlist(req)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	wishlist.Unshare()
+	err = handler.WishlistDB.Update(wishlist)
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusOK)
+}
+
+// GetSharedWishlist godoc
+// @Summary 		Get a shared wishlist
+// @Description 	Get a wishlist through its share link, with its products. No token is needed.
+// @Tags 			wishlists
+// @Accept 			json
+// @Produce 		json
+// @Param 			token						path		string		true 	"share token"
+// @Success 		200							{object}	dto.SharedWishlistOutput
+// @Failure 		404							{object}	Problem
+// @Failure 		500							{object}	Problem
+// @Router 			/wishlists/shared/{token} 	[get]
+func (handler *WishlistHandler) GetSharedWishlist(w http.ResponseWriter, req *http.Request) {
+	wishlist, err := handler.WishlistDB.FindByShareToken(chi.URLParam(req, "token"))
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	output := dto.SharedWishlistOutput{
+		Name:      wishlist.Name,
+		Products:  make([]entity.Product, 0, len(wishlist.Items)),
+		UpdatedAt: wishlist.UpdatedAt,
+	}
+	for _, item := range wishlist.Items {
+		product, err := handler.ProductDB.FindByID(item.ProductID.String())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			writeError(w, req, err)
+			return
+		}
+		output.Products = append(output.Products, *product)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(output)
+}
+
+func (handler *WishlistHandler) findWishlist(req *http.Request) (*entity.Wishlist, error) {
+	return handler.WishlistDB.FindByID(subject(req), chi.URLParam(req, "id"))
+}
+
+// writeFreshWishlist reloads the wishlist to return its current items.
+func (handler *WishlistHandler) writeFreshWishlist(w http.ResponseWriter, req *http.Request, wishlist *entity.Wishlist) {
+	wishlist, err := handler.WishlistDB.FindByID(wishlist.UserID, wishlist.ID.String())
+	if err != nil {
+		writeError(w, req, err)
+		return
+	}
+
+	writeWishlist(w, wishlist, http.StatusOK)
+}
+
+func writeWishlist(w http.ResponseWriter, wishlist *entity.Wishlist, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(wishlist)
+}
@@ -0,0 +1,38 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+
+// Decode reads a JPEG, PNG or GIF image.
+func Decode(data []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if errors.Is(err, image.ErrFormat) {
+		return nil, ErrUnsupportedFormat
+	}
+	return img, err
+}
+
+// Encode writes img as contentType. GIFs are written as PNG, since
+// thumbnails only need their first frame.
+func Encode(img image.Image, contentType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	case "image/png", "image/gif":
+		contentType = "image/png"
+		err = png.Encode(&buf, img)
+	default:
+		return nil, "", ErrUnsupportedFormat
+	}
+	return buf.Bytes(), contentType, err
+}
@@ -0,0 +1,36 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			// left half black, right half white
+			if x >= 200 {
+				img.Set(x, y, color.White)
+			} else {
+				img.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	thumb := Thumbnail(img, 100)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumb.Bounds())
+	assert.Equal(t, color.RGBA{0, 0, 0, 255}, thumb.At(10, 10))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, thumb.At(90, 10))
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 50, 80))
+	assert.Same(t, img, Thumbnail(img, 100))
+
+	tall := Thumbnail(image.NewRGBA(image.Rect(0, 0, 100, 400)), 100)
+	assert.Equal(t, image.Rect(0, 0, 25, 100), tall.Bounds())
+}
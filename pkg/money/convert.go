@@ -0,0 +1,113 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var ErrInvalidRate = errors.New("invalid exchange rate")
+
+// RoundingMode decides how a converted amount that falls between two minor
+// units is rounded.
+type RoundingMode string
+
+const (
+	// RoundHalfEven rounds ties to the even minor unit (banker's rounding).
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundHalfUp rounds ties away from zero.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundDown truncates towards zero.
+	RoundDown RoundingMode = "down"
+)
+
+func ParseRoundingMode(mode string) (RoundingMode, bool) {
+	switch RoundingMode(strings.ToLower(mode)) {
+	case RoundHalfEven:
+		return RoundHalfEven, true
+	case RoundHalfUp:
+		return RoundHalfUp, true
+	case RoundDown:
+		return RoundDown, true
+	}
+	return "", false
+}
+
+// ParseRate reads a positive decimal exchange rate such as "0.9215".
+func ParseRate(rate string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(rate))
+	if !ok || r.Sign() <= 0 || strings.ContainsAny(rate, "/eE") {
+		return nil, ErrInvalidRate
+	}
+	return r, nil
+}
+
+// FormatRate writes a rate with up to ten decimal places.
+func FormatRate(rate *big.Rat) string {
+	formatted := strings.TrimRight(rate.FloatString(10), "0")
+	return strings.TrimSuffix(formatted, ".")
+}
+
+// Convert multiplies m by rate, expressed as units of currency per unit of
+// m's currency, and rounds the result to currency's minor unit.
+func (m Money) Convert(currency string, rate *big.Rat, mode RoundingMode) (Money, error) {
+	currency = strings.ToUpper(currency)
+	if !isCurrencyCode(currency) {
+		return Money{}, ErrInvalidCurrency
+	}
+	if rate == nil || rate.Sign() <= 0 {
+		return Money{}, ErrInvalidRate
+	}
+
+	value := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), rate)
+	shift := Exponent(currency) - Exponent(m.Currency)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		value.Mul(value, scale)
+	} else {
+		value.Quo(value, scale)
+	}
+
+	amount, ok := round(value, mode)
+	if !ok {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// FromRat rounds an amount of minor units of currency, such as the exact
+// result of multiplying a price by a rate. The amount must fit in an int64,
+// as fractions of existing amounts do.
+func FromRat(minor *big.Rat, currency string, mode RoundingMode) Money {
+	amount, _ := round(minor, mode)
+	return Money{Amount: amount, Currency: currency}
+}
+
+// round rounds value to an integer, reporting whether it fits in an int64.
+func round(value *big.Rat, mode RoundingMode) (int64, bool) {
+	quotient, remainder := new(big.Int).QuoRem(value.Num(), value.Denom(), new(big.Int))
+	if remainder.Sign() == 0 || mode == RoundDown {
+		return quotient.Int64(), quotient.IsInt64()
+	}
+
+	step := big.NewInt(int64(value.Sign()))
+	twiceRemainder := new(big.Int).Abs(remainder)
+	twiceRemainder.Lsh(twiceRemainder, 1)
+
+	switch twiceRemainder.Cmp(value.Denom()) {
+	case 1:
+		quotient.Add(quotient, step)
+	case 0:
+		if mode == RoundHalfUp || quotient.Bit(0) == 1 {
+			quotient.Add(quotient, step)
+		}
+	}
+	return quotient.Int64(), quotient.IsInt64()
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,74 @@
+package money
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	rate, err := ParseRate("0.9215")
+	assert.NoError(t, err)
+
+	converted, err := New(1050, "USD").Convert("EUR", rate, RoundHalfEven)
+	assert.NoError(t, err)
+	assert.Equal(t, New(968, "EUR"), converted)
+
+	rate, err = ParseRate("151.25")
+	assert.NoError(t, err)
+	converted, err = New(1999, "USD").Convert("JPY", rate, RoundHalfUp)
+	assert.NoError(t, err)
+	assert.Equal(t, New(3023, "JPY"), converted)
+}
+
+func TestConvertRounding(t *testing.T) {
+	half, err := ParseRate("0.5")
+	assert.NoError(t, err)
+
+	cases := []struct {
+		amount   int64
+		mode     RoundingMode
+		expected int64
+	}{
+		{amount: 5, mode: RoundHalfEven, expected: 2},
+		{amount: 7, mode: RoundHalfEven, expected: 4},
+		{amount: 5, mode: RoundHalfUp, expected: 3},
+		{amount: -5, mode: RoundHalfUp, expected: -3},
+		{amount: 7, mode: RoundDown, expected: 3},
+	}
+	for _, c := range cases {
+		converted, err := New(c.amount, "USD").Convert("EUR", half, c.mode)
+		assert.NoError(t, err)
+		assert.Equal(t, c.expected, converted.Amount, "%d with %s", c.amount, c.mode)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	_, err := ParseRate("0")
+	assert.ErrorIs(t, err, ErrInvalidRate)
+	_, err = ParseRate("1/3")
+	assert.ErrorIs(t, err, ErrInvalidRate)
+	_, err = ParseRate("abc")
+	assert.ErrorIs(t, err, ErrInvalidRate)
+
+	rate, err := ParseRate("4")
+	assert.NoError(t, err)
+	assert.Equal(t, "0.25", FormatRate(rate.Inv(rate)))
+}
+
+func TestFromRat(t *testing.T) {
+	assert.Equal(t, New(483, "USD"), FromRat(big.NewRat(4825, 10), "USD", RoundHalfUp))
+	assert.Equal(t, New(482, "USD"), FromRat(big.NewRat(4825, 10), "USD", RoundHalfEven))
+}
+
+func TestConvertOverflow(t *testing.T) {
+	rate, err := ParseRate("10")
+	assert.NoError(t, err)
+
+	_, err = New(math.MaxInt64/2, "USD").Convert("EUR", rate, RoundHalfEven)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = New(math.MinInt64/2, "USD").Convert("EUR", rate, RoundDown)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+}
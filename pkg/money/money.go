@@ -0,0 +1,230 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount    = errors.New("invalid amount")
+	ErrTooManyDecimals  = errors.New("amount has too many decimal places for its currency")
+	ErrInvalidCurrency  = errors.New("invalid currency")
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+	ErrAmountOverflow   = errors.New("the amount is too large")
+)
+
+// MaxAmount is the largest amount, in minor units, that prices and other
+// amounts entered by users may have. It leaves room to multiply them by
+// quantities and add them up without overflowing.
+const MaxAmount int64 = 1e15
+
+// Money is an amount in the minor units of an ISO 4217 currency, so that
+// 10.50 USD is stored as Amount 1050 and Currency "USD".
+type Money struct {
+	Amount   int64  `json:"amount" swaggertype:"string" example:"10.50"`
+	Currency string `json:"currency" gorm:"size:3" validate:"required,iso4217" example:"USD"`
+}
+
+// minorUnits lists the currencies whose minor unit is not the cent.
+var minorUnits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0,
+	"XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+func New(amount int64, currency string) Money {
+	return Money{Amount: amount, Currency: strings.ToUpper(currency)}
+}
+
+// Exponent returns the number of decimal places of a currency.
+func Exponent(currency string) int {
+	if exponent, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// Parse reads a decimal amount such as "10.50" in the given currency without
+// going through floating point.
+func Parse(amount, currency string) (Money, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency != "" && !isCurrencyCode(currency) {
+		return Money{}, ErrInvalidCurrency
+	}
+
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(amount, "-")
+
+	units, fraction, _ := strings.Cut(amount, ".")
+	if units == "" || !isDigits(units) || !isDigits(fraction) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	exponent := Exponent(currency)
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > exponent {
+		return Money{}, ErrTooManyDecimals
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(units+fraction, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// MustParse is like Parse but panics on invalid input. It is meant for
+// tests and constants.
+func MustParse(amount, currency string) Money {
+	m, err := Parse(amount, currency)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// FromFloat converts a legacy floating point amount, rounding half away from
+// zero to the currency's minor unit.
+func FromFloat(amount float64, currency string) Money {
+	factor := math.Pow10(Exponent(currency))
+	return New(int64(math.Round(amount*factor)), currency)
+}
+
+// Decimal formats the amount with the currency's decimal places, e.g. "10.50".
+func (m Money) Decimal() string {
+	exponent := Exponent(m.Currency)
+	abs := m.Amount
+	sign := ""
+	if abs < 0 {
+		abs = -abs
+		sign = "-"
+	}
+	digits := strconv.FormatInt(abs, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+// InRange tells whether the amount is within MaxAmount of zero.
+func (m Money) InRange() bool {
+	return m.Amount >= -MaxAmount && m.Amount <= MaxAmount
+}
+
+func (m Money) Equal(other Money) bool {
+	return m.Amount == other.Amount && m.Currency == other.Currency
+}
+
+// Add fails with ErrAmountOverflow when the sum does not fit in an int64.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	sum := m.Amount + other.Amount
+	if (other.Amount > 0 && sum < m.Amount) || (other.Amount < 0 && sum > m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: sum, Currency: m.Currency}, nil
+}
+
+// Sub fails with ErrAmountOverflow when the difference does not fit in an
+// int64.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	difference := m.Amount - other.Amount
+	if (other.Amount > 0 && difference > m.Amount) || (other.Amount < 0 && difference < m.Amount) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: difference, Currency: m.Currency}, nil
+}
+
+// Multiply fails with ErrAmountOverflow when the product does not fit in an
+// int64.
+func (m Money) Multiply(quantity int64) (Money, error) {
+	product := m.Amount * quantity
+	if m.Amount != 0 && (product/m.Amount != quantity || (m.Amount == -1 && quantity == math.MinInt64)) {
+		return Money{}, ErrAmountOverflow
+	}
+	return Money{Amount: product, Currency: m.Currency}, nil
+}
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+// MarshalJSON writes the amount as a decimal string so clients never see
+// minor units or floating point values.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.Decimal(), Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount either as a string or as a JSON number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw jsonMoney
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if raw.Amount == "" {
+		*m = Money{Currency: strings.ToUpper(raw.Currency)}
+		return nil
+	}
+	parsed, err := Parse(raw.Amount.String(), raw.Currency)
+	if err != nil {
+		return fmt.Errorf("amount %q: %w", raw.Amount, err)
+	}
+	*m = parsed
+	return nil
+}
+
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
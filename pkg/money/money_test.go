@@ -0,0 +1,97 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("10.5", "usd")
+	assert.NoError(t, err)
+	assert.Equal(t, Money{Amount: 1050, Currency: "USD"}, m)
+
+	m, err = Parse("1500", "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), m.Amount)
+
+	m, err = Parse("-0.125", "BHD")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-125), m.Amount)
+
+	_, err = Parse("10.999", "USD")
+	assert.ErrorIs(t, err, ErrTooManyDecimals)
+
+	_, err = Parse("10,50", "USD")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	_, err = Parse("10.50", "US")
+	assert.ErrorIs(t, err, ErrInvalidCurrency)
+}
+
+func TestDecimal(t *testing.T) {
+	assert.Equal(t, "10.50", New(1050, "USD").Decimal())
+	assert.Equal(t, "0.05", New(5, "USD").Decimal())
+	assert.Equal(t, "-0.05", New(-5, "USD").Decimal())
+	assert.Equal(t, "1500", New(1500, "JPY").Decimal())
+	assert.Equal(t, "0.125", New(125, "KWD").Decimal())
+	assert.Equal(t, "10.50 EUR", New(1050, "EUR").String())
+}
+
+func TestFromFloat(t *testing.T) {
+	assert.Equal(t, New(1999, "USD"), FromFloat(19.99, "USD"))
+	assert.Equal(t, New(30, "USD"), FromFloat(0.1+0.2, "USD"))
+	assert.Equal(t, New(20, "JPY"), FromFloat(19.5, "JPY"))
+}
+
+func TestArithmetic(t *testing.T) {
+	sum, err := New(1050, "USD").Add(New(25, "USD"))
+	assert.NoError(t, err)
+	assert.Equal(t, New(1075, "USD"), sum)
+
+	_, err = New(1050, "USD").Add(New(25, "EUR"))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	product, err := New(1050, "USD").Multiply(3)
+	assert.NoError(t, err)
+	assert.Equal(t, New(3150, "USD"), product)
+}
+
+func TestArithmeticOverflow(t *testing.T) {
+	_, err := New(math.MaxInt64, "USD").Add(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = New(math.MinInt64, "USD").Add(New(-1, "USD"))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = New(math.MinInt64, "USD").Sub(New(1, "USD"))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = New(math.MaxInt64, "USD").Sub(New(-1, "USD"))
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+
+	_, err = New(math.MaxInt64/2+1, "USD").Multiply(2)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = New(-1, "USD").Multiply(math.MinInt64)
+	assert.ErrorIs(t, err, ErrAmountOverflow)
+	product, err := New(MaxAmount, "USD").Multiply(1000)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxAmount*1000, product.Amount)
+
+	assert.True(t, New(MaxAmount, "USD").InRange())
+	assert.False(t, New(MaxAmount+1, "USD").InRange())
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(New(1050, "USD"))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"amount":"10.50","currency":"USD"}`, string(data))
+
+	var m Money
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":"10.50","currency":"usd"}`), &m))
+	assert.Equal(t, New(1050, "USD"), m)
+
+	assert.NoError(t, json.Unmarshal([]byte(`{"amount":19.99,"currency":"EUR"}`), &m))
+	assert.Equal(t, New(1999, "EUR"), m)
+
+	assert.ErrorIs(t, json.Unmarshal([]byte(`{"amount":"1.001","currency":"EUR"}`), &m), ErrTooManyDecimals)
+}